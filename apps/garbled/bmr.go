@@ -7,7 +7,6 @@
 package main
 
 import (
-	"fmt"
 	"log"
 	"math/big"
 
@@ -15,38 +14,44 @@ import (
 	"github.com/markkurossi/mpc/p2p"
 )
 
-func bmrMode(circ *circuit.Circuit, input *big.Int, player int) error {
-	// Create network.
-	addr := makeAddr(player)
-	nw, err := p2p.NewNetwork(addr, player)
-	if err != nil {
-		return err
-	}
-	defer nw.Close()
+func bmrMode(circ *circuit.Circuit, input *big.Int, player int,
+	listen string) error {
 
 	numPlayers := len(circ.Inputs)
 
-	for i := 0; i < numPlayers; i++ {
-		if i == player {
-			continue
-		}
-		err := nw.AddPeer(makeAddr(i), i)
+	// Without peer configuration, all players run in the local host.
+	config := peers
+	if config == nil {
+		config = p2p.LocalConfig(numPlayers, 8080)
+		self, err := config.Peer(player)
 		if err != nil {
 			return err
 		}
+		self.Listen = listen
+	}
+	for i := 0; i < numPlayers; i++ {
+		if _, err := config.Peer(i); err != nil {
+			return err
+		}
 	}
 
+	// Create network.
+	nw, err := p2p.NewNetworkConfig(config, player)
+	if err != nil {
+		return err
+	}
+	defer nw.Close()
+
 	log.Printf("Network created\n")
 
-	result, err := circuit.Player(nw, circ, input, verbose)
+	result, t, err := circuit.PlayerWithTiming(nw, circ, input, verbose)
 	if err != nil {
 		return err
 	}
+	if err := saveTiming(t); err != nil {
+		return err
+	}
 
 	printResult(result, circ.Outputs)
 	return nil
 }
-
-func makeAddr(player int) string {
-	return fmt.Sprintf("127.0.0.1:%d", 8080+player)
-}
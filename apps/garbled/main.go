@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"math/big"
 	"net"
 	"os"
@@ -28,9 +29,18 @@ import (
 )
 
 var (
-	port    = ":8080"
-	verbose = false
-	debug   = false
+	addr      = ":8080"
+	verbose   = false
+	debug     = false
+	simnet    *p2p.SimNet
+	peers     *p2p.Config
+	timingOut string
+)
+
+// Peer IDs of the 2-party computation in the peer configuration.
+const (
+	garblerID   = 0
+	evaluatorID = 1
 )
 
 type input []string
@@ -61,10 +71,19 @@ func main() {
 	ssa := flag.Bool("ssa", false, "compile MPCL to SSA assembly")
 	dot := flag.Bool("dot", false, "create Graphviz DOT output")
 	optimize := flag.Int("O", 1, "optimization level")
+	maxInline := flag.Int("max-inline-depth", utils.DefaultMaxInlineDepth,
+		"maximum depth of nested function call inlining")
 	fVerbose := flag.Bool("v", false, "verbose output")
 	fDebug := flag.Bool("d", false, "debug output")
 	cpuprofile := flag.String("cpuprofile", "", "write cpu profile to `file`")
 	bmr := flag.Int("bmr", -1, "semi-honest secure BMR protocol player number")
+	fSimnet := flag.String("simnet", "",
+		"simulated network conditions, e.g. rtt=50ms,bw=100mbit,jitter=5ms")
+	fPeers := flag.String("peers", "", "read peer configuration from `file`")
+	fAddr := flag.String("addr", "",
+		"listen (evaluator, BMR) or connect (garbler) address")
+	flag.StringVar(&timingOut, "timing-out", "",
+		"write timing report to `file` (JSON, or CSV with .csv suffix)")
 	flag.Parse()
 
 	verbose = *fVerbose
@@ -73,6 +92,47 @@ func main() {
 	var circ *circuit.Circuit
 	var err error
 
+	if len(*fSimnet) > 0 {
+		if *bmr >= 0 {
+			fmt.Printf("-simnet is not supported in the BMR mode\n")
+			os.Exit(1)
+		}
+		simnet, err = p2p.ParseSimNet(*fSimnet)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Simulated network: %s\n", simnet)
+	}
+
+	if len(*fPeers) > 0 {
+		peers, err = p2p.ReadConfig(*fPeers)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+		// The -addr flag overrides our listen address or, for the
+		// garbler, the evaluator address it connects to.
+		if len(*fAddr) > 0 {
+			id := evaluatorID
+			if *bmr >= 0 {
+				id = *bmr
+			}
+			peer, err := peers.Peer(id)
+			if err != nil {
+				fmt.Printf("%s\n", err)
+				os.Exit(1)
+			}
+			if *bmr >= 0 || *evaluator {
+				peer.Listen = *fAddr
+			} else {
+				peer.Addr = *fAddr
+			}
+		}
+	} else if len(*fAddr) > 0 {
+		addr = *fAddr
+	}
+
 	if len(*cpuprofile) > 0 {
 		f, err := os.Create(*cpuprofile)
 		if err != nil {
@@ -86,7 +146,8 @@ func main() {
 	}
 
 	params := &utils.Params{
-		Verbose: *fVerbose,
+		Verbose:        *fVerbose,
+		MaxInlineDepth: *maxInline,
 	}
 	defer params.Close()
 
@@ -203,7 +264,7 @@ func main() {
 		fmt.Printf(" - Out: %s\n", circ.Outputs)
 		fmt.Printf(" - In:  %s\n", inputFlag)
 
-		err := bmrMode(circ, input, *bmr)
+		err := bmrMode(circ, input, *bmr, *fAddr)
 		if err != nil {
 			fmt.Printf("BMR mode failed: %s\n", err)
 			os.Exit(1)
@@ -252,11 +313,11 @@ func main() {
 }
 
 func evaluatorMode(circ *circuit.Circuit, input *big.Int, once bool) error {
-	ln, err := net.Listen("tcp", port)
+	ln, err := listen()
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Listening for connections at %s\n", port)
+	fmt.Printf("Listening for connections at %s\n", ln.Addr())
 
 	for {
 		nc, err := ln.Accept()
@@ -265,13 +326,17 @@ func evaluatorMode(circ *circuit.Circuit, input *big.Int, once bool) error {
 		}
 		fmt.Printf("New connection from %s\n", nc.RemoteAddr())
 
-		conn := p2p.NewConn(nc)
-		result, err := circuit.Evaluator(conn, circ, input, verbose)
+		conn := newConn(nc)
+		result, t, err := circuit.EvaluatorWithTiming(conn, circ, input,
+			verbose)
 		conn.Close()
 
 		if err != nil && err != io.EOF {
 			return err
 		}
+		if err := saveTiming(t); err != nil {
+			return err
+		}
 
 		printResult(result, circ.Outputs)
 		if once {
@@ -281,22 +346,62 @@ func evaluatorMode(circ *circuit.Circuit, input *big.Int, once bool) error {
 }
 
 func garblerMode(circ *circuit.Circuit, input *big.Int) error {
-	nc, err := net.Dial("tcp", port)
+	nc, err := dial()
 	if err != nil {
 		return err
 	}
-	conn := p2p.NewConn(nc)
+	conn := newConn(nc)
 	defer conn.Close()
 
-	result, err := circuit.Garbler(conn, circ, input, verbose)
+	result, t, err := circuit.GarblerWithTiming(conn, circ, input, verbose)
 	if err != nil {
 		return err
 	}
+	if err := saveTiming(t); err != nil {
+		return err
+	}
 	printResult(result, circ.Outputs)
 
 	return nil
 }
 
+// listen creates the evaluator's listener. If the peer configuration
+// is set, the evaluator listens at its configured address and the
+// -addr flag overrides it.
+func listen() (net.Listener, error) {
+	if peers != nil {
+		return peers.Listen(evaluatorID)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// dial connects the garbler to the evaluator. If the peer
+// configuration is set, the garbler connects to the evaluator's
+// configured address and the -addr flag overrides it.
+func dial() (net.Conn, error) {
+	if peers != nil {
+		return peers.Dial(garblerID, evaluatorID)
+	}
+	return net.Dial("tcp", addr)
+}
+
+// saveTiming saves the timing report if the -timing-out flag is set.
+func saveTiming(t *circuit.Timing) error {
+	if len(timingOut) == 0 || t == nil {
+		return nil
+	}
+	return t.Save(timingOut)
+}
+
+// newConn creates a protocol connection for the network connection,
+// applying the simulated network conditions if they are set.
+func newConn(nc net.Conn) *p2p.Conn {
+	if simnet != nil {
+		return p2p.NewConn(simnet.Wrap(nc))
+	}
+	return p2p.NewConn(nc)
+}
+
 func printResult(results []*big.Int, outputs circuit.IO) {
 	for idx, result := range results {
 		if outputs == nil {
@@ -331,6 +436,31 @@ func printResult(results []*big.Int, outputs circuit.IO) {
 				} else {
 					fmt.Printf("Result[%d]: 0x%x\n", idx, bytes)
 				}
+			} else if strings.HasPrefix(output.Type, "float") &&
+				output.Size == 32 {
+				fmt.Printf("Result[%d]: %v\n", idx,
+					math.Float32frombits(uint32(result.Uint64())))
+			} else if strings.HasPrefix(output.Type, "float") &&
+				output.Size == 64 {
+				fmt.Printf("Result[%d]: %v\n", idx,
+					math.Float64frombits(result.Uint64()))
+			} else if strings.HasPrefix(output.Type, "int") &&
+				output.Size > 0 {
+				v := new(big.Int).Set(result)
+				if v.Bit(output.Size-1) != 0 {
+					v.Sub(v, new(big.Int).Lsh(big.NewInt(1),
+						uint(output.Size)))
+				}
+				fmt.Printf("Result[%d]: %v\n", idx, v)
+			} else if strings.HasPrefix(output.Type, "fixed") {
+				bits, frac, ok := circuit.ParseFixedType(output.Type)
+				if ok {
+					fmt.Printf("Result[%d]: %s\n", idx,
+						circuit.FormatFixed(result, bits, frac))
+				} else {
+					fmt.Printf("Result[%d]: %v (%s)\n", idx, result,
+						output.Type)
+				}
 			} else if strings.HasPrefix(output.Type, "bool") {
 				fmt.Printf("Result[%d]: %v\n", idx, result.Uint64() != 0)
 			} else {
@@ -9,21 +9,19 @@ package main
 import (
 	"fmt"
 	"io"
-	"net"
 	"strings"
 
 	"github.com/markkurossi/mpc/circuit"
 	"github.com/markkurossi/mpc/compiler"
 	"github.com/markkurossi/mpc/compiler/utils"
-	"github.com/markkurossi/mpc/p2p"
 )
 
 func streamEvaluatorMode(params *utils.Params, input input, once bool) error {
-	ln, err := net.Listen("tcp", port)
+	ln, err := listen()
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Listening for connections at %s\n", port)
+	fmt.Printf("Listening for connections at %s\n", ln.Addr())
 
 	for {
 		nc, err := ln.Accept()
@@ -32,13 +30,17 @@ func streamEvaluatorMode(params *utils.Params, input input, once bool) error {
 		}
 		fmt.Printf("New connection from %s\n", nc.RemoteAddr())
 
-		conn := p2p.NewConn(nc)
-		outputs, result, err := circuit.StreamEvaluator(conn, input, verbose)
+		conn := newConn(nc)
+		outputs, result, t, err := circuit.StreamEvaluatorWithTiming(conn,
+			input, verbose)
 		conn.Close()
 
 		if err != nil && err != io.EOF {
 			return err
 		}
+		if err := saveTiming(t); err != nil {
+			return err
+		}
 
 		printResult(result, outputs)
 		if once {
@@ -51,18 +53,21 @@ func streamGarblerMode(params *utils.Params, input input, args []string) error {
 	if len(args) != 1 || !strings.HasSuffix(args[0], ".mpcl") {
 		return fmt.Errorf("streaming mode takes single MPCL file")
 	}
-	nc, err := net.Dial("tcp", port)
+	nc, err := dial()
 	if err != nil {
 		return err
 	}
-	conn := p2p.NewConn(nc)
+	conn := newConn(nc)
 	defer conn.Close()
 
-	outputs, result, err := compiler.NewCompiler(params).StreamFile(
+	outputs, result, t, err := compiler.NewCompiler(params).StreamFileWithTiming(
 		conn, args[0], input)
 	if err != nil {
 		return err
 	}
+	if err := saveTiming(t); err != nil {
+		return err
+	}
 	printResult(result, outputs)
 	return nil
 }
@@ -8,7 +8,10 @@ package circuit
 
 import (
 	"fmt"
+	"math"
 	"math/big"
+	"strconv"
+	"strings"
 )
 
 // Operation specifies gate function.
@@ -67,12 +70,7 @@ func (io IOArg) Parse(inputs []string) (*big.Int, error) {
 				fmt.Errorf("invalid amount of arguments, got %d, expected 1",
 					len(inputs))
 		}
-		i := new(big.Int)
-		_, ok := i.SetString(inputs[0], 0)
-		if !ok {
-			return nil, fmt.Errorf("invalid input: %s", inputs[0])
-		}
-		return i, nil
+		return io.parseValue(inputs[0])
 	}
 	if len(inputs) != len(io.Compound) {
 		return nil,
@@ -84,11 +82,10 @@ func (io IOArg) Parse(inputs []string) (*big.Int, error) {
 	var offset int
 
 	for idx, arg := range io.Compound {
-		i := new(big.Int)
 		// XXX Type checks
-		_, ok := i.SetString(inputs[idx], 0)
-		if !ok {
-			return nil, fmt.Errorf("invalid input: %s", inputs[idx])
+		i, err := arg.parseValue(inputs[idx])
+		if err != nil {
+			return nil, err
 		}
 		i.Lsh(i, uint(offset))
 		result.Or(result, i)
@@ -98,6 +95,36 @@ func (io IOArg) Parse(inputs []string) (*big.Int, error) {
 	return result, nil
 }
 
+// parseValue parses the input value of the argument. Floating point
+// values are converted to their IEEE-754 bit patterns and fixed-point
+// values to their two's complement encodings.
+func (io IOArg) parseValue(input string) (*big.Int, error) {
+	i := new(big.Int)
+	if bits, frac, ok := ParseFixedType(io.Type); ok {
+		return ParseFixed(input, bits, frac)
+	}
+	if strings.HasPrefix(io.Type, "float") {
+		f, err := strconv.ParseFloat(input, io.Size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input: %s", input)
+		}
+		switch io.Size {
+		case 32:
+			i.SetUint64(uint64(math.Float32bits(float32(f))))
+		case 64:
+			i.SetUint64(math.Float64bits(f))
+		default:
+			return nil, fmt.Errorf("unsupported float size %d", io.Size)
+		}
+		return i, nil
+	}
+	_, ok := i.SetString(input, 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid input: %s", input)
+	}
+	return i, nil
+}
+
 // IO specifies circuit input and output arguments.
 type IO []IOArg
 
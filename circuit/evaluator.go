@@ -24,8 +24,20 @@ var (
 // Evaluator runs the evaluator on the P2P network.
 func Evaluator(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 	[]*big.Int, error) {
+	result, _, err := EvaluatorWithTiming(conn, circ, inputs, verbose)
+	return result, err
+}
+
+// EvaluatorWithTiming runs the evaluator on the P2P network and
+// returns the timing report of the computation.
+func EvaluatorWithTiming(conn *p2p.Conn, circ *Circuit, inputs *big.Int,
+	verbose bool) ([]*big.Int, *Timing, error) {
 
 	timing := NewTiming()
+	timing.SetIOStats(func() p2p.IOStats {
+		return conn.Stats
+	})
+	timing.SetCircuit(circ)
 
 	garbled := make([][]ot.Label, circ.NumGates)
 
@@ -35,7 +47,7 @@ func Evaluator(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 	}
 	key, err := conn.ReceiveData()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Receive garbled tables.
@@ -43,25 +55,26 @@ func Evaluator(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 	if verbose {
 		fmt.Printf(" - Receiving garbled circuit...\n")
 	}
+	conn.SetMsgType(p2p.MsgTables)
 	count, err := conn.ReceiveUint32()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if count != circ.NumGates {
-		return nil, fmt.Errorf("wrong number of gates: got %d, expected %d",
+		return nil, nil, fmt.Errorf("wrong number of gates: got %d, expected %d",
 			count, circ.NumGates)
 	}
 	for i := 0; i < circ.NumGates; i++ {
 		count, err := conn.ReceiveUint32()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		values := make([]ot.Label, count)
 		for j := 0; j < count; j++ {
 			v, err := conn.ReceiveLabel()
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			values[j] = v
 		}
@@ -71,22 +84,24 @@ func Evaluator(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 	wires := make([]ot.Label, circ.NumWires)
 
 	// Receive peer inputs.
+	conn.SetMsgType(p2p.MsgInputs)
 	for i := 0; i < circ.Inputs[0].Size; i++ {
 		label, err := conn.ReceiveLabel()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		wires[Wire(i)] = label
 	}
 
 	// Init oblivious transfer.
+	conn.SetMsgType(p2p.MsgOT)
 	pubN, err := conn.ReceiveData()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	pubE, err := conn.ReceiveUint32()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	pub := &rsa.PublicKey{
 		N: big.NewInt(0).SetBytes(pubN),
@@ -94,7 +109,7 @@ func Evaluator(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 	}
 	receiver, err := ot.NewReceiver(pub)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	ioStats := conn.Stats
 	timing.Sample("Recv", []string{FileSize(ioStats.Sum()).String()})
@@ -103,15 +118,16 @@ func Evaluator(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 	if verbose {
 		fmt.Printf(" - Querying our inputs...\n")
 	}
+	conn.SetMsgType(p2p.MsgOther)
 	var w int
 	for i := 0; i < circ.Inputs[1].Size; i++ {
 		if err := conn.SendUint32(OpOT); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		n, err := conn.Receive(receiver, uint(circ.Inputs[0].Size+w),
 			inputs.Bit(i))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		wires[Wire(circ.Inputs[0].Size+w)].SetBytes(n)
 		w++
@@ -126,7 +142,7 @@ func Evaluator(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 	}
 	err = circ.Eval(key[:], wires, garbled)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	timing.Sample("Eval", nil)
 
@@ -138,19 +154,21 @@ func Evaluator(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 	}
 
 	// Resolve result values.
+	conn.SetMsgType(p2p.MsgOther)
 	if err := conn.SendUint32(OpResult); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	conn.SetMsgType(p2p.MsgResults)
 	for _, l := range labels {
 		if err := conn.SendLabel(l); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 	conn.Flush()
 
 	result, err := conn.ReceiveData()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	raw := big.NewInt(0).SetBytes(result)
 
@@ -161,5 +179,5 @@ func Evaluator(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 		timing.Print(FileSize(conn.Stats.Sum()).String())
 	}
 
-	return circ.Outputs.Split(raw), nil
+	return circ.Outputs.Split(raw), timing, nil
 }
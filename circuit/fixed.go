@@ -0,0 +1,116 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package circuit
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+)
+
+var reFixedType = regexp.MustCompilePOSIX(
+	`^fixed([[:digit:]]+)\.([[:digit:]]+)$`)
+
+// ParseFixedType parses the fixed-point type name fixedN.F and
+// returns its size and the number of fractional bits.
+func ParseFixedType(name string) (bits, frac int, ok bool) {
+	m := reFixedType.FindStringSubmatch(name)
+	if m == nil {
+		return 0, 0, false
+	}
+	bits, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	frac, err = strconv.Atoi(m[2])
+	if err != nil {
+		return 0, 0, false
+	}
+	return bits, frac, true
+}
+
+// FixedFromRat converts the rational value v to the two's complement
+// encoding of a fixed-point value with size bits and frac fractional
+// bits. The value is rounded to the nearest representable value,
+// ties away from zero. The function returns an error if the value
+// does not fit into the fixed-point type.
+func FixedFromRat(v *big.Rat, bits, frac int) (*big.Int, error) {
+	scaled := new(big.Rat).Mul(v, new(big.Rat).SetInt(
+		new(big.Int).Lsh(big.NewInt(1), uint(frac))))
+	raw := roundRat(scaled)
+
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	if raw.Cmp(limit) >= 0 || raw.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return nil, fmt.Errorf("constant %s overflows fixed%d.%d",
+			v.RatString(), bits, frac)
+	}
+	if raw.Sign() < 0 {
+		raw.Add(raw, new(big.Int).Lsh(limit, 1))
+	}
+	return raw, nil
+}
+
+// FixedToRat converts the two's complement encoded fixed-point value
+// raw with size bits and frac fractional bits to a rational value.
+func FixedToRat(raw *big.Int, bits, frac int) *big.Rat {
+	v := new(big.Int).Set(raw)
+	if v.Bit(bits-1) != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+	}
+	return new(big.Rat).SetFrac(v,
+		new(big.Int).Lsh(big.NewInt(1), uint(frac)))
+}
+
+// ParseFixed parses the decimal input value into the two's complement
+// encoding of a fixed-point value with size bits and frac fractional
+// bits.
+func ParseFixed(input string, bits, frac int) (*big.Int, error) {
+	v, ok := new(big.Rat).SetString(input)
+	if !ok {
+		return nil, fmt.Errorf("invalid input: %s", input)
+	}
+	return FixedFromRat(v, bits, frac)
+}
+
+// FormatFixed formats the two's complement encoded fixed-point value
+// raw with size bits and frac fractional bits. The function returns
+// the shortest decimal representation that parses back to the same
+// value.
+func FormatFixed(raw *big.Int, bits, frac int) string {
+	v := FixedToRat(raw, bits, frac)
+	scale := big.NewInt(1)
+	ten := big.NewInt(10)
+	for digits := 0; ; digits++ {
+		d := roundRat(new(big.Rat).Mul(v, new(big.Rat).SetInt(scale)))
+		candidate := new(big.Rat).SetFrac(d, scale)
+		if candidate.Cmp(v) == 0 {
+			return candidate.FloatString(digits)
+		}
+		back, err := FixedFromRat(candidate, bits, frac)
+		if err == nil && back.Cmp(raw) == 0 {
+			return candidate.FloatString(digits)
+		}
+		scale.Mul(scale, ten)
+	}
+}
+
+// roundRat rounds v to the nearest integer, ties away from zero.
+func roundRat(v *big.Rat) *big.Int {
+	num := new(big.Int).Abs(v.Num())
+	den := v.Denom()
+
+	// (2*|num| + den) / (2*den)
+	n := new(big.Int).Lsh(num, 1)
+	n.Add(n, den)
+	d := new(big.Int).Lsh(den, 1)
+	r := n.Quo(n, d)
+	if v.Sign() < 0 {
+		r.Neg(r)
+	}
+	return r
+}
@@ -0,0 +1,57 @@
+//
+// fixed_test.go
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package circuit
+
+import (
+	"testing"
+)
+
+var fixedTests = []struct {
+	input  string
+	bits   int
+	frac   int
+	raw    uint64
+	output string
+}{
+	{"0", 32, 16, 0, "0"},
+	{"1.5", 32, 16, 0x18000, "1.5"},
+	{"-2.25", 32, 16, 0xfffdc000, "-2.25"},
+	{"0.1", 32, 16, 0x199a, "0.1"},
+	{"-7.3", 32, 16, 0xfff8b333, "-7.3"},
+	{"1e2", 16, 8, 0x6400, "100"},
+	{"0.001", 16, 8, 0x0000, "0"},
+	{"0.003", 16, 8, 0x0001, "0.004"},
+	{"-128", 16, 8, 0x8000, "-128"},
+	{"127.99", 16, 8, 0x7ffd, "127.99"},
+	{"-5", 8, 0, 0xfb, "-5"},
+}
+
+func TestFixed(t *testing.T) {
+	for _, test := range fixedTests {
+		raw, err := ParseFixed(test.input, test.bits, test.frac)
+		if err != nil {
+			t.Fatalf("ParseFixed(%s): %s", test.input, err)
+		}
+		if raw.Uint64() != test.raw {
+			t.Errorf("ParseFixed(%s): got %x, expected %x",
+				test.input, raw, test.raw)
+		}
+		str := FormatFixed(raw, test.bits, test.frac)
+		if str != test.output {
+			t.Errorf("FormatFixed(%x): got %s, expected %s",
+				raw, str, test.output)
+		}
+	}
+	for _, input := range []string{"128", "-128.01", "x"} {
+		_, err := ParseFixed(input, 16, 8)
+		if err == nil {
+			t.Errorf("ParseFixed(%s) succeeded", input)
+		}
+	}
+}
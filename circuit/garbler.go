@@ -46,6 +46,14 @@ func (s FileSize) String() string {
 // Garbler runs the garbler on the P2P network.
 func Garbler(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 	[]*big.Int, error) {
+	result, _, err := GarblerWithTiming(conn, circ, inputs, verbose)
+	return result, err
+}
+
+// GarblerWithTiming runs the garbler on the P2P network and returns
+// the timing report of the computation.
+func GarblerWithTiming(conn *p2p.Conn, circ *Circuit, inputs *big.Int,
+	verbose bool) ([]*big.Int, *Timing, error) {
 
 	timing := NewTiming()
 	if verbose {
@@ -55,12 +63,17 @@ func Garbler(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 	var key [32]byte
 	_, err := rand.Read(key[:])
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	timing.SetIOStats(func() p2p.IOStats {
+		return conn.Stats
+	})
+	timing.SetCircuit(circ)
+
 	garbled, err := circ.Garble(key[:])
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	timing.Sample("Garble", nil)
@@ -70,20 +83,21 @@ func Garbler(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 		fmt.Printf(" - Sending garbled circuit...\n")
 	}
 	if err := conn.SendData(key[:]); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Send garbled tables.
+	conn.SetMsgType(p2p.MsgTables)
 	if err := conn.SendUint32(len(garbled.Gates)); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	for _, data := range garbled.Gates {
 		if err := conn.SendUint32(len(data)); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		for _, d := range data {
 			if err := conn.SendLabel(d); err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		}
 	}
@@ -104,12 +118,13 @@ func Garbler(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 	}
 
 	// Send our inputs.
+	conn.SetMsgType(p2p.MsgInputs)
 	for idx, i := range n1 {
 		if verbose && false {
 			fmt.Printf("N1[%d]:\t%s\n", idx, i)
 		}
 		if err := conn.SendLabel(i); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 	ioStats := conn.Stats
@@ -121,17 +136,18 @@ func Garbler(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 	// Init oblivious transfer.
 	sender, err := ot.NewSender(2048)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Send our public key.
+	conn.SetMsgType(p2p.MsgOT)
 	pub := sender.PublicKey()
 	data := pub.N.Bytes()
 	if err := conn.SendData(data); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if err := conn.SendUint32(pub.E); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	conn.Flush()
 
@@ -151,19 +167,21 @@ func Garbler(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 	result := big.NewInt(0)
 
 	for !done {
+		conn.SetMsgType(p2p.MsgOther)
 		op, err := conn.ReceiveUint32()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		switch op {
 		case OpOT:
+			conn.SetMsgType(p2p.MsgOT)
 			bit, err := conn.ReceiveUint32()
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			if !allowedOTs[bit] {
-				return nil, fmt.Errorf("peer can't OT wire %d", bit)
+				return nil, nil, fmt.Errorf("peer can't OT wire %d", bit)
 			}
 			allowedOTs[bit] = false
 
@@ -174,42 +192,43 @@ func Garbler(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 
 			xfer, err := sender.NewTransfer(m0Data, m1Data)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 
 			x0, x1 := xfer.RandomMessages()
 			if err := conn.SendData(x0); err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			if err := conn.SendData(x1); err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			conn.Flush()
 
 			v, err := conn.ReceiveData()
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			xfer.ReceiveV(v)
 
 			m0p, m1p, err := xfer.Messages()
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			if err := conn.SendData(m0p); err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			if err := conn.SendData(m1p); err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			conn.Flush()
 			lastOT = time.Now()
 
 		case OpResult:
+			conn.SetMsgType(p2p.MsgResults)
 			for i := 0; i < circ.Outputs.Size(); i++ {
 				label, err := conn.ReceiveLabel()
 				if err != nil {
-					return nil, err
+					return nil, nil, err
 				}
 				wire := garbled.Wires[circ.NumWires-circ.Outputs.Size()+i]
 
@@ -219,14 +238,14 @@ func Garbler(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 				} else if label.Equal(wire.L1) {
 					bit = 1
 				} else {
-					return nil, fmt.Errorf("Unknown label %s for result %d",
+					return nil, nil, fmt.Errorf("Unknown label %s for result %d",
 						label, i)
 				}
 				result = big.NewInt(0).SetBit(result, i, bit)
 			}
 			data := result.Bytes()
 			if err := conn.SendData(data); err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			conn.Flush()
 			done = true
@@ -239,5 +258,5 @@ func Garbler(conn *p2p.Conn, circ *Circuit, inputs *big.Int, verbose bool) (
 		timing.Print(FileSize(conn.Stats.Sum()).String())
 	}
 
-	return circ.Outputs.Split(result), nil
+	return circ.Outputs.Split(result), timing, nil
 }
@@ -0,0 +1,67 @@
+//
+// local.go
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package circuit
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/markkurossi/mpc/p2p"
+)
+
+// RunLocal runs the garbler and the evaluator of the circuit in the
+// current process. The parties are connected with an in-memory
+// pipe. The function returns the results of both parties.
+func RunLocal(circ *Circuit, garblerInput, evaluatorInput *big.Int,
+	verbose bool) ([]*big.Int, []*big.Int, error) {
+
+	gConn, eConn, gRaw, eRaw := p2p.Pipe()
+
+	var gResult []*big.Int
+	done := make(chan error)
+
+	go func() {
+		var err error
+		gResult, err = Garbler(gConn, circ, garblerInput, verbose)
+		if err != nil {
+			// Unblock the evaluator.
+			gRaw.Close()
+		}
+		done <- err
+	}()
+
+	eResult, eErr := Evaluator(eConn, circ, evaluatorInput, verbose)
+	// Closing the pipe unblocks the garbler if the evaluator failed.
+	eRaw.Close()
+
+	gErr := <-done
+	gRaw.Close()
+
+	err := LocalError(gErr, eErr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gResult, eResult, nil
+}
+
+// LocalError combines the garbler and evaluator errors of an
+// in-process computation into one error.
+func LocalError(garblerErr, evaluatorErr error) error {
+	switch {
+	case garblerErr != nil && evaluatorErr != nil:
+		return fmt.Errorf("garbler: %s, evaluator: %s",
+			garblerErr, evaluatorErr)
+	case garblerErr != nil:
+		return fmt.Errorf("garbler: %s", garblerErr)
+	case evaluatorErr != nil:
+		return fmt.Errorf("evaluator: %s", evaluatorErr)
+	default:
+		return nil
+	}
+}
@@ -23,18 +23,28 @@ var key [32]byte
 // Player runs the BMR protocol client on the P2P network.
 func Player(nw *p2p.Network, circ *Circuit, inputs *big.Int, verbose bool) (
 	[]*big.Int, error) {
+	result, _, err := PlayerWithTiming(nw, circ, inputs, verbose)
+	return result, err
+}
+
+// PlayerWithTiming runs the BMR protocol client on the P2P network
+// and returns the timing report of the computation.
+func PlayerWithTiming(nw *p2p.Network, circ *Circuit, inputs *big.Int,
+	verbose bool) ([]*big.Int, *Timing, error) {
 
 	numPlayers := len(nw.Peers) + 1
 	player := nw.ID
 
 	timing := NewTiming()
+	timing.SetIOStats(nw.Stats)
+	timing.SetCircuit(circ)
 	if verbose {
 		fmt.Printf(" - Garbling...\n")
 	}
 
 	garbled, err := circ.Garble(key[:])
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	timing.Sample("Garble", nil)
@@ -104,7 +114,7 @@ func Player(nw *p2p.Network, circ *Circuit, inputs *big.Int, verbose bool) (
 	for i := 0; i < len(nw.Peers); i++ {
 		result := <-lambdaResults
 		if result.err != nil {
-			return nil, fmt.Errorf("OT-Lambda with peer %d failed: %s",
+			return nil, nil, fmt.Errorf("OT-Lambda with peer %d failed: %s",
 				result.peerID, result.err)
 		}
 		luv.Xor(luv, result.x1)
@@ -182,21 +192,21 @@ func Player(nw *p2p.Network, circ *Circuit, inputs *big.Int, verbose bool) (
 			default:
 				rand1, err := ot.NewLabel(rand.Reader)
 				if err != nil {
-					return nil, err
+					return nil, nil, err
 				}
 				X1LongAg[peerID][g] = rand1
 				Gs.Ag[player][g].Xor(rand1)
 
 				rand2, err := ot.NewLabel(rand.Reader)
 				if err != nil {
-					return nil, err
+					return nil, nil, err
 				}
 				X1LongBg[peerID][g] = rand2
 				Gs.Bg[player][g].Xor(rand2)
 
 				rand3, err := ot.NewLabel(rand.Reader)
 				if err != nil {
-					return nil, err
+					return nil, nil, err
 				}
 				X1LongCg[peerID][g] = rand3
 				Gs.Cg[player][g].Xor(rand3)
@@ -248,7 +258,7 @@ func Player(nw *p2p.Network, circ *Circuit, inputs *big.Int, verbose bool) (
 	for i := 0; i < len(nw.Peers); i++ {
 		result := <-rResults
 		if result.err != nil {
-			return nil, fmt.Errorf("OT-R with peer %d failed: %s",
+			return nil, nil, fmt.Errorf("OT-R with peer %d failed: %s",
 				result.peerID, result.err)
 		}
 		for g, gate := range circ.Gates {
@@ -309,7 +319,7 @@ func Player(nw *p2p.Network, circ *Circuit, inputs *big.Int, verbose bool) (
 	}
 	for _, result := range gResults {
 		if result.err != nil {
-			return nil, fmt.Errorf("Gate exchange with peer %d failed: %s",
+			return nil, nil, fmt.Errorf("Gate exchange with peer %d failed: %s",
 				result.peerID, result.err)
 		}
 		for p := 0; p < numPlayers; p++ {
@@ -365,7 +375,7 @@ func Player(nw *p2p.Network, circ *Circuit, inputs *big.Int, verbose bool) (
 
 	fmt.Printf("player not implemented yet\n")
 
-	return []*big.Int{new(big.Int)}, nil
+	return []*big.Int{new(big.Int)}, timing, nil
 }
 
 // OTLambdaResult contain oblivious transfer lambda results.
@@ -71,8 +71,20 @@ func (stream *StreamEval) InitCircuit(numWires, numTmpWires int) {
 // StreamEvaluator runs the stream evaluator on the connection.
 func StreamEvaluator(conn *p2p.Conn, inputFlag []string, verbose bool) (
 	IO, []*big.Int, error) {
+	outputs, result, _, err := StreamEvaluatorWithTiming(conn, inputFlag,
+		verbose)
+	return outputs, result, err
+}
+
+// StreamEvaluatorWithTiming runs the stream evaluator on the
+// connection and returns the timing report of the computation.
+func StreamEvaluatorWithTiming(conn *p2p.Conn, inputFlag []string,
+	verbose bool) (IO, []*big.Int, *Timing, error) {
 
 	timing := NewTiming()
+	timing.SetIOStats(func() p2p.IOStats {
+		return conn.Stats
+	})
 
 	// Receive program info.
 	if verbose {
@@ -80,43 +92,43 @@ func StreamEvaluator(conn *p2p.Conn, inputFlag []string, verbose bool) (
 	}
 	key, err := conn.ReceiveData()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	alg, err := aes.NewCipher(key)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	// Peer input.
 	in1, err := receiveArgument(conn)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	// Our input.
 	in2, err := receiveArgument(conn)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	inputs, err := in2.Parse(inputFlag)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	// Program outputs.
 	numOutputs, err := conn.ReceiveUint32()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	var outputs IO
 	for i := 0; i < numOutputs; i++ {
 		out, err := receiveArgument(conn)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		outputs = append(outputs, out)
 	}
 
 	numSteps, err := conn.ReceiveUint32()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	fmt.Printf(" - In1: %s\n", in1)
@@ -126,26 +138,28 @@ func StreamEvaluator(conn *p2p.Conn, inputFlag []string, verbose bool) (
 
 	streaming, err := NewStreamEval(key, in1.Size+in2.Size, outputs.Size())
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Receive peer inputs.
+	conn.SetMsgType(p2p.MsgInputs)
 	for w := 0; w < in1.Size; w++ {
 		label, err := conn.ReceiveLabel()
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		streaming.Set(false, w, label)
 	}
 
 	// Init oblivious transfer.
+	conn.SetMsgType(p2p.MsgOT)
 	pubN, err := conn.ReceiveData()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	pubE, err := conn.ReceiveUint32()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	pub := &rsa.PublicKey{
 		N: big.NewInt(0).SetBytes(pubN),
@@ -153,7 +167,7 @@ func StreamEvaluator(conn *p2p.Conn, inputFlag []string, verbose bool) (
 	}
 	receiver, err := ot.NewReceiver(pub)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	ioStats := conn.Stats
@@ -166,7 +180,7 @@ func StreamEvaluator(conn *p2p.Conn, inputFlag []string, verbose bool) (
 	for w := 0; w < in2.Size; w++ {
 		n, err := conn.Receive(receiver, uint(in1.Size+w), inputs.Bit(w))
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		var label ot.Label
 		label.SetBytes(n)
@@ -196,27 +210,29 @@ func StreamEvaluator(conn *p2p.Conn, inputFlag []string, verbose bool) (
 	lastReport := start
 loop:
 	for {
+		conn.SetMsgType(p2p.MsgOther)
 		op, err := conn.ReceiveUint32()
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		switch op {
 		case OpCircuit:
+			conn.SetMsgType(p2p.MsgTables)
 			step, err := conn.ReceiveUint32()
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 			numGates, err := conn.ReceiveUint32()
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 			numTmpWires, err := conn.ReceiveUint32()
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 			numWires, err := conn.ReceiveUint32()
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 			if step-lastStep >= 10 && verbose {
 				lastStep = step
@@ -241,7 +257,7 @@ loop:
 			for i := 0; i < numGates; i++ {
 				gop, err := conn.ReceiveByte()
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, nil, err
 				}
 				var aTmp, bTmp, cTmp bool
 				if gop&0b10000000 != 0 {
@@ -273,36 +289,36 @@ loop:
 				case XOR, XNOR:
 					aIndex, err = recvWire()
 					if err != nil {
-						return nil, nil, err
+						return nil, nil, nil, err
 					}
 					bIndex, err = recvWire()
 					if err != nil {
-						return nil, nil, err
+						return nil, nil, nil, err
 					}
 					cIndex, err = recvWire()
 					if err != nil {
-						return nil, nil, err
+						return nil, nil, nil, err
 					}
 
 				case INV:
 					count = 2
 					aIndex, err = recvWire()
 					if err != nil {
-						return nil, nil, err
+						return nil, nil, nil, err
 					}
 					cIndex, err = recvWire()
 					if err != nil {
-						return nil, nil, err
+						return nil, nil, nil, err
 					}
 				default:
-					return nil, nil, fmt.Errorf("invalid operation %s",
+					return nil, nil, nil, fmt.Errorf("invalid operation %s",
 						Operation(gop))
 				}
 
 				for c := 0; c < count; c++ {
 					garbled[c], err = conn.ReceiveLabel()
 					if err != nil {
-						return nil, nil, err
+						return nil, nil, nil, err
 					}
 				}
 
@@ -336,25 +352,25 @@ loop:
 				case AND, OR:
 					index := idx(a, b)
 					if index >= count {
-						return nil, nil,
+						return nil, nil, nil,
 							fmt.Errorf("corrupted circuit: index %d >= %d",
 								index, count)
 					}
 					output, err = decrypt(alg, a, b, uint32(i), garbled[index])
 					if err != nil {
-						return nil, nil, err
+						return nil, nil, nil, err
 					}
 
 				case INV:
 					index := idxUnary(a)
 					if index >= count {
-						return nil, nil,
+						return nil, nil, nil,
 							fmt.Errorf("corrupted circuit: index %d >= %d",
 								index, count)
 					}
 					output, err = decrypt(alg, a, b, uint32(i), garbled[index])
 					if err != nil {
-						return nil, nil, err
+						return nil, nil, nil, err
 					}
 				}
 				streaming.Set(cTmp, cIndex, output)
@@ -365,36 +381,39 @@ loop:
 			ioStats = conn.Stats
 			timing.Sample("Eval", []string{FileSize(xfer.Sum()).String()})
 
+			conn.SetMsgType(p2p.MsgResults)
 			var labels []ot.Label
 			for i := 0; i < outputs.Size(); i++ {
 				id, err := conn.ReceiveUint32()
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, nil, err
 				}
 				label := streaming.Get(false, id)
 				labels = append(labels, label)
 			}
 
 			// Resolve result values.
+			conn.SetMsgType(p2p.MsgOther)
 			if err := conn.SendUint32(OpResult); err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
+			conn.SetMsgType(p2p.MsgResults)
 			for _, l := range labels {
 				if err := conn.SendLabel(l); err != nil {
-					return nil, nil, err
+					return nil, nil, nil, err
 				}
 			}
 			conn.Flush()
 
 			result, err := conn.ReceiveData()
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 			rawResult = new(big.Int).SetBytes(result)
 			break loop
 
 		default:
-			return nil, nil, fmt.Errorf("unknown operation %d", op)
+			return nil, nil, nil, fmt.Errorf("unknown operation %d", op)
 		}
 	}
 
@@ -406,7 +425,7 @@ loop:
 		timing.Print(FileSize(conn.Stats.Sum()).String())
 	}
 
-	return outputs, outputs.Split(rawResult), nil
+	return outputs, outputs.Split(rawResult), timing, nil
 }
 
 func receiveArgument(conn *p2p.Conn) (arg IOArg, err error) {
@@ -11,6 +11,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/markkurossi/mpc/p2p"
 	"github.com/markkurossi/tabulate"
 )
 
@@ -18,6 +19,9 @@ import (
 type Timing struct {
 	Start   time.Time
 	Samples []*Sample
+	Circuit *CircuitStats
+	ioStats func() p2p.IOStats
+	lastIO  p2p.IOStats
 }
 
 // NewTiming creates a new Timing instance.
@@ -27,6 +31,13 @@ func NewTiming() *Timing {
 	}
 }
 
+// SetIOStats sets the I/O statistics source for the timing. If the
+// source is set, each sample records the I/O of its phase.
+func (t *Timing) SetIOStats(f func() p2p.IOStats) {
+	t.ioStats = f
+	t.lastIO = f()
+}
+
 // Sample adds a timing sample with label and data columns.
 func (t *Timing) Sample(label string, cols []string) *Sample {
 	start := t.Start
@@ -39,6 +50,12 @@ func (t *Timing) Sample(label string, cols []string) *Sample {
 		End:   time.Now(),
 		Cols:  cols,
 	}
+	if t.ioStats != nil {
+		stats := t.ioStats()
+		io := stats.Sub(t.lastIO)
+		sample.IO = &io
+		t.lastIO = stats
+	}
 	t.Samples = append(t.Samples, sample)
 	return sample
 }
@@ -55,7 +72,7 @@ func (t *Timing) Print(xfer string) {
 	tab.Header("%").SetAlign(tabulate.MR)
 	tab.Header("Xfer").SetAlign(tabulate.MR)
 
-	total := t.Samples[len(t.Samples)-1].End.Sub(t.Start)
+	total := t.Total()
 	for _, sample := range t.Samples {
 		row := tab.Row()
 		row.Column(sample.Label)
@@ -73,12 +90,7 @@ func (t *Timing) Print(xfer string) {
 			row := tab.Row()
 			row.Column(sub.Label).SetFormat(tabulate.FmtItalic)
 
-			var d time.Duration
-			if sub.Abs > 0 {
-				d = sub.Abs
-			} else {
-				d = sub.End.Sub(sub.Start)
-			}
+			d := sub.Duration()
 			row.Column(d.String()).SetFormat(tabulate.FmtItalic)
 
 			row.Column(
@@ -89,12 +101,66 @@ func (t *Timing) Print(xfer string) {
 	}
 	row := tab.Row()
 	row.Column("Total").SetFormat(tabulate.FmtBold)
-	row.Column(t.Samples[len(t.Samples)-1].End.Sub(t.Start).String()).
+	row.Column(total.String()).
 		SetFormat(tabulate.FmtBold)
 	row.Column("").SetFormat(tabulate.FmtBold)
 	row.Column(xfer).SetFormat(tabulate.FmtBold)
 
 	tab.Print(os.Stdout)
+
+	if t.ioStats != nil {
+		t.printIO()
+	}
+}
+
+func (t *Timing) printIO() {
+	tab := tabulate.New(tabulate.Unicode)
+	tab.Header("Op").SetAlign(tabulate.ML)
+	tab.Header("Type").SetAlign(tabulate.ML)
+	tab.Header("Sent").SetAlign(tabulate.MR)
+	tab.Header("Rcvd").SetAlign(tabulate.MR)
+	tab.Header("Msgs").SetAlign(tabulate.MR)
+	tab.Header("Flushes").SetAlign(tabulate.MR)
+
+	var total p2p.IOStats
+	for _, sample := range t.Samples {
+		if sample.IO == nil {
+			continue
+		}
+		total = total.Add(*sample.IO)
+		label := sample.Label
+		for i, stats := range sample.IO.Types {
+			if stats.Zero() {
+				continue
+			}
+			row := tab.Row()
+			row.Column(label)
+			label = ""
+			ioRow(row, p2p.MsgType(i), stats, tabulate.FmtNone)
+		}
+	}
+	label := "Total"
+	for i, stats := range total.Types {
+		if stats.Zero() {
+			continue
+		}
+		row := tab.Row()
+		row.Column(label).SetFormat(tabulate.FmtBold)
+		label = ""
+		ioRow(row, p2p.MsgType(i), stats, tabulate.FmtBold)
+	}
+	tab.Print(os.Stdout)
+}
+
+func ioRow(row *tabulate.Row, t p2p.MsgType, stats p2p.MsgStats,
+	format tabulate.Format) {
+
+	row.Column(t.String()).SetFormat(format)
+	row.Column(FileSize(stats.Sent).String()).SetFormat(format)
+	row.Column(FileSize(stats.Recvd).String()).SetFormat(format)
+	row.Column(fmt.Sprintf("%d/%d", stats.SentMsgs, stats.RecvdMsgs)).
+		SetFormat(format)
+	row.Column(fmt.Sprintf("%d", stats.Flushes)).SetFormat(format)
 }
 
 // Sample contains information about one timing sample.
@@ -105,6 +171,7 @@ type Sample struct {
 	Abs     time.Duration
 	Cols    []string
 	Samples []*Sample
+	IO      *p2p.IOStats
 }
 
 // SubSample adds a sub-sample for a timing sample.
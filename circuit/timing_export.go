@@ -0,0 +1,264 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package circuit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/markkurossi/mpc/p2p"
+)
+
+// CircuitStats contains the circuit statistics of a timing report.
+type CircuitStats struct {
+	NumGates  int            `json:"gates"`
+	NumWires  int            `json:"wires,omitempty"`
+	NumNonXOR int            `json:"non_xor"`
+	Ops       map[string]int `json:"ops,omitempty"`
+}
+
+// SetCircuit sets the circuit statistics from the circuit.
+func (t *Timing) SetCircuit(circ *Circuit) {
+	stats := &CircuitStats{
+		NumGates: circ.NumGates,
+		NumWires: circ.NumWires,
+		Ops:      make(map[string]int),
+	}
+	for op, count := range circ.Stats {
+		stats.Ops[op.String()] = count
+		if op != XOR && op != XNOR {
+			stats.NumNonXOR += count
+		}
+	}
+	t.Circuit = stats
+}
+
+// IO returns the total I/O statistics of the timing samples.
+func (t *Timing) IO() p2p.IOStats {
+	var total p2p.IOStats
+	for _, sample := range t.Samples {
+		if sample.IO != nil {
+			total = total.Add(*sample.IO)
+		}
+	}
+	return total
+}
+
+// Total returns the total duration of the timing samples.
+func (t *Timing) Total() time.Duration {
+	if len(t.Samples) == 0 {
+		return 0
+	}
+	return t.Samples[len(t.Samples)-1].End.Sub(t.Start)
+}
+
+// Duration returns the duration of the sample.
+func (s *Sample) Duration() time.Duration {
+	if s.Abs > 0 {
+		return s.Abs
+	}
+	return s.End.Sub(s.Start)
+}
+
+type timingJSON struct {
+	Start   time.Time     `json:"start"`
+	Total   time.Duration `json:"total_ns"`
+	Samples []*sampleJSON `json:"samples"`
+	Circuit *CircuitStats `json:"circuit,omitempty"`
+	IO      *p2p.IOStats  `json:"io,omitempty"`
+}
+
+type sampleJSON struct {
+	Label    string        `json:"label"`
+	Duration time.Duration `json:"duration_ns"`
+	Cols     []string      `json:"cols,omitempty"`
+	IO       *p2p.IOStats  `json:"io,omitempty"`
+	Samples  []*sampleJSON `json:"samples,omitempty"`
+}
+
+func newSampleJSON(s *Sample) *sampleJSON {
+	result := &sampleJSON{
+		Label:    s.Label,
+		Duration: s.Duration(),
+		Cols:     s.Cols,
+		IO:       s.IO,
+	}
+	for _, sub := range s.Samples {
+		result.Samples = append(result.Samples, newSampleJSON(sub))
+	}
+	return result
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t *Timing) MarshalJSON() ([]byte, error) {
+	data := &timingJSON{
+		Start:   t.Start,
+		Total:   t.Total(),
+		Circuit: t.Circuit,
+	}
+	for _, sample := range t.Samples {
+		data.Samples = append(data.Samples, newSampleJSON(sample))
+	}
+	if t.ioStats != nil {
+		io := t.IO()
+		data.IO = &io
+	}
+	return json.Marshal(data)
+}
+
+// JSON writes the timing report in JSON to the writer.
+func (t *Timing) JSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// CSV writes the timing report in CSV to the writer. The report has
+// one row for each sample, sub-sample, message category of the
+// sample I/O, and circuit statistics value. The first column
+// specifies the row type. The total row contains the total I/O of
+// the samples and it is followed by the I/O rows of the total
+// message categories.
+func (t *Timing) CSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	err := out.Write([]string{
+		"type", "label", "sub", "duration_ns", "sent", "recvd", "sent_msgs",
+		"recvd_msgs", "flushes", "value",
+	})
+	if err != nil {
+		return err
+	}
+	for _, sample := range t.Samples {
+		row := []string{
+			"sample", sample.Label, "", fmt.Sprint(int64(sample.Duration())),
+			"", "", "", "", "", "",
+		}
+		if sample.IO != nil {
+			setIOColumns(row, *sample.IO)
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+		for _, sub := range sample.Samples {
+			err := out.Write([]string{
+				"subsample", sample.Label, sub.Label,
+				fmt.Sprint(int64(sub.Duration())), "", "", "", "", "", "",
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if sample.IO != nil {
+			if err := writeIOTypes(out, sample.Label, *sample.IO); err != nil {
+				return err
+			}
+		}
+	}
+	row := []string{
+		"total", "Total", "", fmt.Sprint(int64(t.Total())),
+		"", "", "", "", "", "",
+	}
+	if t.ioStats != nil {
+		setIOColumns(row, t.IO())
+	}
+	if err := out.Write(row); err != nil {
+		return err
+	}
+	if t.ioStats != nil {
+		if err := writeIOTypes(out, "Total", t.IO()); err != nil {
+			return err
+		}
+	}
+	if t.Circuit != nil {
+		values := []struct {
+			name  string
+			value int
+		}{
+			{"gates", t.Circuit.NumGates},
+			{"wires", t.Circuit.NumWires},
+			{"non_xor", t.Circuit.NumNonXOR},
+		}
+		for op := XOR; op <= INV; op++ {
+			count, ok := t.Circuit.Ops[op.String()]
+			if ok {
+				values = append(values, struct {
+					name  string
+					value int
+				}{op.String(), count})
+			}
+		}
+		for _, v := range values {
+			err := out.Write([]string{
+				"circuit", v.name, "", "", "", "", "", "", "",
+				fmt.Sprint(v.value),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// setIOColumns sets the I/O columns of the CSV row from the
+// stats. The message counts are the sums of the message categories.
+func setIOColumns(row []string, stats p2p.IOStats) {
+	var msgs p2p.MsgStats
+	for _, s := range stats.Types {
+		msgs = msgs.Add(s)
+	}
+	row[4] = fmt.Sprint(stats.Sent)
+	row[5] = fmt.Sprint(stats.Recvd)
+	row[6] = fmt.Sprint(msgs.SentMsgs)
+	row[7] = fmt.Sprint(msgs.RecvdMsgs)
+	row[8] = fmt.Sprint(stats.Flushes)
+}
+
+// writeIOTypes writes an I/O row for each non-zero message category
+// of the stats.
+func writeIOTypes(out *csv.Writer, label string, stats p2p.IOStats) error {
+	for i, s := range stats.Types {
+		if s.Zero() {
+			continue
+		}
+		err := out.Write([]string{
+			"io", label, p2p.MsgType(i).String(), "",
+			fmt.Sprint(s.Sent), fmt.Sprint(s.Recvd),
+			fmt.Sprint(s.SentMsgs), fmt.Sprint(s.RecvdMsgs),
+			fmt.Sprint(s.Flushes), "",
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save saves the timing report to the file. The report is written
+// in CSV if the file name has the .csv suffix and in JSON otherwise.
+func (t *Timing) Save(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(strings.ToLower(file), ".csv") {
+		err = t.CSV(f)
+	} else {
+		err = t.JSON(f)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,141 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package circuit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/markkurossi/mpc/p2p"
+)
+
+func TestTimingExport(t *testing.T) {
+	var stats p2p.IOStats
+
+	timing := NewTiming()
+	timing.SetIOStats(func() p2p.IOStats {
+		return stats
+	})
+	timing.Circuit = &CircuitStats{
+		NumGates:  10,
+		NumNonXOR: 4,
+	}
+
+	stats.Sent = 100
+	stats.Types[p2p.MsgTables].Sent = 100
+	stats.Types[p2p.MsgTables].SentMsgs = 5
+	timing.Sample("Xfer", []string{"100B"})
+
+	stats.Recvd = 20
+	stats.Flushes = 2
+	stats.Types[p2p.MsgOT].Recvd = 20
+	stats.Types[p2p.MsgOT].RecvdMsgs = 3
+	stats.Types[p2p.MsgOT].Flushes = 2
+	timing.Sample("Eval", nil).AbsSubSample("OT", time.Millisecond)
+
+	var buf bytes.Buffer
+	if err := timing.JSON(&buf); err != nil {
+		t.Fatalf("JSON failed: %s", err)
+	}
+	var report struct {
+		Samples []struct {
+			Label   string
+			IO      map[string]interface{}
+			Samples []struct {
+				Label    string
+				Duration int64 `json:"duration_ns"`
+			}
+		}
+		Circuit struct {
+			NumGates int `json:"gates"`
+		}
+		IO struct {
+			Sent    uint64
+			Recvd   uint64
+			Flushes uint64
+			Types   map[string]p2p.MsgStats
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %s", err)
+	}
+	if len(report.Samples) != 2 || report.Samples[1].Label != "Eval" {
+		t.Fatalf("unexpected samples: %v", report.Samples)
+	}
+	sub := report.Samples[1].Samples
+	if len(sub) != 1 || sub[0].Duration != int64(time.Millisecond) {
+		t.Errorf("unexpected sub-samples: %v", sub)
+	}
+	if report.Circuit.NumGates != 10 {
+		t.Errorf("unexpected gates: %d", report.Circuit.NumGates)
+	}
+	if report.IO.Sent != 100 || report.IO.Recvd != 20 ||
+		report.IO.Flushes != 2 {
+		t.Errorf("unexpected total I/O: %+v", report.IO)
+	}
+	if report.IO.Types["Garbled tables"].SentMsgs != 5 {
+		t.Errorf("unexpected I/O types: %v", report.IO.Types)
+	}
+
+	buf.Reset()
+	if err := timing.CSV(&buf); err != nil {
+		t.Fatalf("CSV failed: %s", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %s", err)
+	}
+	counts := make(map[string]int)
+	rows := make(map[string][]string)
+	for _, r := range records[1:] {
+		counts[r[0]]++
+		rows[strings.Join(r[:3], "/")] = r
+	}
+	expected := map[string]int{
+		"sample":    2,
+		"subsample": 1,
+		"io":        4,
+		"total":     1,
+		"circuit":   3,
+	}
+	for k, v := range expected {
+		if counts[k] != v {
+			t.Errorf("CSV rows %s: got %d, expected %d", k, counts[k], v)
+		}
+	}
+
+	// The I/O columns are sent, recvd, sent_msgs, recvd_msgs, and
+	// flushes.
+	values := map[string][]string{
+		"sample/Xfer/":            {"100", "0", "5", "0", "0"},
+		"sample/Eval/":            {"0", "20", "0", "3", "2"},
+		"io/Xfer/Garbled tables":  {"100", "0", "5", "0", "0"},
+		"io/Eval/OT":              {"0", "20", "0", "3", "2"},
+		"total/Total/":            {"100", "20", "5", "3", "2"},
+		"io/Total/Garbled tables": {"100", "0", "5", "0", "0"},
+		"io/Total/OT":             {"0", "20", "0", "3", "2"},
+		"subsample/Eval/OT":       {"", "", "", "", ""},
+		"circuit/non_xor/":        {"", "", "", "", ""},
+	}
+	for k, v := range values {
+		r, ok := rows[k]
+		if !ok {
+			t.Errorf("CSV row %s not found", k)
+			continue
+		}
+		if strings.Join(r[4:9], ",") != strings.Join(v, ",") {
+			t.Errorf("CSV row %s: got %v, expected %v", k, r[4:9], v)
+		}
+	}
+	if r := rows["circuit/non_xor/"]; r != nil && r[9] != "4" {
+		t.Errorf("CSV non_xor: got %s, expected 4", r[9])
+	}
+}
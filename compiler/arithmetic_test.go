@@ -8,13 +8,11 @@ package compiler
 
 import (
 	"fmt"
-	"io"
 	"math/big"
 	"testing"
 
 	"github.com/markkurossi/mpc/circuit"
 	"github.com/markkurossi/mpc/compiler/utils"
-	"github.com/markkurossi/mpc/p2p"
 )
 
 type Test struct {
@@ -113,27 +111,12 @@ func TestArithmetics(t *testing.T) {
 
 		for g := 0; g < limit; g++ {
 			for e := 0; e < limit; e++ {
-				gr, ew := io.Pipe()
-				er, gw := io.Pipe()
-
-				gio := newReadWriter(gr, gw)
-				eio := newReadWriter(er, ew)
-
 				gInput := big.NewInt(int64(g))
 				eInput := big.NewInt(int64(e))
 
-				go func() {
-					_, err := circuit.Garbler(p2p.NewConn(gio), circ,
-						gInput, false)
-					if err != nil {
-						t.Fatalf("Garbler failed: %s\n", err)
-					}
-				}()
-
-				result, err := circuit.Evaluator(p2p.NewConn(eio), circ,
-					eInput, false)
+				_, result, err := circuit.RunLocal(circ, gInput, eInput, false)
 				if err != nil {
-					t.Fatalf("Evaluator failed: %s\n", err)
+					t.Fatalf("RunLocal failed: %s\n", err)
 				}
 
 				expected := test.Eval(gInput, eInput)
@@ -161,44 +144,192 @@ func BenchmarkMult(b *testing.B) {
 		b.Fatalf("failed to compile test: %s", err)
 	}
 
-	gr, ew := io.Pipe()
-	er, gw := io.Pipe()
-
-	gio := newReadWriter(gr, gw)
-	eio := newReadWriter(er, ew)
-
 	gInput := big.NewInt(int64(11))
 	eInput := big.NewInt(int64(13))
 
-	go func() {
-		_, err := circuit.Garbler(p2p.NewConn(gio), circ, gInput, false)
-		if err != nil {
-			b.Fatalf("Garbler failed: %s\n", err)
-		}
-	}()
-
-	_, err = circuit.Evaluator(p2p.NewConn(eio), circ, eInput, false)
+	_, _, err = circuit.RunLocal(circ, gInput, eInput, false)
 	if err != nil {
-		b.Fatalf("Evaluator failed: %s\n", err)
+		b.Fatalf("RunLocal failed: %s\n", err)
 	}
 }
 
-func newReadWriter(in io.Reader, out io.Writer) io.ReadWriter {
-	return &wrap{
-		in:  in,
-		out: out,
-	}
+type SignedTest struct {
+	Name    string
+	Operand string
+	Bits    int
+	Eval    func(a int64, b int64) int64
+	Code    string
+}
+
+var signedTests = []SignedTest{
+	{
+		Name:    "Div",
+		Operand: "/",
+		Bits:    5,
+		Eval: func(a int64, b int64) int64 {
+			return a / b
+		},
+		Code: `
+package main
+func main(a, b int5) int5 {
+    return a / b
+}
+`,
+	},
+	{
+		Name:    "Mod",
+		Operand: "%",
+		Bits:    5,
+		Eval: func(a int64, b int64) int64 {
+			return a % b
+		},
+		Code: `
+package main
+func main(a, b int5) int5 {
+    return a % b
+}
+`,
+	},
+	{
+		Name:    "Div const",
+		Operand: "/",
+		Bits:    6,
+		Eval: func(a int64, b int64) int64 {
+			return a/-3 + b%5
+		},
+		Code: `
+package main
+func main(a, b int6) int6 {
+    return a/-3 + b%5
+}
+`,
+	},
+	{
+		Name:    "Lt",
+		Operand: "<",
+		Bits:    5,
+		Eval: func(a int64, b int64) int64 {
+			return boolInt(a < b)
+		},
+		Code: `
+package main
+func main(a, b int5) bool {
+    return a < b
+}
+`,
+	},
+	{
+		Name:    "Le",
+		Operand: "<=",
+		Bits:    5,
+		Eval: func(a int64, b int64) int64 {
+			return boolInt(a <= b)
+		},
+		Code: `
+package main
+func main(a, b int5) bool {
+    return a <= b
+}
+`,
+	},
+	{
+		Name:    "Gt",
+		Operand: ">",
+		Bits:    5,
+		Eval: func(a int64, b int64) int64 {
+			return boolInt(a > b)
+		},
+		Code: `
+package main
+func main(a, b int5) bool {
+    return a > b
+}
+`,
+	},
+	{
+		Name:    "Ge",
+		Operand: ">=",
+		Bits:    5,
+		Eval: func(a int64, b int64) int64 {
+			return boolInt(a >= b)
+		},
+		Code: `
+package main
+func main(a, b int5) bool {
+    return a >= b
+}
+`,
+	},
+	{
+		Name:    "Negative const",
+		Operand: "<",
+		Bits:    8,
+		Eval: func(a int64, b int64) int64 {
+			var r int8 = -1
+			if a < -100 || b == -2 {
+				r = int8(a) * -3
+			}
+			return int64(r)
+		},
+		Code: `
+package main
+func main(a, b int8) int8 {
+    var r int8 = -1
+    if a < -100 || b == -2 {
+        r = a * -3
+    }
+    return r
+}
+`,
+	},
 }
 
-type wrap struct {
-	in  io.Reader
-	out io.Writer
+func boolInt(v bool) int64 {
+	if v {
+		return 1
+	}
+	return 0
 }
 
-func (w *wrap) Read(p []byte) (n int, err error) {
-	return w.in.Read(p)
+func TestSignedArithmetics(t *testing.T) {
+	for _, test := range signedTests {
+		circ, _, err := NewCompiler(&utils.Params{}).Compile(test.Code)
+		if err != nil {
+			t.Fatalf("Failed to compile test %s: %s", test.Name, err)
+		}
+		size := circ.Outputs[0].Size
+		mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(size)),
+			big.NewInt(1))
+
+		limit := int64(1) << test.Bits
+		for g := int64(0); g < limit; g++ {
+			for e := int64(0); e < limit; e++ {
+				a := signExtend(g, test.Bits)
+				b := signExtend(e, test.Bits)
+				if b == 0 && (test.Operand == "/" || test.Operand == "%") {
+					continue
+				}
+				results, err := circ.Compute([]*big.Int{
+					big.NewInt(g), big.NewInt(e),
+				})
+				if err != nil {
+					t.Fatalf("%s: compute failed: %s", test.Name, err)
+				}
+				expected := big.NewInt(test.Eval(a, b))
+				expected.And(expected, mask)
+
+				if expected.Cmp(results[0]) != 0 {
+					t.Errorf("%s failed: %d %s %d = %s, expected %s",
+						test.Name, a, test.Operand, b, results[0], expected)
+				}
+			}
+		}
+	}
 }
 
-func (w *wrap) Write(p []byte) (n int, err error) {
-	return w.out.Write(p)
+func signExtend(v int64, bits int) int64 {
+	if v&(1<<(bits-1)) != 0 {
+		return v - 1<<bits
+	}
+	return v
 }
@@ -15,6 +15,7 @@ import (
 	"regexp"
 	"strconv"
 
+	"github.com/markkurossi/mpc/circuit"
 	"github.com/markkurossi/mpc/compiler/ssa"
 	"github.com/markkurossi/mpc/compiler/types"
 	"github.com/markkurossi/mpc/compiler/utils"
@@ -27,13 +28,21 @@ var (
 	_ AST = &VariableDef{}
 	_ AST = &Assign{}
 	_ AST = &If{}
+	_ AST = &Switch{}
 	_ AST = &Call{}
 	_ AST = &Return{}
 	_ AST = &For{}
+	_ AST = &Break{}
+	_ AST = &Continue{}
 	_ AST = &Binary{}
+	_ AST = &Unary{}
 	_ AST = &Slice{}
+	_ AST = &Index{}
+	_ AST = &Selector{}
 	_ AST = &VariableRef{}
 	_ AST = &Constant{}
+	_ AST = &CompositeLit{}
+	_ AST = &valueRef{}
 )
 
 func indent(w io.Writer, indent int) {
@@ -52,6 +61,7 @@ const (
 	TypeSlice
 	TypeStruct
 	TypeAlias
+	TypeDefined
 )
 
 // TypeInfo contains AST type information.
@@ -62,7 +72,7 @@ type TypeInfo struct {
 	ArrayLength  AST
 	TypeName     string
 	StructFields []StructField
-	AliasType    *TypeInfo
+	AliasType    *TypeInfo // Aliased or underlying type
 }
 
 // StructField contains AST structure field information.
@@ -85,7 +95,33 @@ func (ti *TypeInfo) Resolve(env *Env, ctx *Codegen, gen *ssa.Generator) (
 	}
 	switch ti.Type {
 	case TypeName:
-		// XXX package
+		if len(ti.Name.Package) > 0 {
+			// Qualified type name.
+			pkg, ok := ctx.Packages[ti.Name.Package]
+			if !ok {
+				return result, fmt.Errorf("package '%s' not found",
+					ti.Name.Package)
+			}
+			b, ok := pkg.Bindings.Get(ti.Name.Name)
+			if ok {
+				val, ok := b.Bound.(*ssa.Variable)
+				if ok && val.TypeRef {
+					return val.Type, nil
+				}
+			}
+			return result, fmt.Errorf("unknown type %s", ti)
+		}
+		bits, frac, ok := circuit.ParseFixedType(ti.Name.Name)
+		if ok {
+			if bits == 0 || frac >= bits {
+				return result, fmt.Errorf("invalid fixed-point type %s", ti)
+			}
+			return types.Info{
+				Type:     types.Fixed,
+				Bits:     bits,
+				Fraction: frac,
+			}, nil
+		}
 		matches := reSizedType.FindStringSubmatch(ti.Name.Name)
 		if matches != nil {
 			tt, ok := types.Types[matches[1]]
@@ -100,6 +136,10 @@ func (ti *TypeInfo) Resolve(env *Env, ctx *Codegen, gen *ssa.Generator) (
 					// Undefined size.
 					bits = 0
 				}
+				if tt == types.Float && bits != 0 && bits != 32 && bits != 64 {
+					return result, fmt.Errorf("unsupported float size %d",
+						bits)
+				}
 				return types.Info{
 					Type: tt,
 					Bits: bits,
@@ -112,6 +152,12 @@ func (ti *TypeInfo) Resolve(env *Env, ctx *Codegen, gen *ssa.Generator) (
 				Bits: 1,
 			}, nil
 		}
+		if ti.Name.Name == "byte" {
+			return types.Info{
+				Type: types.Uint,
+				Bits: 8,
+			}, nil
+		}
 		// Check dynamic types from the env.
 		b, ok := env.Get(ti.Name.Name)
 		if ok {
@@ -122,6 +168,38 @@ func (ti *TypeInfo) Resolve(env *Env, ctx *Codegen, gen *ssa.Generator) (
 		}
 		return result, fmt.Errorf("unknown type %s", ti)
 
+	case TypeArray:
+		elType, err := ti.ElementType.Resolve(env, ctx, gen)
+		if err != nil {
+			return result, err
+		}
+		if elType.Bits == 0 {
+			return result, fmt.Errorf(
+				"array element type %s has unspecified size", ti.ElementType)
+		}
+		constLength, ok, err := ti.ArrayLength.Eval(env, ctx, gen)
+		if err != nil {
+			return result, err
+		}
+		if !ok {
+			return result, fmt.Errorf("array length is not constant: %s",
+				ti.ArrayLength)
+		}
+		var length int
+		switch l := constLength.(type) {
+		case int32:
+			length = int(l)
+		case uint64:
+			length = int(l)
+		default:
+			return result, fmt.Errorf("invalid array length: %s",
+				ti.ArrayLength)
+		}
+		if length <= 0 {
+			return result, fmt.Errorf("invalid array length: %d", length)
+		}
+		return types.ArrayType(elType, length), nil
+
 	default:
 		return result, fmt.Errorf("unsupported type %s", ti)
 	}
@@ -151,6 +229,9 @@ func (ti *TypeInfo) String() string {
 	case TypeAlias:
 		return fmt.Sprintf("%s=%s", ti.TypeName, ti.AliasType)
 
+	case TypeDefined:
+		return fmt.Sprintf("%s %s", ti.TypeName, ti.AliasType)
+
 	default:
 		return fmt.Sprintf("{TypeInfo %d}", ti.Type)
 	}
@@ -231,10 +312,15 @@ type Variable struct {
 	Type *TypeInfo
 }
 
-// Func implements an AST function.
+// Func implements an AST function. Methods have the Receiver
+// argument and their Name is qualified with the receiver type name,
+// for example Point.Add. Generic functions have the TypeParams and
+// they are instantiated separately for each call site.
 type Func struct {
 	Loc          utils.Point
 	Name         string
+	TypeParams   []*TypeParam
+	Receiver     *Variable
 	Args         []*Variable
 	Return       []*Variable
 	Body         List
@@ -242,6 +328,27 @@ type Func struct {
 	Annotations  Annotations
 }
 
+// TypeParam implements a type parameter of a generic function. The
+// Constraint lists the types of a type union or it names one of the
+// predeclared constraints, such as any or Ordered. The parameters
+// with the int constraint are array length parameters.
+type TypeParam struct {
+	Loc        utils.Point
+	Name       string
+	Constraint []*TypeInfo
+}
+
+func (tp *TypeParam) String() string {
+	var constraint string
+	for idx, t := range tp.Constraint {
+		if idx > 0 {
+			constraint += " | "
+		}
+		constraint += t.String()
+	}
+	return fmt.Sprintf("%s %s", tp.Name, constraint)
+}
+
 // Annotations specify function annotations.
 type Annotations []string
 
@@ -268,12 +375,15 @@ func (ast *Func) Location() utils.Point {
 	return ast.Loc
 }
 
-// ConstantDef implements an AST constant definition.
+// ConstantDef implements an AST constant definition. The Iota
+// specifies the index of the constant in its const declaration
+// block.
 type ConstantDef struct {
 	Loc  utils.Point
 	Name string
 	Type *TypeInfo
 	Init AST
+	Iota int
 }
 
 func (ast *ConstantDef) String() string {
@@ -357,14 +467,82 @@ func (ast *If) Location() utils.Point {
 	return ast.Loc
 }
 
-// Call implements an AST call expression.
-type Call struct {
+// Switch implements an AST switch statement. The Expr is nil for
+// switch statements without a tag expression.
+type Switch struct {
+	Loc   utils.Point
+	Expr  AST
+	Cases []*Case
+}
+
+func (ast *Switch) String() string {
+	if ast.Expr == nil {
+		return "switch"
+	}
+	return fmt.Sprintf("switch %s", ast.Expr)
+}
+
+// Location implements the compiler.ast.AST.Location for switch
+// statements.
+func (ast *Switch) Location() utils.Point {
+	return ast.Loc
+}
+
+// Case implements a switch statement case clause. The Exprs is nil
+// for the default clause.
+type Case struct {
 	Loc   utils.Point
-	Name  Identifier
 	Exprs []AST
+	Body  List
+}
+
+func (c *Case) String() string {
+	if c.Exprs == nil {
+		return "default"
+	}
+	return fmt.Sprintf("case %v", c.Exprs)
+}
+
+// valueRef references an SSA value that has already been computed.
+// It is used when lowering statements into other AST nodes so that
+// expressions are not evaluated multiple times.
+type valueRef struct {
+	Loc   utils.Point
+	Value ssa.Variable
+}
+
+func (ast *valueRef) String() string {
+	return ast.Value.String()
+}
+
+// Location implements the compiler.ast.AST.Location for value
+// references.
+func (ast *valueRef) Location() utils.Point {
+	return ast.Loc
+}
+
+// Call implements an AST call expression. The Recv is the method
+// receiver expression or nil if the receiver is specified with the
+// package part of the Name. The Type is set for conversions to type
+// literals, e.g. [4]byte(s), which can't be named with the Name.
+// The TypeArgs are the explicit type arguments of generic function
+// calls, e.g. Max[uint8](a, b).
+type Call struct {
+	Loc      utils.Point
+	Recv     AST
+	Name     Identifier
+	TypeArgs []*TypeInfo
+	Type     *TypeInfo
+	Exprs    []AST
 }
 
 func (ast *Call) String() string {
+	if ast.Recv != nil {
+		return fmt.Sprintf("%s.%s()", ast.Recv, ast.Name)
+	}
+	if ast.Type != nil {
+		return fmt.Sprintf("%s()", ast.Type)
+	}
 	return fmt.Sprintf("%s()", ast.Name)
 }
 
@@ -390,16 +568,23 @@ func (ast *Return) Location() utils.Point {
 	return ast.Loc
 }
 
-// For implements an AST for statement.
+// For implements an AST for statement. The Max specifies the
+// maximum iteration count for loops whose condition depends on
+// secret values. It is 0 if the loop does not have a bound.
 type For struct {
 	Loc  utils.Point
 	Init AST
 	Cond AST
 	Inc  AST
+	Max  int
 	Body List
 }
 
 func (ast *For) String() string {
+	if ast.Max > 0 {
+		return fmt.Sprintf("for %s; %s; %s /* max %d */ %s",
+			ast.Init, ast.Cond, ast.Inc, ast.Max, ast.Body)
+	}
 	return fmt.Sprintf("for %s; %s; %s %s",
 		ast.Init, ast.Cond, ast.Inc, ast.Body)
 }
@@ -410,6 +595,36 @@ func (ast *For) Location() utils.Point {
 	return ast.Loc
 }
 
+// Break implements an AST break statement.
+type Break struct {
+	Loc utils.Point
+}
+
+func (ast *Break) String() string {
+	return "break"
+}
+
+// Location implements the compiler.ast.AST.Location for break
+// statements.
+func (ast *Break) Location() utils.Point {
+	return ast.Loc
+}
+
+// Continue implements an AST continue statement.
+type Continue struct {
+	Loc utils.Point
+}
+
+func (ast *Continue) String() string {
+	return "continue"
+}
+
+// Location implements the compiler.ast.AST.Location for continue
+// statements.
+func (ast *Continue) Location() utils.Point {
+	return ast.Loc
+}
+
 // BinaryType defines binary expression types.
 type BinaryType int
 
@@ -484,6 +699,83 @@ func (ast *Binary) Location() utils.Point {
 	return ast.Loc
 }
 
+// UnaryType defines unary expression types.
+type UnaryType int
+
+// Unary expression types.
+const (
+	UnaryPlus UnaryType = iota
+	UnaryMinus
+	UnaryNot
+	UnaryBnot
+)
+
+var unaryTypes = map[UnaryType]string{
+	UnaryPlus:  "+",
+	UnaryMinus: "-",
+	UnaryNot:   "!",
+	UnaryBnot:  "^",
+}
+
+func (t UnaryType) String() string {
+	name, ok := unaryTypes[t]
+	if ok {
+		return name
+	}
+	return fmt.Sprintf("{UnaryType %d}", t)
+}
+
+// Unary implements an AST unary expression.
+type Unary struct {
+	Loc  utils.Point
+	Op   UnaryType
+	Expr AST
+}
+
+func (ast *Unary) String() string {
+	return fmt.Sprintf("%s%s", ast.Op, ast.Expr)
+}
+
+// Location implements the compiler.ast.AST.Location for unary
+// expressions.
+func (ast *Unary) Location() utils.Point {
+	return ast.Loc
+}
+
+// Index implements an AST array index expression.
+type Index struct {
+	Loc   utils.Point
+	Expr  AST
+	Index AST
+}
+
+func (ast *Index) String() string {
+	return fmt.Sprintf("%s[%s]", ast.Expr, ast.Index)
+}
+
+// Location implements the compiler.ast.AST.Location for index
+// expressions.
+func (ast *Index) Location() utils.Point {
+	return ast.Loc
+}
+
+// Selector implements an AST struct field selector expression.
+type Selector struct {
+	Loc  utils.Point
+	Expr AST
+	Name string
+}
+
+func (ast *Selector) String() string {
+	return fmt.Sprintf("%s.%s", ast.Expr, ast.Name)
+}
+
+// Location implements the compiler.ast.AST.Location for selector
+// expressions.
+func (ast *Selector) Location() utils.Point {
+	return ast.Loc
+}
+
 // Slice implements an AST slice expression.
 type Slice struct {
 	Loc  utils.Point
@@ -535,6 +827,47 @@ func (ast *Constant) String() string {
 	return ConstantName(ast.Value)
 }
 
+// CompositeLit implements an AST composite literal value. The Type
+// is nil if the literal type is elided inside an enclosing composite
+// literal.
+type CompositeLit struct {
+	Loc   utils.Point
+	Type  *TypeInfo
+	Value []KeyedElement
+}
+
+// KeyedElement implements a keyed element of a composite literal. The
+// Key is nil for positional elements.
+type KeyedElement struct {
+	Key     AST
+	Element AST
+}
+
+func (ast *CompositeLit) String() string {
+	var str string
+	if ast.Type != nil {
+		str = ast.Type.String()
+	}
+	str += "{"
+	for idx, e := range ast.Value {
+		if idx > 0 {
+			str += ", "
+		}
+		if e.Key != nil {
+			str += fmt.Sprintf("%s: %s", e.Key, e.Element)
+		} else {
+			str += e.Element.String()
+		}
+	}
+	return str + "}"
+}
+
+// Location implements the compiler.ast.AST.Location for composite
+// literals.
+func (ast *CompositeLit) Location() utils.Point {
+	return ast.Loc
+}
+
 // ConstantName returns the name of the constant value.
 func ConstantName(value interface{}) string {
 	switch val := value.(type) {
@@ -542,8 +875,16 @@ func ConstantName(value interface{}) string {
 		return fmt.Sprintf("$%d", val)
 	case *big.Int:
 		return fmt.Sprintf("$%s", val)
+	case float32:
+		return fmt.Sprintf("$%vf32", val)
+	case float64:
+		return fmt.Sprintf("$%vf64", val)
 	case bool:
 		return fmt.Sprintf("$%v", val)
+	case *ssa.CompositeValue:
+		return fmt.Sprintf("$%s", val)
+	case *ssa.FixedConst:
+		return fmt.Sprintf("$%s%s", val, val.Type.ShortString())
 	default:
 		return fmt.Sprintf("{undefined constant %v (%T)}", val, val)
 	}
@@ -44,22 +44,102 @@ type Eval func(args []AST, env *Env, ctx *Codegen, gen *ssa.Generator,
 
 // Predeclared identifiers.
 var builtins = []Builtin{
+	{
+		Name: "abs",
+		Type: BuiltinFunc,
+		SSA:  absSSA,
+	},
+	{
+		Name: "addOverflow",
+		Type: BuiltinFunc,
+		SSA:  checkedSSA("addOverflow", circuits.NewCheckedAdder),
+	},
+	{
+		Name: "addSat",
+		Type: BuiltinFunc,
+		SSA:  saturatingSSA("addSat", circuits.NewSaturatingAdder),
+	},
+	{
+		Name: "bitreverse",
+		Type: BuiltinFunc,
+		SSA:  bitreverseSSA,
+	},
+	{
+		Name: "clz",
+		Type: BuiltinFunc,
+		SSA:  countSSA("clz", circuits.NewLeadingZeros),
+	},
+	{
+		Name: "ctz",
+		Type: BuiltinFunc,
+		SSA:  countSSA("ctz", circuits.NewTrailingZeros),
+	},
 	{
 		Name: "make",
 		Type: BuiltinFunc,
 		Eval: makeEval,
 	},
+	{
+		Name: "max",
+		Type: BuiltinFunc,
+		SSA:  minMaxSSA("max", circuits.NewMax, circuits.NewSignedMax),
+	},
+	{
+		Name: "min",
+		Type: BuiltinFunc,
+		SSA:  minMaxSSA("min", circuits.NewMin, circuits.NewSignedMin),
+	},
+	{
+		Name: "mulOverflow",
+		Type: BuiltinFunc,
+		SSA:  checkedSSA("mulOverflow", circuits.NewCheckedMultiplier),
+	},
+	{
+		Name: "mulSat",
+		Type: BuiltinFunc,
+		SSA:  saturatingSSA("mulSat", circuits.NewSaturatingMultiplier),
+	},
+	{
+		Name: "mux",
+		Type: BuiltinFunc,
+		SSA:  muxSSA,
+	},
 	{
 		Name: "native",
 		Type: BuiltinFunc,
 		SSA:  nativeSSA,
 	},
+	{
+		Name: "popcount",
+		Type: BuiltinFunc,
+		SSA:  countSSA("popcount", circuits.NewPopcount),
+	},
+	{
+		Name: "rotl",
+		Type: BuiltinFunc,
+		SSA:  rotateSSA("rotl", ssa.NewRotlInstr),
+	},
+	{
+		Name: "rotr",
+		Type: BuiltinFunc,
+		SSA:  rotateSSA("rotr", ssa.NewRotrInstr),
+	},
 	{
 		Name: "size",
 		Type: BuiltinFunc,
 		SSA:  sizeSSA,
 		Eval: sizeEval,
 	},
+	{
+		Name: "subOverflow",
+		Type: BuiltinFunc,
+		SSA:  checkedSSA("subOverflow", circuits.NewCheckedSubtractor),
+	},
+	{
+		Name: "subSat",
+		Type: BuiltinFunc,
+		SSA:  saturatingSSA("subSat", circuits.NewSaturatingSubtractor),
+	},
 }
 
 func makeEval(args []AST, env *Env, ctx *Codegen, gen *ssa.Generator,
@@ -138,8 +218,8 @@ func nativeSSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
 		}
 
 		v := gen.AnonVar(typeInfo)
-		block.AddInstr(ssa.NewBuiltinInstr(circuits.Hamming, args[0], args[1],
-			v))
+		block.AddInstr(ssa.NewBuiltinInstr(name,
+			binaryBuiltin(circuits.Hamming), args, v))
 
 		return block, []ssa.Variable{v}, nil
 
@@ -198,6 +278,319 @@ func nativeCircuit(name string, block *ssa.Block, ctx *Codegen,
 	return block, result, nil
 }
 
+func rotateSSA(name string, newInstr func(l, r, o ssa.Variable) ssa.Instr) SSA {
+	return func(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
+		args []ssa.Variable, loc utils.Point) (
+		*ssa.Block, []ssa.Variable, error) {
+
+		if len(args) != 2 {
+			return nil, nil, ctx.logger.Errorf(loc,
+				"invalid amount of arguments in call to %s", name)
+		}
+		for _, arg := range args {
+			if arg.Type.Type != types.Int && arg.Type.Type != types.Uint {
+				return nil, nil, ctx.logger.Errorf(loc,
+					"invalid argument %s (type %s) in call to %s",
+					arg, arg.Type, name)
+			}
+		}
+		v := gen.AnonVar(args[0].Type)
+		block.AddInstr(newInstr(args[0], args[1], v))
+
+		return block, []ssa.Variable{v}, nil
+	}
+}
+
+// unaryBuiltin creates a builtin circuit from the unary circuit
+// constructor f.
+func unaryBuiltin(f func(cc *circuits.Compiler,
+	x, r []*circuits.Wire) error) circuits.Builtin {
+
+	return func(cc *circuits.Compiler, args [][]*circuits.Wire,
+		r []*circuits.Wire) error {
+		return f(cc, args[0], r)
+	}
+}
+
+// binaryBuiltin creates a builtin circuit from the binary circuit
+// constructor f.
+func binaryBuiltin(f func(cc *circuits.Compiler,
+	x, y, r []*circuits.Wire) error) circuits.Builtin {
+
+	return func(cc *circuits.Compiler, args [][]*circuits.Wire,
+		r []*circuits.Wire) error {
+		return f(cc, args[0], args[1], r)
+	}
+}
+
+// integerArg checks that the builtin function name has one integer
+// argument.
+func integerArg(name string, ctx *Codegen, args []ssa.Variable,
+	loc utils.Point) error {
+
+	if len(args) != 1 {
+		return ctx.logger.Errorf(loc,
+			"invalid amount of arguments in call to %s", name)
+	}
+	if args[0].Type.Type != types.Int && args[0].Type.Type != types.Uint {
+		return ctx.logger.Errorf(loc,
+			"invalid argument %s (type %s) in call to %s",
+			args[0], args[0].Type, name)
+	}
+	return nil
+}
+
+// countSSA creates the SSA generator for the bit counting builtin
+// functions. The count is returned as int32 value.
+func countSSA(name string, f func(cc *circuits.Compiler,
+	x, r []*circuits.Wire) error) SSA {
+
+	return func(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
+		args []ssa.Variable, loc utils.Point) (
+		*ssa.Block, []ssa.Variable, error) {
+
+		err := integerArg(name, ctx, args, loc)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := gen.AnonVar(types.Info{
+			Type: types.Int,
+			Bits: 32,
+		})
+		block.AddInstr(ssa.NewBuiltinInstr(name, unaryBuiltin(f), args, v))
+
+		return block, []ssa.Variable{v}, nil
+	}
+}
+
+func bitreverseSSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
+	args []ssa.Variable, loc utils.Point) (*ssa.Block, []ssa.Variable, error) {
+
+	err := integerArg("bitreverse", ctx, args, loc)
+	if err != nil {
+		return nil, nil, err
+	}
+	v := gen.AnonVar(args[0].Type)
+	block.AddInstr(ssa.NewBuiltinInstr("bitreverse",
+		unaryBuiltin(circuits.NewBitReverse), args, v))
+
+	return block, []ssa.Variable{v}, nil
+}
+
+func absSSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
+	args []ssa.Variable, loc utils.Point) (*ssa.Block, []ssa.Variable, error) {
+
+	if len(args) != 1 {
+		return nil, nil, ctx.logger.Errorf(loc,
+			"invalid amount of arguments in call to abs")
+	}
+	switch args[0].Type.Type {
+	case types.Uint:
+		return block, args, nil
+
+	case types.Int, types.Fixed:
+		v := gen.AnonVar(args[0].Type)
+		block.AddInstr(ssa.NewBuiltinInstr("abs",
+			unaryBuiltin(circuits.NewAbs), args, v))
+		return block, []ssa.Variable{v}, nil
+
+	default:
+		return nil, nil, ctx.logger.Errorf(loc,
+			"invalid argument %s (type %s) in call to abs",
+			args[0], args[0].Type)
+	}
+}
+
+// operandType checks that the values l and r are type compatible and
+// returns the type of the result. The constant values get the type of
+// the other operand.
+func operandType(name string, ctx *Codegen, gen *ssa.Generator,
+	l, r ssa.Variable, loc utils.Point) (
+	ssa.Variable, ssa.Variable, types.Info, error) {
+
+	var err error
+
+	if !r.Const {
+		l, err = fitConst(gen, l, r.Type)
+		if err != nil {
+			return l, r, r.Type, ctx.logger.Errorf(loc, "%s", err)
+		}
+	}
+	if !l.Const {
+		r, err = fitConst(gen, r, l.Type)
+		if err != nil {
+			return l, r, l.Type, ctx.logger.Errorf(loc, "%s", err)
+		}
+	}
+	if !l.TypeCompatible(r) {
+		return l, r, l.Type, ctx.logger.Errorf(loc,
+			"invalid types %s and %s in call to %s", l.Type, r.Type, name)
+	}
+	if l.Const {
+		return l, r, r.Type, nil
+	}
+	return l, r, l.Type, nil
+}
+
+// minMaxSSA creates the SSA generator for the min and max builtin
+// functions. The signed circuit constructor is used for signed
+// integer and fixed-point values.
+func minMaxSSA(name string, unsigned, signed func(cc *circuits.Compiler,
+	x, y, r []*circuits.Wire) error) SSA {
+
+	return func(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
+		args []ssa.Variable, loc utils.Point) (
+		*ssa.Block, []ssa.Variable, error) {
+
+		if len(args) != 2 {
+			return nil, nil, ctx.logger.Errorf(loc,
+				"invalid amount of arguments in call to %s", name)
+		}
+		l, r, t, err := operandType(name, ctx, gen, args[0], args[1], loc)
+		if err != nil {
+			return nil, nil, err
+		}
+		var f func(cc *circuits.Compiler, x, y, r []*circuits.Wire) error
+		switch t.Type {
+		case types.Uint:
+			f = unsigned
+		case types.Int, types.Fixed:
+			f = signed
+		default:
+			return nil, nil, ctx.logger.Errorf(loc,
+				"invalid argument %s (type %s) in call to %s", l, t, name)
+		}
+		v := gen.AnonVar(t)
+		block.AddInstr(ssa.NewBuiltinInstr(name, binaryBuiltin(f),
+			[]ssa.Variable{l, r}, v))
+
+		return block, []ssa.Variable{v}, nil
+	}
+}
+
+// integerOperands checks that the builtin function name has two
+// type compatible integer arguments. The function returns the
+// arguments, their type, and tells if the type is signed.
+func integerOperands(name string, ctx *Codegen, gen *ssa.Generator,
+	args []ssa.Variable, loc utils.Point) (
+	ssa.Variable, ssa.Variable, types.Info, bool, error) {
+
+	if len(args) != 2 {
+		return ssa.Variable{}, ssa.Variable{}, types.Info{}, false,
+			ctx.logger.Errorf(loc,
+				"invalid amount of arguments in call to %s", name)
+	}
+	l, r, t, err := operandType(name, ctx, gen, args[0], args[1], loc)
+	if err != nil {
+		return l, r, t, false, err
+	}
+	if t.Type != types.Int && t.Type != types.Uint {
+		return l, r, t, false, ctx.logger.Errorf(loc,
+			"invalid argument %s (type %s) in call to %s", l, t, name)
+	}
+	return l, r, t, t.Type == types.Int, nil
+}
+
+// checkedSSA creates the SSA generator for the checked arithmetic
+// builtin functions. The functions return the result and a boolean
+// overflow flag. The builtin circuit computes both into one value
+// which is sliced into the return values.
+func checkedSSA(name string, f func(cc *circuits.Compiler, signed bool,
+	x, y, r []*circuits.Wire, ov *circuits.Wire) error) SSA {
+
+	return func(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
+		args []ssa.Variable, loc utils.Point) (
+		*ssa.Block, []ssa.Variable, error) {
+
+		l, r, t, signed, err := integerOperands(name, ctx, gen, args, loc)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := gen.AnonVar(types.Info{
+			Type: types.Uint,
+			Bits: t.Bits + 1,
+		})
+		block.AddInstr(ssa.NewBuiltinInstr(name,
+			func(cc *circuits.Compiler, args [][]*circuits.Wire,
+				r []*circuits.Wire) error {
+				n := len(r) - 1
+				return f(cc, signed, args[0], args[1], r[:n], r[n])
+			},
+			[]ssa.Variable{l, r}, v))
+
+		from, err := ssa.Constant(gen, int32(0))
+		if err != nil {
+			return nil, nil, err
+		}
+		to, err := ssa.Constant(gen, int32(t.Bits))
+		if err != nil {
+			return nil, nil, err
+		}
+		end, err := ssa.Constant(gen, int32(t.Bits+1))
+		if err != nil {
+			return nil, nil, err
+		}
+		result := gen.AnonVar(t)
+		block.AddInstr(ssa.NewSliceInstr(v, from, to, result))
+		overflow := gen.AnonVar(types.BoolType())
+		block.AddInstr(ssa.NewSliceInstr(v, to, end, overflow))
+
+		return block, []ssa.Variable{result, overflow}, nil
+	}
+}
+
+// saturatingSSA creates the SSA generator for the saturating
+// arithmetic builtin functions.
+func saturatingSSA(name string, f func(cc *circuits.Compiler, signed bool,
+	x, y, r []*circuits.Wire) error) SSA {
+
+	return func(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
+		args []ssa.Variable, loc utils.Point) (
+		*ssa.Block, []ssa.Variable, error) {
+
+		l, r, t, signed, err := integerOperands(name, ctx, gen, args, loc)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := gen.AnonVar(t)
+		block.AddInstr(ssa.NewBuiltinInstr(name,
+			func(cc *circuits.Compiler, args [][]*circuits.Wire,
+				r []*circuits.Wire) error {
+				return f(cc, signed, args[0], args[1], r)
+			},
+			[]ssa.Variable{l, r}, v))
+
+		return block, []ssa.Variable{v}, nil
+	}
+}
+
+func muxSSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
+	args []ssa.Variable, loc utils.Point) (*ssa.Block, []ssa.Variable, error) {
+
+	if len(args) != 3 {
+		return nil, nil, ctx.logger.Errorf(loc,
+			"invalid amount of arguments in call to mux")
+	}
+	if args[0].Type.Type != types.Bool {
+		return nil, nil, ctx.logger.Errorf(loc,
+			"non-bool %s (type %s) used as mux condition",
+			args[0], args[0].Type)
+	}
+	t, f, typeInfo, err := operandType("mux", ctx, gen, args[1], args[2], loc)
+	if err != nil {
+		return nil, nil, err
+	}
+	v := gen.AnonVar(typeInfo)
+	block.AddInstr(ssa.NewBuiltinInstr("mux",
+		func(cc *circuits.Compiler, args [][]*circuits.Wire,
+			r []*circuits.Wire) error {
+			return circuits.NewMUX(cc, args[0], args[1], args[2], r)
+		},
+		[]ssa.Variable{args[0], t, f}, v))
+
+	return block, []ssa.Variable{v}, nil
+}
+
 func sizeSSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
 	args []ssa.Variable, loc utils.Point) (*ssa.Block, []ssa.Variable, error) {
 
@@ -9,27 +9,36 @@
 package ast
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/markkurossi/mpc/compiler/ssa"
 	"github.com/markkurossi/mpc/compiler/utils"
 )
 
 // Codegen implements compilation stack.
 type Codegen struct {
-	logger   *utils.Logger
-	Verbose  bool
-	Package  *Package
-	Packages map[string]*Package
-	Stack    []Compilation
+	logger         *utils.Logger
+	Verbose        bool
+	MaxInlineDepth int
+	Package        *Package
+	Packages       map[string]*Package
+	Stack          []Compilation
 }
 
 // NewCodegen creates a new compilation.
 func NewCodegen(logger *utils.Logger, pkg *Package,
-	packages map[string]*Package, verbose bool) *Codegen {
+	packages map[string]*Package, params *utils.Params) *Codegen {
+	maxDepth := params.MaxInlineDepth
+	if maxDepth <= 0 {
+		maxDepth = utils.DefaultMaxInlineDepth
+	}
 	return &Codegen{
-		logger:   logger,
-		Package:  pkg,
-		Packages: packages,
-		Verbose:  verbose,
+		logger:         logger,
+		Package:        pkg,
+		Packages:       packages,
+		Verbose:        params.Verbose,
+		MaxInlineDepth: maxDepth,
 	}
 }
 
@@ -70,6 +79,64 @@ func (ctx *Codegen) PopCompilation() {
 	ctx.Stack = ctx.Stack[:len(ctx.Stack)-1]
 }
 
+// CallChain returns the function call chain of the compilation stack
+// ending to the argument function. Repeating call cycles, such as
+// recursive or mutually recursive calls, are collapsed into one
+// entry.
+func (ctx *Codegen) CallChain(called *Func) string {
+	var names []string
+	for _, c := range append(ctx.Stack, Compilation{Called: called}) {
+		if c.Called != nil {
+			names = append(names, c.Called.Name)
+		}
+	}
+
+	var chain []string
+	for i := 0; i < len(names); {
+		period, count := callCycle(names[i:])
+		if count <= 1 {
+			chain = append(chain, names[i])
+			i++
+			continue
+		}
+		cycle := strings.Join(names[i:i+period], " -> ")
+		if period > 1 {
+			cycle = "(" + cycle + ")"
+		}
+		chain = append(chain, fmt.Sprintf("%s (%d times)", cycle, count))
+		i += period * count
+	}
+
+	return strings.Join(chain, " -> ")
+}
+
+// callCycle finds the shortest call cycle that starts the call chain
+// names and repeats at least twice. The function returns the cycle
+// length and the number of its consecutive repetitions.
+func callCycle(names []string) (int, int) {
+	for period := 1; period*2 <= len(names); period++ {
+		count := 1
+		for (count+1)*period <= len(names) &&
+			equalNames(names[:period],
+				names[count*period:(count+1)*period]) {
+			count++
+		}
+		if count > 1 {
+			return period, count
+		}
+	}
+	return 1, 1
+}
+
+func equalNames(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // Start returns the start block of the current compilation.
 func (ctx *Codegen) Start() *ssa.Block {
 	return ctx.Stack[len(ctx.Stack)-1].Start
@@ -85,10 +152,56 @@ func (ctx *Codegen) Caller() *ssa.Block {
 	return ctx.Stack[len(ctx.Stack)-1].Caller
 }
 
+// PushBreakable pushes a new breakable statement to the current
+// compilation. The loop argument specifies if the statement is a
+// loop that can also be terminated with a continue statement.
+func (ctx *Codegen) PushBreakable(loop bool) *Breakable {
+	c := &ctx.Stack[len(ctx.Stack)-1]
+	depth := len(c.Breakables)
+	b := &Breakable{
+		Break: fmt.Sprintf("%%break%d", depth),
+	}
+	if loop {
+		b.Continue = fmt.Sprintf("%%continue%d", depth)
+	}
+	c.Breakables = append(c.Breakables, b)
+	return b
+}
+
+// PopBreakable pops the innermost breakable statement of the current
+// compilation.
+func (ctx *Codegen) PopBreakable() {
+	c := &ctx.Stack[len(ctx.Stack)-1]
+	if len(c.Breakables) == 0 {
+		panic("breakable stack underflow")
+	}
+	c.Breakables = c.Breakables[:len(c.Breakables)-1]
+}
+
+// Breakables returns the breakable statements of the current
+// compilation, innermost last.
+func (ctx *Codegen) Breakables() []*Breakable {
+	if len(ctx.Stack) == 0 {
+		return nil
+	}
+	return ctx.Stack[len(ctx.Stack)-1].Breakables
+}
+
 // Compilation contains information about a function call compilation.
 type Compilation struct {
-	Start  *ssa.Block
-	Return *ssa.Block
-	Caller *ssa.Block
-	Called *Func
+	Start      *ssa.Block
+	Return     *ssa.Block
+	Caller     *ssa.Block
+	Called     *Func
+	Breakables []*Breakable
+}
+
+// Breakable contains information about a statement that can be
+// terminated with break or continue statements. The Break and
+// Continue name the boolean flag bindings that are set when the
+// statement is terminated. The Continue is empty for statements that
+// are not loops.
+type Breakable struct {
+	Break    string
+	Continue string
 }
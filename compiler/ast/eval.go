@@ -12,13 +12,54 @@ import (
 	"math/big"
 
 	"github.com/markkurossi/mpc/compiler/ssa"
+	"github.com/markkurossi/mpc/compiler/types"
 	"github.com/markkurossi/mpc/compiler/utils"
 )
 
-// Eval implements the compiler.ast.AST.Eval for list statements.
+// evalControl specifies how an evaluated statement transfers control
+// to its enclosing statements.
+type evalControl int
+
+// Control transfers of evaluated break and continue statements.
+const (
+	evalBreak evalControl = iota
+	evalContinue
+)
+
+// evalReturn holds the values of an evaluated return statement.
+type evalReturn []interface{}
+
+// Eval implements the compiler.ast.AST.Eval for list statements. The
+// statements are evaluated in order until a break, continue, or
+// return statement transfers control out of the list. The control
+// transfer is returned as the value of the list.
 func (ast List) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 	interface{}, bool, error) {
-	return nil, false, fmt.Errorf("List.Eval not implemented yet")
+
+	for _, stmt := range ast {
+		val, ok, err := stmt.Eval(env, ctx, gen)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		switch val.(type) {
+		case evalControl, evalReturn:
+			return val, true, nil
+		}
+	}
+	return nil, true, nil
+}
+
+// evalBlock evaluates the statement list in a nested scope. The
+// variables defined in the list are removed from the environment
+// when the list terminates.
+func evalBlock(list List, env *Env, ctx *Codegen, gen *ssa.Generator) (
+	interface{}, bool, error) {
+
+	n := len(env.Bindings)
+	val, ok, err := list.Eval(env, ctx, gen)
+	env.Bindings = env.Bindings[:n]
+
+	return val, ok, err
 }
 
 // Eval implements the compiler.ast.AST.Eval for function definitions.
@@ -30,13 +71,145 @@ func (ast *Func) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 // Eval implements the compiler.ast.AST.Eval for constant definitions.
 func (ast *ConstantDef) Eval(env *Env, ctx *Codegen,
 	gen *ssa.Generator) (interface{}, bool, error) {
-	return nil, false, nil
+
+	typeInfo, err := ast.Type.Resolve(env, ctx, gen)
+	if err != nil {
+		return nil, false, ctx.logger.Errorf(ast.Loc, "%s", err)
+	}
+	initEnv, err := ast.initEnv(env, gen)
+	if err != nil {
+		return nil, false, err
+	}
+	val, ok, err := ast.Init.Eval(initEnv, ctx, gen)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	val, err = fitValue(val, typeInfo)
+	if err != nil {
+		return nil, false, ctx.logger.Errorf(ast.Init.Location(), "%s", err)
+	}
+	constVar, err := ssa.Constant(gen, val)
+	if err != nil {
+		return nil, false, err
+	}
+	lValue := constVar
+	lValue.Name = ast.Name
+	env.Set(lValue, &constVar)
+
+	return nil, true, nil
+}
+
+// initEnv returns the environment for evaluating the init value of
+// the constant. The environment binds iota to the index of the
+// constant in its const declaration block.
+func (ast *ConstantDef) initEnv(env *Env, gen *ssa.Generator) (*Env, error) {
+	v, err := ssa.Constant(gen, int32(ast.Iota))
+	if err != nil {
+		return nil, err
+	}
+	lValue := v
+	lValue.Name = "iota"
+
+	result := &Env{
+		Bindings: env.Bindings.Clone(),
+	}
+	result.Set(lValue, &v)
+
+	return result, nil
 }
 
 // Eval implements the compiler.ast.AST.Eval for variable definitions.
 func (ast *VariableDef) Eval(env *Env, ctx *Codegen,
 	gen *ssa.Generator) (interface{}, bool, error) {
-	return nil, false, nil
+
+	typeInfo, err := ast.Type.Resolve(env, ctx, gen)
+	if err != nil {
+		return nil, false, ctx.logger.Errorf(ast.Loc, "%s", err)
+	}
+	var val interface{}
+	if ast.Init != nil {
+		var ok bool
+		val, ok, err = ast.Init.Eval(env, ctx, gen)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		if typeInfo.Undefined() {
+			t, _, ok := evalType(ast.Init, env, ctx, gen)
+			if ok {
+				typeInfo = t
+			}
+		}
+		val, err = fitValue(val, typeInfo)
+	} else {
+		val, err = zeroValue(typeInfo)
+	}
+	if err != nil {
+		return nil, false, ctx.logger.Errorf(ast.Loc, "%s", err)
+	}
+	for _, n := range ast.Names {
+		err = evalDefine(env, ctx, gen, n, typeInfo, val)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return nil, true, nil
+}
+
+// evalDefine defines the variable name of type t with the constant
+// value. The variable gets the type of the value if the type t is
+// undefined.
+func evalDefine(env *Env, ctx *Codegen, gen *ssa.Generator, name string,
+	t types.Info, value interface{}) error {
+
+	constVal, err := ssa.Constant(gen, value)
+	if err != nil {
+		return err
+	}
+	if t.Undefined() {
+		t = constVal.Type
+	}
+	lValue, err := gen.NewVar(name, t, ctx.Scope())
+	if err != nil {
+		return err
+	}
+	env.Set(lValue, &constVal)
+
+	return nil
+}
+
+// fitValue converts the constant value to the type t of the variable
+// it is assigned to. Integer values must fit in sized integer types.
+func fitValue(value interface{}, t types.Info) (interface{}, error) {
+	switch t.Type {
+	case types.Int, types.Uint:
+		if t.Bits == 0 {
+			return value, nil
+		}
+	case types.Bool, types.Float, types.Fixed:
+	default:
+		return value, nil
+	}
+	result, ok, err := convertConst(value, t)
+	if err != nil || !ok {
+		return value, err
+	}
+	return result, nil
+}
+
+// zeroValue returns the zero value of the type t.
+func zeroValue(t types.Info) (interface{}, error) {
+	switch t.Type {
+	case types.Bool:
+		return false, nil
+	case types.String:
+		return "", nil
+	case types.Struct, types.Array:
+		return ssa.NewCompositeValue(t), nil
+	case types.Int, types.Uint, types.Float, types.Fixed:
+		return fitValue(int32(0), t)
+	default:
+		return int32(0), nil
+	}
 }
 
 // Eval implements the compiler.ast.AST.Eval for assignment expressions.
@@ -44,13 +217,27 @@ func (ast *Assign) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 	interface{}, bool, error) {
 
 	var values []interface{}
-	for _, expr := range ast.Exprs {
-		val, ok, err := expr.Eval(env, ctx, gen)
+	var valueTypes []types.Info
+
+	call, ok := ast.Exprs[0].(*Call)
+	if ok && len(ast.Exprs) == 1 && len(ast.LValues) > 1 {
+		// Multiple return values.
+		var err error
+		values, ok, err = call.evalCall(env, ctx, gen)
 		if err != nil || !ok {
 			return nil, ok, err
 		}
-		// XXX multiple return values.
-		values = append(values, val)
+		valueTypes, _ = call.resultTypes(ctx, gen)
+	} else {
+		for _, expr := range ast.Exprs {
+			val, ok, err := expr.Eval(env, ctx, gen)
+			if err != nil || !ok {
+				return nil, ok, err
+			}
+			values = append(values, val)
+			t, _, _ := evalType(expr, env, ctx, gen)
+			valueTypes = append(valueTypes, t)
+		}
 	}
 
 	if len(ast.LValues) != len(values) {
@@ -60,102 +247,887 @@ func (ast *Assign) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 	}
 
 	for idx, lv := range ast.LValues {
+		var t types.Info
+		if idx < len(valueTypes) {
+			t = valueTypes[idx]
+		}
+		ok, err := ast.evalAssign(env, ctx, gen, lv, t, values[idx])
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+	}
 
-		constVal, err := ssa.Constant(gen, values[idx])
+	return values, true, nil
+}
+
+// evalAssign assigns the constant value to the lvalue lv. The type t
+// is the type of the value or undefined for untyped constants. Struct
+// fields and array elements are assigned by updating a copy of the
+// enclosing composite value.
+func (ast *Assign) evalAssign(env *Env, ctx *Codegen, gen *ssa.Generator,
+	lv AST, t types.Info, value interface{}) (bool, error) {
+
+	switch lv := lv.(type) {
+	case *VariableRef:
+		_, ok := env.Get(lv.Name.Package)
+		if ok {
+			// Struct field.
+			return ast.evalAssign(env, ctx, gen, &Selector{
+				Loc: lv.Loc,
+				Expr: &VariableRef{
+					Loc: lv.Loc,
+					Name: Identifier{
+						Name: lv.Name.Package,
+					},
+				},
+				Name: lv.Name.Name,
+			}, t, value)
+		}
+		// XXX package.name below
+
+		var b ssa.Binding
+		var err error
+		if !ast.Define {
+			b, ok = env.Get(lv.Name.Name)
+			if !ok {
+				return false, ctx.logger.Errorf(ast.Loc,
+					"undefined variable '%s'", lv.Name)
+			}
+			if b.Scope == 0 {
+				return false, ctx.logger.Errorf(ast.Loc,
+					"cannot assign to %s (read-only)", lv.Name)
+			}
+			// Variables defined from untyped constants keep their
+			// values as-is. Values of declared variables are fitted
+			// to the variable type.
+			if b.Type.MinBits == 0 {
+				value, err = fitValue(value, b.Type)
+				if err != nil {
+					return false, ctx.logger.Errorf(ast.Loc, "%s", err)
+				}
+			}
+		}
+		constVal, err := ssa.Constant(gen, value)
 		if err != nil {
-			return nil, false, err
+			return false, err
 		}
 		gen.AddConstant(constVal)
 
-		ref, ok := lv.(*VariableRef)
+		var lValue ssa.Variable
+		if ast.Define {
+			// Variables defined from typed values get the type of the
+			// value.
+			if t.Undefined() {
+				t = constVal.Type
+			}
+			lValue, err = gen.NewVar(lv.Name.Name, t, ctx.Scope())
+		} else {
+			lValue, err = gen.NewVar(b.Name, b.Type, ctx.Scope())
+		}
+		if err != nil {
+			return false, err
+		}
+		env.Set(lValue, &constVal)
+		return true, nil
+
+	case *Index:
+		val, ok, err := lv.Expr.Eval(env, ctx, gen)
+		if err != nil || !ok {
+			return ok, err
+		}
+		arr, ok := val.(*ssa.CompositeValue)
+		if !ok || arr.Type.Type != types.Array {
+			return false, ctx.logger.Errorf(lv.Loc,
+				"invalid operation: %s (type %T does not support indexing)",
+				lv, val)
+		}
+		val, ok, err = lv.Index.Eval(env, ctx, gen)
+		if err != nil || !ok {
+			return ok, err
+		}
+		idx, err := intVal(val)
+		if err != nil {
+			return false, ctx.logger.Errorf(lv.Index.Location(),
+				"invalid array index %s", lv.Index)
+		}
+		if idx < 0 || idx >= arr.Type.ArraySize {
+			return false, ctx.logger.Errorf(lv.Index.Location(),
+				"invalid array index %d (out of bounds for %d-element array)",
+				idx, arr.Type.ArraySize)
+		}
+		return ast.evalUpdate(env, ctx, gen, lv.Expr, arr, idx, value)
+
+	case *Selector:
+		val, ok, err := lv.Expr.Eval(env, ctx, gen)
+		if err != nil || !ok {
+			return ok, err
+		}
+		s, ok := val.(*ssa.CompositeValue)
+		if ok && s.Type.Type == types.Struct {
+			for idx, f := range s.Type.Struct {
+				if f.Name == lv.Name {
+					return ast.evalUpdate(env, ctx, gen, lv.Expr, s, idx,
+						value)
+				}
+			}
+		}
+		return false, ctx.logger.Errorf(lv.Loc, "%s undefined", lv)
+
+	default:
+		return false, ctx.logger.Errorf(ast.Loc, "cannot assign to %s", lv)
+	}
+}
+
+// evalUpdate assigns the constant value to the element idx of the
+// composite value c and assigns the updated composite value to the
+// lvalue lv.
+func (ast *Assign) evalUpdate(env *Env, ctx *Codegen, gen *ssa.Generator,
+	lv AST, c *ssa.CompositeValue, idx int, value interface{}) (bool, error) {
+
+	t, _ := c.Element(idx)
+	value, err := fitValue(value, t)
+	if err != nil {
+		return false, ctx.logger.Errorf(ast.Loc, "%s", err)
+	}
+	result := &ssa.CompositeValue{
+		Type:     c.Type,
+		Elements: make([]interface{}, len(c.Elements)),
+	}
+	copy(result.Elements, c.Elements)
+	result.Elements[idx] = value
+
+	return ast.evalAssign(env, ctx, gen, lv, c.Type, result)
+}
+
+// Eval implements the compiler.ast.AST.Eval for if statements.
+func (ast *If) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+	interface{}, bool, error) {
+
+	val, ok, err := ast.Expr.Eval(env, ctx, gen)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	cond, ok := val.(bool)
+	if !ok {
+		return nil, false, ctx.logger.Errorf(ast.Expr.Location(),
+			"condition is not boolean expression")
+	}
+	if cond {
+		return evalBlock(ast.True, env, ctx, gen)
+	}
+	return evalBlock(ast.False, env, ctx, gen)
+}
+
+// Eval implements the compiler.ast.AST.Eval for switch statements.
+func (ast *Switch) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+	interface{}, bool, error) {
+
+	var tag interface{}
+	if ast.Expr != nil {
+		val, ok, err := ast.Expr.Eval(env, ctx, gen)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		tag = val
+	}
+
+	var selected, def *Case
+
+cases:
+	for _, c := range ast.Cases {
+		if c.Exprs == nil {
+			def = c
+			continue
+		}
+		for _, expr := range c.Exprs {
+			cond := expr
+			if ast.Expr != nil {
+				cond = &Binary{
+					Loc: expr.Location(),
+					Left: &Constant{
+						Loc:   ast.Expr.Location(),
+						Value: tag,
+					},
+					Op:    BinaryEq,
+					Right: expr,
+				}
+			}
+			val, ok, err := cond.Eval(env, ctx, gen)
+			if err != nil || !ok {
+				return nil, ok, err
+			}
+			match, ok := val.(bool)
+			if !ok {
+				return nil, false, ctx.logger.Errorf(expr.Location(),
+					"condition is not boolean expression")
+			}
+			if match {
+				selected = c
+				break cases
+			}
+		}
+	}
+	if selected == nil {
+		selected = def
+	}
+	if selected == nil {
+		return nil, true, nil
+	}
+	val, ok, err := evalBlock(selected.Body, env, ctx, gen)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	if val == evalBreak {
+		return nil, true, nil
+	}
+	return val, true, nil
+}
+
+// Eval implements the compiler.ast.AST.Eval for value references.
+func (ast *valueRef) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+	interface{}, bool, error) {
+	if ast.Value.Const {
+		return ast.Value.ConstValue, true, nil
+	}
+	return nil, false, nil
+}
+
+// Eval implements the compiler.ast.AST.Eval for call expressions.
+func (ast *Call) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+	interface{}, bool, error) {
+
+	if ast.receiver(env) != nil {
+		return nil, false, nil
+	}
+
+	// Resolve called.
+	var pkg *Package
+	var ok bool
+	if len(ast.Name.Package) > 0 {
+		pkg, ok = ctx.Packages[ast.Name.Package]
 		if !ok {
-			return nil, false, ctx.logger.Errorf(ast.Loc,
-				"cannot assign to %s", lv)
+			return nil, false,
+				ctx.logger.Errorf(ast.Loc, "package '%s' not found",
+					ast.Name.Package)
 		}
-		// XXX package.name below
+	} else {
+		pkg = ctx.Package
+	}
+	called, ok := pkg.Functions[ast.Name.Name]
+	if ok {
+		values, ok, err := ast.evalFunc(env, ctx, gen, pkg, called)
+		if err != nil || !ok || len(values) != 1 {
+			return nil, false, err
+		}
+		return values[0], true, nil
+	}
+	// Check builtin functions.
+	for _, bi := range builtins {
+		if bi.Name != ast.Name.Name {
+			continue
+		}
+		if bi.Type != BuiltinFunc {
+			return nil, false, fmt.Errorf("builtin %s used as function",
+				bi.Name)
+		}
+		if bi.Eval == nil {
+			return nil, false, nil
+		}
+		return bi.Eval(ast.Exprs, env, ctx, gen, ast.Location())
+	}
+
+	// Check type conversions.
+	t, ok, err := ast.conversionType(env, ctx, gen)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if len(ast.Exprs) != 1 {
+		return nil, false, ctx.logger.Errorf(ast.Loc,
+			"invalid amount of arguments in conversion to %s", t)
+	}
+	val, ok, err := ast.Exprs[0].Eval(env, ctx, gen)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	// Conversions of variables wrap around like in the generated
+	// circuits.
+	_, variable, _ := evalType(ast.Exprs[0], env, ctx, gen)
+	if variable && isIntValue(val) && t.Bits > 0 &&
+		(t.Type == types.Int || t.Type == types.Uint) {
+		return wrapValue(val, t), true, nil
+	}
+	result, ok, err := convertConst(val, t)
+	if err != nil {
+		return nil, false, ctx.logger.Errorf(ast.Exprs[0].Location(),
+			"%s", err)
+	}
+	return result, ok, nil
+}
+
+// evalCall evaluates the call of a user-defined function at compile
+// time. The function returns false if the call is not a function
+// call or if it can't be evaluated at compile time.
+func (ast *Call) evalCall(env *Env, ctx *Codegen, gen *ssa.Generator) (
+	[]interface{}, bool, error) {
+
+	if ast.receiver(env) != nil {
+		return nil, false, nil
+	}
+	var pkg *Package
+	var ok bool
+	if len(ast.Name.Package) > 0 {
+		pkg, ok = ctx.Packages[ast.Name.Package]
+		if !ok {
+			return nil, false, nil
+		}
+	} else {
+		pkg = ctx.Package
+	}
+	called, ok := pkg.Functions[ast.Name.Name]
+	if !ok {
+		return nil, false, nil
+	}
+	return ast.evalFunc(env, ctx, gen, pkg, called)
+}
+
+// resultTypes returns the result types of the called user-defined
+// function. The function returns false if the call is not a function
+// call or if the result types can't be resolved.
+func (ast *Call) resultTypes(ctx *Codegen, gen *ssa.Generator) (
+	[]types.Info, bool) {
+
+	var pkg *Package
+	var ok bool
+	if len(ast.Name.Package) > 0 {
+		pkg, ok = ctx.Packages[ast.Name.Package]
+		if !ok {
+			return nil, false
+		}
+	} else {
+		pkg = ctx.Package
+	}
+	called, ok := pkg.Functions[ast.Name.Name]
+	if !ok || len(called.TypeParams) > 0 {
+		return nil, false
+	}
+	env := &Env{
+		Bindings: pkg.Bindings,
+	}
+	var result []types.Info
+	for _, ret := range called.Return {
+		t, err := ret.Type.Resolve(env, ctx, gen)
+		if err != nil {
+			return nil, false
+		}
+		result = append(result, t)
+	}
+	return result, true
+}
+
+// evalFunc evaluates the function called with the call arguments at
+// compile time. The function body is interpreted in a new
+// environment where the arguments and named results are bound to
+// constant values. The function returns false if any of the
+// arguments is not constant or if the body contains statements that
+// can't be evaluated at compile time.
+func (ast *Call) evalFunc(env *Env, ctx *Codegen, gen *ssa.Generator,
+	pkg *Package, called *Func) ([]interface{}, bool, error) {
+
+	// Generic functions are instantiated during SSA generation.
+	if len(called.TypeParams) > 0 || len(ast.TypeArgs) > 0 {
+		return nil, false, nil
+	}
+	if len(ast.Exprs) != len(called.Args) {
+		return nil, false, nil
+	}
+	var args []interface{}
+	for _, expr := range ast.Exprs {
+		val, ok, err := expr.Eval(env, ctx, gen)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		args = append(args, val)
+	}
+
+	// Bound the recursion.
+	if len(ctx.Stack) > ctx.MaxInlineDepth {
+		return nil, false, ctx.logger.Errorf(ast.Loc,
+			"maximum inline depth %d exceeded: %s", ctx.MaxInlineDepth,
+			ctx.CallChain(called))
+	}
+	ctx.PushCompilation(nil, nil, nil, called)
+	defer ctx.PopCompilation()
+
+	callEnv := &Env{
+		Bindings: pkg.Bindings.Clone(),
+	}
+
+	// Define arguments.
+	for idx, arg := range called.Args {
+		typeInfo, err := arg.Type.Resolve(callEnv, ctx, gen)
+		if err != nil {
+			return nil, false, ctx.logger.Errorf(arg.Loc,
+				"invalid argument type: %s", err)
+		}
+		val, err := fitValue(args[idx], typeInfo)
+		if err != nil {
+			return nil, false, ctx.logger.Errorf(ast.Exprs[idx].Location(),
+				"%s", err)
+		}
+		err = evalDefine(callEnv, ctx, gen, arg.Name, typeInfo, val)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	// Define named result variables.
+	var results []types.Info
+	for _, ret := range called.Return {
+		typeInfo, err := ret.Type.Resolve(callEnv, ctx, gen)
+		if err != nil {
+			return nil, false, ctx.logger.Errorf(ret.Loc,
+				"invalid return type: %s", err)
+		}
+		results = append(results, typeInfo)
+		if len(ret.Name) == 0 {
+			continue
+		}
+		zero, err := zeroValue(typeInfo)
+		if err != nil {
+			return nil, false, ctx.logger.Errorf(ret.Loc, "%s", err)
+		}
+		err = evalDefine(callEnv, ctx, gen, ret.Name, typeInfo, zero)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	val, ok, err := called.Body.Eval(callEnv, ctx, gen)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	values, _ := val.(evalReturn)
+	if len(values) != len(results) {
+		return nil, false, nil
+	}
+	for idx, t := range results {
+		values[idx], err = fitValue(values[idx], t)
+		if err != nil {
+			return nil, false, ctx.logger.Errorf(ast.Loc, "%s", err)
+		}
+	}
+	return values, true, nil
+}
+
+// isConversion tests if the expression is a type conversion.
+func isConversion(expr AST, env *Env, ctx *Codegen,
+	gen *ssa.Generator) bool {
+
+	call, ok := expr.(*Call)
+	if !ok {
+		return false
+	}
+	_, ok, _ = call.conversionType(env, ctx, gen)
+	return ok
+}
+
+// convertConst converts the constant value to the type t. Integer
+// values must fit in the sized integer types. The function returns
+// false if the value can't be converted at compile time.
+func convertConst(value interface{}, t types.Info) (interface{}, bool, error) {
+	switch t.Type {
+	case types.Float:
+		bits := t.Bits
+		if bits == 0 {
+			bits = 64
+			if _, ok := value.(float32); ok {
+				bits = 32
+			}
+		}
+		val, err := ssa.FloatValue(value, bits)
+		if err != nil {
+			return nil, false, err
+		}
+		return val, true, nil
+
+	case types.Fixed:
+		val, err := ssa.FixedValue(value, t)
+		if err != nil {
+			return nil, false, err
+		}
+		return val, true, nil
+
+	case types.Bool:
+		switch val := value.(type) {
+		case bool:
+			return val, true, nil
+		case int32, uint64, *big.Int:
+			i, _ := constInt(val)
+			if i.BitLen() <= 1 && i.Sign() >= 0 {
+				return i.Sign() != 0, true, nil
+			}
+		}
+		return nil, false, fmt.Errorf("cannot convert %v (%T) to %s",
+			value, value, t)
+
+	case types.Int, types.Uint:
+		i, err := constInt(value)
+		if err != nil {
+			return nil, false, err
+		}
+		if t.Bits == 0 {
+			if t.Type == types.Uint && i.Sign() < 0 {
+				return nil, false, fmt.Errorf("constant %s overflows %s",
+					i, t)
+			}
+			return compactInt(i), true, nil
+		}
+		result := wrapInt(i, t)
+		r, _ := constInt(result)
+		if r.Cmp(i) != 0 {
+			return nil, false, fmt.Errorf("constant %s overflows %s", i, t)
+		}
+		return result, true, nil
+
+	case types.String:
+		val, ok := value.(string)
+		if !ok {
+			return nil, false, nil
+		}
+		if t.Bits != 0 && len([]byte(val))*8 > t.Bits {
+			return nil, false, fmt.Errorf("constant %q overflows %s", val, t)
+		}
+		return val, true, nil
+
+	default:
+		return nil, false, nil
+	}
+}
+
+// wrapInt truncates the integer value i to the size of the sized
+// integer type t and sign-extends signed values.
+func wrapInt(i *big.Int, t types.Info) interface{} {
+	mod := new(big.Int).Lsh(big.NewInt(1), uint(t.Bits))
+	i = new(big.Int).Mod(i, mod)
+	if t.Type == types.Int && i.Bit(t.Bits-1) != 0 {
+		i.Sub(i, mod)
+	}
+	return compactInt(i)
+}
+
+// evalType returns the type of the expression if the expression has
+// a declared type. The function returns false for untyped
+// expressions. The variable flag tells if the value depends on
+// variables or if it is a constant expression.
+func evalType(expr AST, env *Env, ctx *Codegen, gen *ssa.Generator) (
+	t types.Info, variable, ok bool) {
+
+	switch expr := expr.(type) {
+	case *VariableRef:
+		b, ok := env.Get(expr.Name.Package)
+		if ok {
+			return fieldType(b.Type, expr.Name.Name, true)
+		}
+		if len(expr.Name.Package) > 0 {
+			pkg, ok := ctx.Packages[expr.Name.Package]
+			if !ok {
+				return t, false, false
+			}
+			b, ok = pkg.Bindings.Get(expr.Name.Name)
+		} else {
+			b, ok = env.Get(expr.Name.Name)
+		}
+		if !ok {
+			return t, false, false
+		}
+		// Variables defined from untyped constants have the minimum
+		// size of their value. Named constants are bound in scope 0.
+		if b.Type.Undefined() || b.Type.MinBits != 0 {
+			return t, b.Scope != 0, false
+		}
+		return b.Type, true, true
+
+	case *Binary:
+		switch expr.Op {
+		case BinaryEq, BinaryNeq, BinaryLt, BinaryLe, BinaryGt, BinaryGe,
+			BinaryAnd, BinaryOr:
+			_, lv, _ := evalType(expr.Left, env, ctx, gen)
+			_, rv, _ := evalType(expr.Right, env, ctx, gen)
+			return t, lv || rv, false
+
+		case BinaryLshift, BinaryRshift:
+			t, variable, ok = evalType(expr.Left, env, ctx, gen)
+			_, rv, _ := evalType(expr.Right, env, ctx, gen)
+			return t, variable || rv, ok
+
+		default:
+			lt, lv, lok := evalType(expr.Left, env, ctx, gen)
+			rt, rv, rok := evalType(expr.Right, env, ctx, gen)
+			if lok {
+				return lt, lv || rv, true
+			}
+			return rt, lv || rv, rok
+		}
+
+	case *Unary:
+		t, variable, ok = evalType(expr.Expr, env, ctx, gen)
+		return t, variable, ok && expr.Op != UnaryNot
+
+	case *Call:
+		if expr.receiver(env) != nil {
+			return t, false, false
+		}
+		results, ok := expr.resultTypes(ctx, gen)
+		if ok {
+			if len(results) != 1 {
+				return t, false, false
+			}
+			return results[0], true, true
+		}
+		t, ok, err := expr.conversionType(env, ctx, gen)
+		if err != nil || !ok || len(expr.Exprs) != 1 {
+			return t, false, false
+		}
+		_, variable, _ = evalType(expr.Exprs[0], env, ctx, gen)
+		return t, variable, true
+
+	case *Index:
+		t, variable, ok = evalType(expr.Expr, env, ctx, gen)
+		if !ok || t.Type != types.Array {
+			return types.Info{}, variable, false
+		}
+		return *t.ElementType, variable, true
+
+	case *Selector:
+		t, variable, ok = evalType(expr.Expr, env, ctx, gen)
+		if !ok {
+			return t, variable, false
+		}
+		return fieldType(t, expr.Name, variable)
+	}
+	return t, false, false
+}
+
+// fieldType returns the type of the field name of the struct type t.
+func fieldType(t types.Info, name string, variable bool) (
+	types.Info, bool, bool) {
+
+	if t.Type != types.Struct {
+		return types.Info{}, variable, false
+	}
+	for _, f := range t.Struct {
+		if f.Name == name {
+			return f.Type, variable, true
+		}
+	}
+	return types.Info{}, variable, false
+}
+
+// fitTyped fits the integer value of the expression to the sized
+// integer type of the expression. The values computed from variables
+// wrap around like in the generated circuits and the values of
+// constant expressions must fit in the type.
+func fitTyped(expr AST, env *Env, ctx *Codegen, gen *ssa.Generator,
+	value interface{}) (interface{}, bool, error) {
+
+	if !isIntValue(value) {
+		return value, true, nil
+	}
+	t, variable, ok := evalType(expr, env, ctx, gen)
+	if !ok || t.Bits == 0 || (t.Type != types.Int && t.Type != types.Uint) {
+		return value, true, nil
+	}
+	if variable {
+		return wrapValue(value, t), true, nil
+	}
+	result, _, err := convertConst(value, t)
+	if err != nil {
+		return nil, false, ctx.logger.Errorf(expr.Location(), "%s", err)
+	}
+	return result, true, nil
+}
+
+// wrapValue truncates the integer value to the size of the sized
+// integer type t.
+func wrapValue(value interface{}, t types.Info) interface{} {
+	i, err := constInt(value)
+	if err != nil {
+		return value
+	}
+	return wrapInt(i, t)
+}
+
+// constInt returns the integer value of the numeric constant
+// value. Floating point and fixed-point constants must have an
+// integer value.
+func constInt(value interface{}) (*big.Int, error) {
+	switch val := value.(type) {
+	case bool:
+		if val {
+			return big.NewInt(1), nil
+		}
+		return big.NewInt(0), nil
+	case int32:
+		return big.NewInt(int64(val)), nil
+	case int64:
+		return big.NewInt(val), nil
+	case uint64:
+		return new(big.Int).SetUint64(val), nil
+	case *big.Int:
+		return new(big.Int).Set(val), nil
+	case float32, float64:
+		f, _ := ssa.FloatValue(val, 64)
+		if math.IsInf(f.(float64), 0) || math.IsNaN(f.(float64)) ||
+			f.(float64) != math.Trunc(f.(float64)) {
+			return nil, fmt.Errorf("constant %v truncated to integer", val)
+		}
+		i, _ := new(big.Float).SetFloat64(f.(float64)).Int(nil)
+		return i, nil
+	case *ssa.FixedConst:
+		// Fixed-point values truncate towards negative infinity.
+		i := new(big.Int).Set(val.Raw)
+		if i.Bit(val.Type.Bits-1) != 0 {
+			i.Sub(i, new(big.Int).Lsh(big.NewInt(1), uint(val.Type.Bits)))
+		}
+		return i.Rsh(i, uint(val.Type.Fraction)), nil
+	default:
+		return nil, fmt.Errorf("invalid integer constant %v (%T)", val, val)
+	}
+}
+
+// compactInt returns the integer value with the smallest constant
+// representation.
+func compactInt(i *big.Int) interface{} {
+	if i.IsInt64() && i.Int64() >= math.MinInt32 && i.Int64() <= math.MaxInt32 {
+		return int32(i.Int64())
+	}
+	if i.IsUint64() {
+		return i.Uint64()
+	}
+	return i
+}
+
+// Eval implements the compiler.ast.AST.Eval for return statements.
+func (ast *Return) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+	interface{}, bool, error) {
+
+	f := ctx.Func()
+	if f == nil {
+		return nil, false, nil
+	}
+	var values []interface{}
 
-		var lValue ssa.Variable
-		if ast.Define {
-			lValue, err = gen.NewVar(ref.Name.Name, constVal.Type, ctx.Scope())
-			if err != nil {
-				return nil, false, err
+	if len(ast.Exprs) == 0 {
+		// Named results.
+		for _, r := range f.Return {
+			b, ok := env.Get(r.Name)
+			if len(r.Name) == 0 || !ok {
+				return nil, false, nil
 			}
-		} else {
-			b, ok := env.Get(ref.Name.Name)
-			if !ok {
-				return nil, false, ctx.logger.Errorf(ast.Loc,
-					"undefined variable '%s'", ref.Name)
-			}
-			lValue, err = gen.NewVar(b.Name, b.Type, ctx.Scope())
-			if err != nil {
-				return nil, false, err
+			v, ok := b.Bound.(*ssa.Variable)
+			if !ok || !v.Const {
+				return nil, false, nil
 			}
+			values = append(values, v.ConstValue)
 		}
-		env.Set(lValue, &constVal)
+		return evalReturn(values), true, nil
 	}
 
-	return values, true, nil
+	call, ok := ast.Exprs[0].(*Call)
+	if ok && len(ast.Exprs) == 1 && len(f.Return) > 1 {
+		// Multiple return values.
+		var err error
+		values, ok, err = call.evalCall(env, ctx, gen)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		return evalReturn(values), true, nil
+	}
+	for _, expr := range ast.Exprs {
+		val, ok, err := expr.Eval(env, ctx, gen)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		values = append(values, val)
+	}
+	return evalReturn(values), true, nil
 }
 
-// Eval implements the compiler.ast.AST.Eval for if statements.
-func (ast *If) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+// Eval implements the compiler.ast.AST.Eval for for statements.
+func (ast *For) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 	interface{}, bool, error) {
-	return nil, false, nil
+
+	n := len(env.Bindings)
+	val, ok, err := ast.eval(env, ctx, gen)
+	env.Bindings = env.Bindings[:n]
+
+	return val, ok, err
 }
 
-// Eval implements the compiler.ast.AST.Eval for call expressions.
-func (ast *Call) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+func (ast *For) eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 	interface{}, bool, error) {
 
-	// Resolve called.
-	var pkg *Package
-	var ok bool
-	if len(ast.Name.Package) > 0 {
-		pkg, ok = ctx.Packages[ast.Name.Package]
+	_, ok, err := ast.Init.Eval(env, ctx, gen)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	for count := 0; ; count++ {
+		val, ok, err := ast.Cond.Eval(env, ctx, gen)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		cond, ok := val.(bool)
 		if !ok {
-			return nil, false,
-				ctx.logger.Errorf(ast.Loc, "package '%s' not found",
-					ast.Name.Package)
+			return nil, false, ctx.logger.Errorf(ast.Cond.Location(),
+				"condition is not boolean expression")
 		}
-	} else {
-		pkg = ctx.Package
-	}
-	_, ok = pkg.Functions[ast.Name.Name]
-	if ok {
-		return nil, false, nil
-	}
-	// Check builtin functions.
-	for _, bi := range builtins {
-		if bi.Name != ast.Name.Name {
-			continue
+		if !cond {
+			return nil, true, nil
 		}
-		if bi.Type != BuiltinFunc {
-			return nil, false, fmt.Errorf("builtin %s used as function",
-				bi.Name)
+		if ast.Max > 0 && count >= ast.Max {
+			return nil, false, ctx.logger.Errorf(ast.Loc,
+				"loop exceeds the maximum iteration count %d", ast.Max)
 		}
-		if bi.Eval == nil {
-			return nil, false, nil
+		val, ok, err = evalBlock(ast.Body, env, ctx, gen)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		if _, ok := val.(evalReturn); ok {
+			return val, true, nil
+		}
+		if val == evalBreak {
+			return nil, true, nil
+		}
+		_, ok, err = ast.Inc.Eval(env, ctx, gen)
+		if err != nil || !ok {
+			return nil, ok, err
 		}
-		return bi.Eval(ast.Exprs, env, ctx, gen, ast.Location())
 	}
-
-	return nil, false, nil
 }
 
-// Eval implements the compiler.ast.AST.Eval for return statements.
-func (ast *Return) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+// Eval implements the compiler.ast.AST.Eval for break statements.
+func (ast *Break) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 	interface{}, bool, error) {
-	return nil, false, nil
+	return evalBreak, true, nil
 }
 
-// Eval implements the compiler.ast.AST.Eval for for statements.
-func (ast *For) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+// Eval implements the compiler.ast.AST.Eval for continue statements.
+func (ast *Continue) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 	interface{}, bool, error) {
-	return nil, false, nil
+	return evalContinue, true, nil
 }
 
 // Eval implements the compiler.ast.AST.Eval for binary expressions.
 func (ast *Binary) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+	interface{}, bool, error) {
+	val, ok, err := ast.eval(env, ctx, gen)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	return fitTyped(ast, env, ctx, gen, val)
+}
+
+func (ast *Binary) eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 	interface{}, bool, error) {
 	l, ok, err := ast.Left.Eval(env, ctx, gen)
 	if err != nil || !ok {
@@ -166,45 +1138,34 @@ func (ast *Binary) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 		return nil, ok, err
 	}
 
+	if isFloatValue(l) || isFloatValue(r) {
+		return ast.evalFloat(ctx, l, r)
+	}
+
+	if isIntValue(l) && isIntValue(r) {
+		_, l64 := l.(uint64)
+		_, r64 := r.(uint64)
+		if !l64 || !r64 {
+			return ast.evalInt(ctx, l, r)
+		}
+	}
+
 	switch lval := l.(type) {
-	case int32:
-		var rval int32
-		switch rv := r.(type) {
-		case int32:
-			rval = rv
-		default:
+	case bool:
+		rval, ok := r.(bool)
+		if !ok {
 			return nil, false, ctx.logger.Errorf(ast.Right.Location(),
-				"invalid r-value %T %s %T", lval, ast.Op, rv)
+				"invalid r-value %T %s %T", lval, ast.Op, r)
 		}
 		switch ast.Op {
-		case BinaryMult:
-			return lval * rval, true, nil
-		case BinaryDiv:
-			return lval / rval, true, nil
-		case BinaryMod:
-			return lval % rval, true, nil
-		case BinaryLshift:
-			return lval << rval, true, nil
-		case BinaryRshift:
-			return lval >> rval, true, nil
-
-		case BinaryPlus:
-			return lval + rval, true, nil
-		case BinaryMinus:
-			return lval - rval, true, nil
-
 		case BinaryEq:
 			return lval == rval, true, nil
 		case BinaryNeq:
 			return lval != rval, true, nil
-		case BinaryLt:
-			return lval < rval, true, nil
-		case BinaryLe:
-			return lval <= rval, true, nil
-		case BinaryGt:
-			return lval > rval, true, nil
-		case BinaryGe:
-			return lval >= rval, true, nil
+		case BinaryAnd:
+			return lval && rval, true, nil
+		case BinaryOr:
+			return lval || rval, true, nil
 		default:
 			return nil, false, ctx.logger.Errorf(ast.Right.Location(),
 				"Binary.Eval '%T %s %T' not implemented yet", l, ast.Op, r)
@@ -230,6 +1191,14 @@ func (ast *Binary) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 			return lval << rval, true, nil
 		case BinaryRshift:
 			return lval >> rval, true, nil
+		case BinaryBand:
+			return lval & rval, true, nil
+		case BinaryBclear:
+			return lval &^ rval, true, nil
+		case BinaryBor:
+			return lval | rval, true, nil
+		case BinaryBxor:
+			return lval ^ rval, true, nil
 
 		case BinaryPlus:
 			return lval + rval, true, nil
@@ -253,12 +1222,232 @@ func (ast *Binary) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 				"Binary.Eval '%T %s %T' not implemented yet", l, ast.Op, r)
 		}
 
+	case *ssa.CompositeValue:
+		return nil, false, nil
+
 	default:
 		return nil, false, ctx.logger.Errorf(ast.Left.Location(),
 			"invalid l-value %v (%T)", lval, lval)
 	}
 }
 
+func isIntValue(v interface{}) bool {
+	switch v.(type) {
+	case int32, uint64, *big.Int:
+		return true
+	default:
+		return false
+	}
+}
+
+// evalInt evaluates the binary expression with arbitrary precision
+// integer operands. The result is returned in its smallest constant
+// representation.
+func (ast *Binary) evalInt(ctx *Codegen, l, r interface{}) (
+	interface{}, bool, error) {
+
+	lval, err := constInt(l)
+	if err != nil {
+		return nil, false, ctx.logger.Errorf(ast.Left.Location(), "%s", err)
+	}
+	rval, err := constInt(r)
+	if err != nil {
+		return nil, false, ctx.logger.Errorf(ast.Right.Location(), "%s", err)
+	}
+
+	result := new(big.Int)
+	switch ast.Op {
+	case BinaryMult:
+		result.Mul(lval, rval)
+	case BinaryDiv, BinaryMod:
+		if rval.Sign() == 0 {
+			return nil, false, ctx.logger.Errorf(ast.Right.Location(),
+				"division by zero")
+		}
+		if ast.Op == BinaryDiv {
+			result.Quo(lval, rval)
+		} else {
+			result.Rem(lval, rval)
+		}
+	case BinaryLshift, BinaryRshift:
+		if rval.Sign() < 0 || !rval.IsInt64() || rval.Int64() > math.MaxUint16 {
+			return nil, false, ctx.logger.Errorf(ast.Right.Location(),
+				"invalid shift count %s", rval)
+		}
+		if ast.Op == BinaryLshift {
+			result.Lsh(lval, uint(rval.Int64()))
+		} else {
+			result.Rsh(lval, uint(rval.Int64()))
+		}
+	case BinaryBand:
+		result.And(lval, rval)
+	case BinaryBclear:
+		result.AndNot(lval, rval)
+	case BinaryPlus:
+		result.Add(lval, rval)
+	case BinaryMinus:
+		result.Sub(lval, rval)
+	case BinaryBor:
+		result.Or(lval, rval)
+	case BinaryBxor:
+		result.Xor(lval, rval)
+
+	case BinaryEq:
+		return lval.Cmp(rval) == 0, true, nil
+	case BinaryNeq:
+		return lval.Cmp(rval) != 0, true, nil
+	case BinaryLt:
+		return lval.Cmp(rval) < 0, true, nil
+	case BinaryLe:
+		return lval.Cmp(rval) <= 0, true, nil
+	case BinaryGt:
+		return lval.Cmp(rval) > 0, true, nil
+	case BinaryGe:
+		return lval.Cmp(rval) >= 0, true, nil
+	default:
+		return nil, false, ctx.logger.Errorf(ast.Loc,
+			"invalid operation: operator %s not defined for %v (%T)",
+			ast.Op, l, l)
+	}
+	return compactInt(result), true, nil
+}
+
+func isFloatValue(v interface{}) bool {
+	switch v.(type) {
+	case float32, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// evalFloat evaluates the binary expression with floating point
+// operands. The result is float32 if either operand is float32.
+func (ast *Binary) evalFloat(ctx *Codegen, l, r interface{}) (
+	interface{}, bool, error) {
+
+	_, l32 := l.(float32)
+	_, r32 := r.(float32)
+
+	lf, err := ssa.FloatValue(l, 64)
+	if err != nil {
+		return nil, false, ctx.logger.Errorf(ast.Left.Location(),
+			"invalid l-value %v (%T)", l, l)
+	}
+	rf, err := ssa.FloatValue(r, 64)
+	if err != nil {
+		return nil, false, ctx.logger.Errorf(ast.Right.Location(),
+			"invalid r-value %v (%T)", r, r)
+	}
+	lval := lf.(float64)
+	rval := rf.(float64)
+
+	var result float64
+	switch ast.Op {
+	case BinaryMult:
+		result = lval * rval
+	case BinaryDiv:
+		if rval == 0 {
+			return nil, false, ctx.logger.Errorf(ast.Right.Location(),
+				"division by zero")
+		}
+		result = lval / rval
+	case BinaryPlus:
+		result = lval + rval
+	case BinaryMinus:
+		result = lval - rval
+
+	case BinaryEq:
+		return lval == rval, true, nil
+	case BinaryNeq:
+		return lval != rval, true, nil
+	case BinaryLt:
+		return lval < rval, true, nil
+	case BinaryLe:
+		return lval <= rval, true, nil
+	case BinaryGt:
+		return lval > rval, true, nil
+	case BinaryGe:
+		return lval >= rval, true, nil
+	default:
+		return nil, false, ctx.logger.Errorf(ast.Loc,
+			"invalid operation: operator %s not defined for %v (%T)",
+			ast.Op, l, l)
+	}
+	if l32 || r32 {
+		return float32(result), true, nil
+	}
+	return result, true, nil
+}
+
+// Eval implements the compiler.ast.AST.Eval for unary expressions.
+func (ast *Unary) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+	interface{}, bool, error) {
+	val, ok, err := ast.eval(env, ctx, gen)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	if ast.Op == UnaryBnot && isIntValue(val) {
+		// The complement of unsigned values has the size of the
+		// type.
+		t, _, ok := evalType(ast, env, ctx, gen)
+		if ok && t.Type == types.Uint && t.Bits > 0 {
+			return wrapValue(val, t), true, nil
+		}
+	}
+	return fitTyped(ast, env, ctx, gen, val)
+}
+
+func (ast *Unary) eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+	interface{}, bool, error) {
+	expr, ok, err := ast.Expr.Eval(env, ctx, gen)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	switch val := expr.(type) {
+	case bool:
+		switch ast.Op {
+		case UnaryNot:
+			return !val, true, nil
+		}
+
+	case int32, int64, uint64, *big.Int:
+		// Integer constants are arbitrary precision so negation
+		// must not overflow the Go type of the operand.
+		i, err := constInt(val)
+		if err != nil {
+			return nil, false, ctx.logger.Errorf(ast.Loc, "%s", err)
+		}
+		switch ast.Op {
+		case UnaryPlus:
+			return val, true, nil
+		case UnaryMinus:
+			return compactInt(i.Neg(i)), true, nil
+		case UnaryBnot:
+			return compactInt(i.Not(i)), true, nil
+		}
+
+	case float32:
+		switch ast.Op {
+		case UnaryPlus:
+			return val, true, nil
+		case UnaryMinus:
+			return -val, true, nil
+		}
+
+	case float64:
+		switch ast.Op {
+		case UnaryPlus:
+			return val, true, nil
+		case UnaryMinus:
+			return -val, true, nil
+		}
+	}
+	return nil, false, ctx.logger.Errorf(ast.Loc,
+		"invalid operation: operator %s not defined for %v (%T)",
+		ast.Op, expr, expr)
+}
+
 func bigInt(i interface{}, ctx *Codegen, loc utils.Point) (*big.Int, error) {
 	switch val := i.(type) {
 	case int:
@@ -270,6 +1459,69 @@ func bigInt(i interface{}, ctx *Codegen, loc utils.Point) (*big.Int, error) {
 	}
 }
 
+// Eval implements the compiler.ast.AST.Eval for index expressions.
+func (ast *Index) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+	interface{}, bool, error) {
+
+	expr, ok, err := ast.Expr.Eval(env, ctx, gen)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	arr, ok := expr.(*ssa.CompositeValue)
+	if !ok || arr.Type.Type != types.Array {
+		return nil, false, ctx.logger.Errorf(ast.Loc,
+			"invalid operation: %s (type %T does not support indexing)",
+			ast, expr)
+	}
+	val, ok, err := ast.Index.Eval(env, ctx, gen)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	idx, err := intVal(val)
+	if err != nil {
+		return nil, false, ctx.logger.Errorf(ast.Index.Location(),
+			"invalid array index %s", ast.Index)
+	}
+	if idx < 0 || idx >= arr.Type.ArraySize {
+		return nil, false, ctx.logger.Errorf(ast.Index.Location(),
+			"invalid array index %d (out of bounds for %d-element array)",
+			idx, arr.Type.ArraySize)
+	}
+	_, val = arr.Element(idx)
+	return val, true, nil
+}
+
+// Eval implements the compiler.ast.AST.Eval for selector expressions.
+func (ast *Selector) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+	interface{}, bool, error) {
+
+	expr, ok, err := ast.Expr.Eval(env, ctx, gen)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	return evalField(ctx, ast.Loc, expr, ast.Expr.String(), ast.Name)
+}
+
+// evalField selects the field name from the constant struct value.
+func evalField(ctx *Codegen, loc utils.Point, value interface{},
+	owner, name string) (interface{}, bool, error) {
+
+	s, ok := value.(*ssa.CompositeValue)
+	if !ok || s.Type.Type != types.Struct {
+		return nil, false, ctx.logger.Errorf(loc,
+			"%s.%s undefined", owner, name)
+	}
+	for idx, f := range s.Type.Struct {
+		if f.Name == name {
+			_, val := s.Element(idx)
+			return val, true, nil
+		}
+	}
+	return nil, false, ctx.logger.Errorf(loc,
+		"%s.%s undefined (type %s has no field or method %s)",
+		owner, name, s.Type, name)
+}
+
 // Eval implements the compiler.ast.AST.Eval for slice expressions.
 func (ast *Slice) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 	interface{}, bool, error) {
@@ -320,16 +1572,41 @@ func (ast *Slice) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 		tmp &^= 0xffffffff << (to - from)
 		return int32(tmp), ok, nil
 
+	case *ssa.CompositeValue:
+		return nil, false, nil
+
 	default:
 		return nil, false, ctx.logger.Errorf(ast.Expr.Location(),
 			"Slice.Eval: expr %T not implemented yet", val)
 	}
 }
 
+// constIntVal evaluates the constant integer value of the expression
+// expr.
+func constIntVal(expr AST, env *Env, ctx *Codegen, gen *ssa.Generator) (
+	int, error) {
+
+	val, ok, err := expr.Eval(env, ctx, gen)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ctx.logger.Errorf(expr.Location(),
+			"%s is not constant", expr)
+	}
+	i, err := intVal(val)
+	if err != nil {
+		return 0, ctx.logger.Errorf(expr.Location(), err.Error())
+	}
+	return i, nil
+}
+
 func intVal(val interface{}) (int, error) {
 	switch v := val.(type) {
 	case int32:
 		return int(v), nil
+	case uint64:
+		return int(v), nil
 	default:
 		return 0, fmt.Errorf("invalid slice index: %T", v)
 	}
@@ -350,8 +1627,8 @@ func (ast *VariableRef) Eval(env *Env, ctx *Codegen,
 		if !ok || !val.Const {
 			return nil, false, nil
 		}
-		return nil, false, ctx.logger.Errorf(ast.Loc,
-			"select not implemented yet")
+		return evalField(ctx, ast.Loc, val.ConstValue, ast.Name.Package,
+			ast.Name.Name)
 	}
 
 	if len(ast.Name.Package) > 0 {
@@ -378,6 +1655,53 @@ func (ast *VariableRef) Eval(env *Env, ctx *Codegen,
 	return val.ConstValue, true, nil
 }
 
+// Eval implements the compiler.ast.AST.Eval for composite literals.
+func (ast *CompositeLit) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
+	interface{}, bool, error) {
+	return ast.eval(env, ctx, gen, nil)
+}
+
+func (ast *CompositeLit) eval(env *Env, ctx *Codegen, gen *ssa.Generator,
+	elided *types.Info) (interface{}, bool, error) {
+
+	t, elements, err := ast.elements(env, ctx, gen, elided)
+	if err != nil {
+		return nil, false, err
+	}
+	result := ssa.NewCompositeValue(t)
+	for idx, el := range elements {
+		if el == nil {
+			continue
+		}
+		elType, _ := compositeElement(t, idx)
+		val, ok, err := evalElement(env, ctx, gen, el, elType)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		v, err := ssa.Constant(gen, val)
+		if err != nil {
+			return nil, false, err
+		}
+		err = checkElement(ctx, el, v, elType)
+		if err != nil {
+			return nil, false, err
+		}
+		result.Elements[idx] = val
+	}
+	return result, true, nil
+}
+
+// evalElement evaluates the composite literal element el of type t.
+func evalElement(env *Env, ctx *Codegen, gen *ssa.Generator, el AST,
+	t types.Info) (interface{}, bool, error) {
+
+	lit, ok := el.(*CompositeLit)
+	if ok {
+		return lit.eval(env, ctx, gen, &t)
+	}
+	return el.Eval(env, ctx, gen)
+}
+
 // Eval implements the compiler.ast.AST.Eval for constant values.
 func (ast *Constant) Eval(env *Env, ctx *Codegen, gen *ssa.Generator) (
 	interface{}, bool, error) {
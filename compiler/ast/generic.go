@@ -0,0 +1,223 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package ast
+
+import (
+	"github.com/markkurossi/mpc/compiler/ssa"
+	"github.com/markkurossi/mpc/compiler/types"
+)
+
+// constraints define the predeclared type constraints.
+var constraints = map[string]func(t types.Info) bool{
+	"any": func(t types.Info) bool {
+		return true
+	},
+	"comparable": func(t types.Info) bool {
+		return t.Type != types.Undefined
+	},
+	"Ordered": func(t types.Info) bool {
+		return t.Type == types.Int || t.Type == types.Uint ||
+			t.Type == types.Float || t.Type == types.Fixed
+	},
+	"Integer": func(t types.Info) bool {
+		return t.Type == types.Int || t.Type == types.Uint
+	},
+	"Signed": func(t types.Info) bool {
+		return t.Type == types.Int
+	},
+	"Unsigned": func(t types.Info) bool {
+		return t.Type == types.Uint
+	},
+	"Float": func(t types.Info) bool {
+		return t.Type == types.Float
+	},
+}
+
+// isLength tests if the type parameter is an array length parameter.
+func (tp *TypeParam) isLength() bool {
+	if len(tp.Constraint) != 1 {
+		return false
+	}
+	c := tp.Constraint[0]
+	return c.Type == TypeName && len(c.Name.Package) == 0 &&
+		c.Name.Name == "int"
+}
+
+// satisfies tests if the type t satisfies the type parameter's
+// constraint.
+func (tp *TypeParam) satisfies(t types.Info, env *Env, ctx *Codegen,
+	gen *ssa.Generator) (bool, error) {
+
+	if len(tp.Constraint) == 1 {
+		c := tp.Constraint[0]
+		if c.Type == TypeName && len(c.Name.Package) == 0 {
+			pred, ok := constraints[c.Name.Name]
+			if ok {
+				return pred(t), nil
+			}
+		}
+	}
+	for _, c := range tp.Constraint {
+		ct, err := c.Resolve(env, ctx, gen)
+		if err != nil {
+			return false, err
+		}
+		if ct.Bits == 0 {
+			// Unsized types match all sizes.
+			if ct.Type == t.Type {
+				return true, nil
+			}
+		} else if ct.Equal(t) && ct.Name == t.Name &&
+			ct.Package == t.Package {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// instantiate binds the type parameters of the generic function
+// called in the start block of the current compilation. The type
+// parameters are bound to the explicit type arguments of the call
+// and the remaining parameters are inferred from the argument types.
+func (ast *Call) instantiate(env *Env, ctx *Codegen, gen *ssa.Generator,
+	called *Func, args []ssa.Variable) error {
+
+	if len(called.TypeParams) == 0 {
+		return ctx.logger.Errorf(ast.Loc, "%s is not a generic function",
+			ast.Name)
+	}
+	if len(ast.TypeArgs) > len(called.TypeParams) {
+		return ctx.logger.Errorf(ast.Loc,
+			"got %d type arguments but %s has %d type parameters",
+			len(ast.TypeArgs), ast.Name, len(called.TypeParams))
+	}
+
+	params := make(map[string]*TypeParam)
+	bound := make(map[string]types.Info)
+	lengths := make(map[string]int)
+
+	for idx, tp := range called.TypeParams {
+		params[tp.Name] = tp
+		if idx >= len(ast.TypeArgs) {
+			continue
+		}
+		if tp.isLength() {
+			return ctx.logger.Errorf(ast.Loc,
+				"array length %s can't be specified as type argument",
+				tp.Name)
+		}
+		t, err := ast.TypeArgs[idx].Resolve(env, ctx, gen)
+		if err != nil {
+			return ctx.logger.Errorf(ast.Loc, "%s", err)
+		}
+		bound[tp.Name] = t
+	}
+
+	// Infer the remaining type parameters. The variable arguments
+	// are unified first so that the types of constant arguments
+	// don't override them.
+	for _, constArgs := range []bool{false, true} {
+		for idx, arg := range called.Args {
+			if args[idx].Const == constArgs {
+				unify(arg.Type, args[idx].Type, params, bound, lengths)
+			}
+		}
+	}
+
+	calledEnv := NewEnv(ctx.Start())
+	for _, tp := range called.TypeParams {
+		var v ssa.Variable
+		var lval ssa.Variable
+		var err error
+
+		if tp.isLength() {
+			length, ok := lengths[tp.Name]
+			if !ok {
+				return ctx.logger.Errorf(ast.Loc,
+					"in call to %s, cannot infer %s", ast.Name, tp.Name)
+			}
+			v, err = ssa.Constant(gen, int32(length))
+			if err != nil {
+				return err
+			}
+			lval = v
+			lval.Name = tp.Name
+			lval.Scope = ctx.Scope()
+		} else {
+			t, ok := bound[tp.Name]
+			if !ok {
+				return ctx.logger.Errorf(ast.Loc,
+					"in call to %s, cannot infer %s", ast.Name, tp.Name)
+			}
+			ok, err = tp.satisfies(t, calledEnv, ctx, gen)
+			if err != nil {
+				return ctx.logger.Errorf(tp.Loc, "%s", err)
+			}
+			if !ok {
+				typeName := t.String()
+				if len(t.Name) > 0 {
+					typeName = t.Name
+				}
+				return ctx.logger.Errorf(ast.Loc,
+					"%s does not satisfy %s", typeName, tp)
+			}
+			v, err = ssa.Constant(gen, t)
+			if err != nil {
+				return err
+			}
+			lval, err = gen.NewVar(tp.Name, t, ctx.Scope())
+			if err != nil {
+				return err
+			}
+		}
+		ctx.Start().Bindings.Set(lval, &v)
+	}
+	return nil
+}
+
+// unify infers the type parameters of the parameter type ti from the
+// argument type t. The inferred types are collected into bound and
+// the inferred array lengths into lengths. Type parameters that are
+// already bound are not changed.
+func unify(ti *TypeInfo, t types.Info, params map[string]*TypeParam,
+	bound map[string]types.Info, lengths map[string]int) {
+
+	switch ti.Type {
+	case TypeName:
+		if len(ti.Name.Package) > 0 {
+			return
+		}
+		tp, ok := params[ti.Name.Name]
+		if !ok || tp.isLength() {
+			return
+		}
+		_, ok = bound[tp.Name]
+		if ok {
+			return
+		}
+		if t.Type != types.Struct && t.Type != types.Array {
+			t.MinBits = 0
+		}
+		bound[tp.Name] = t
+
+	case TypeArray:
+		if t.Type != types.Array {
+			return
+		}
+		ref, ok := ti.ArrayLength.(*VariableRef)
+		if ok && len(ref.Name.Package) == 0 {
+			tp, ok := params[ref.Name.Name]
+			if ok && tp.isLength() {
+				_, ok = lengths[tp.Name]
+				if !ok {
+					lengths[tp.Name] = t.ArraySize
+				}
+			}
+		}
+		unify(ti.ElementType, *t.ElementType, params, bound, lengths)
+	}
+}
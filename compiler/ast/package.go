@@ -23,7 +23,9 @@ type Package struct {
 	Bindings    ssa.Bindings
 	Types       []*TypeInfo
 	Constants   []*ConstantDef
+	Variables   []*VariableDef
 	Functions   map[string]*Func
+	Methods     map[string]*Func
 }
 
 // NewPackage creates a new package.
@@ -32,6 +34,7 @@ func NewPackage(name string) *Package {
 		Name:      name,
 		Imports:   make(map[string]string),
 		Functions: make(map[string]*Func),
+		Methods:   make(map[string]*Func),
 	}
 }
 
@@ -44,9 +47,13 @@ func (pkg *Package) Compile(packages map[string]*Package, logger *utils.Logger,
 		return nil, nil, logger.Errorf(utils.Point{},
 			"no main function defined")
 	}
+	if len(main.TypeParams) > 0 {
+		return nil, nil, logger.Errorf(main.Loc,
+			"func main must have no type parameters")
+	}
 
 	gen := ssa.NewGenerator(params)
-	ctx := NewCodegen(logger, pkg, packages, params.Verbose)
+	ctx := NewCodegen(logger, pkg, packages, params)
 
 	// Init package.
 	err := pkg.Init(packages, ctx, gen)
@@ -82,8 +89,8 @@ func (pkg *Package) Compile(packages map[string]*Package, logger *utils.Logger,
 			Type: a.Type.String(),
 			Size: a.Type.Bits,
 		}
-		if typeInfo.Type == types.Struct {
-			arg.Compound = flattenStruct(typeInfo)
+		if typeInfo.Type == types.Struct || typeInfo.Type == types.Array {
+			arg.Compound = flattenCompound(typeInfo)
 		}
 
 		inputs = append(inputs, arg)
@@ -149,28 +156,37 @@ func (pkg *Package) Compile(packages map[string]*Package, logger *utils.Logger,
 	return program, main.Annotations, nil
 }
 
-func flattenStruct(t types.Info) circuit.IO {
+func flattenCompound(t types.Info) circuit.IO {
 	var result circuit.IO
-	if t.Type != types.Struct {
-		return result
-	}
 
-	for _, f := range t.Struct {
-		if f.Type.Type == types.Struct {
-			ios := flattenStruct(f.Type)
-			result = append(result, ios...)
-		} else {
-			result = append(result, circuit.IOArg{
-				Name: f.Name,
-				Type: f.Type.String(),
-				Size: f.Type.Bits,
-			})
+	switch t.Type {
+	case types.Array:
+		for i := 0; i < t.ArraySize; i++ {
+			result = append(result, flattenElement("", *t.ElementType)...)
+		}
+
+	case types.Struct:
+		for _, f := range t.Struct {
+			result = append(result, flattenElement(f.Name, f.Type)...)
 		}
 	}
 
 	return result
 }
 
+func flattenElement(name string, t types.Info) circuit.IO {
+	if t.Type == types.Struct || t.Type == types.Array {
+		return flattenCompound(t)
+	}
+	return circuit.IO{
+		circuit.IOArg{
+			Name: name,
+			Type: t.String(),
+			Size: t.Bits,
+		},
+	}
+}
+
 // Init initializes the package.
 func (pkg *Package) Init(packages map[string]*Package, ctx *Codegen,
 	gen *ssa.Generator) error {
@@ -203,6 +219,23 @@ func (pkg *Package) Init(packages map[string]*Package, ctx *Codegen,
 		}
 	}
 
+	// Check method receivers.
+	env := &Env{
+		Bindings: pkg.Bindings,
+	}
+	for _, m := range pkg.Methods {
+		info, err := m.Receiver.Type.Resolve(env, ctx, gen)
+		if err != nil {
+			return ctx.logger.Errorf(m.Receiver.Loc,
+				"invalid receiver type: %s", err)
+		}
+		if info.Name != m.Receiver.Type.Name.Name || info.Package != pkg.Name {
+			return ctx.logger.Errorf(m.Receiver.Loc,
+				"cannot define new methods on non-local type %s",
+				m.Receiver.Type)
+		}
+	}
+
 	// Define constants.
 
 	block := gen.Block()
@@ -217,6 +250,23 @@ func (pkg *Package) Init(packages map[string]*Package, ctx *Codegen,
 	}
 	pkg.Bindings = block.Bindings
 
+	// Define variables. Package variables are read-only tables that
+	// are initialized at compile time.
+	env = &Env{
+		Bindings: pkg.Bindings,
+	}
+	for _, def := range pkg.Variables {
+		_, ok, err := def.Eval(env, ctx, gen)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ctx.logger.Errorf(def.Init.Location(),
+				"initializer is not constant: %s", def.Init)
+		}
+	}
+	pkg.Bindings = env.Bindings
+
 	return nil
 }
 
@@ -258,6 +308,8 @@ func (pkg *Package) defineType(def *TypeInfo, ctx *Codegen,
 			Bits:    bits,
 			MinBits: minBits,
 			Struct:  fields,
+			Name:    def.TypeName,
+			Package: pkg.Name,
 		}
 
 		v, err := ssa.Constant(gen, info)
@@ -271,7 +323,7 @@ func (pkg *Package) defineType(def *TypeInfo, ctx *Codegen,
 		pkg.Bindings.Set(lval, &v)
 		return nil
 
-	case TypeAlias:
+	case TypeAlias, TypeDefined:
 		_, ok := pkg.Bindings.Get(def.TypeName)
 		if ok {
 			return fmt.Errorf("type %s already defined", def.TypeName)
@@ -280,6 +332,10 @@ func (pkg *Package) defineType(def *TypeInfo, ctx *Codegen,
 		if err != nil {
 			return err
 		}
+		if def.Type == TypeDefined {
+			info.Name = def.TypeName
+			info.Package = pkg.Name
+		}
 		v, err := ssa.Constant(gen, info)
 		if err != nil {
 			return err
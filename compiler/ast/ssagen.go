@@ -8,9 +8,11 @@ package ast
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/markkurossi/mpc/compiler/ssa"
 	"github.com/markkurossi/mpc/compiler/types"
+	"github.com/markkurossi/mpc/compiler/utils"
 )
 
 // SSA implements the compiler.ast.AST.SSA for list statements.
@@ -19,7 +21,7 @@ func (ast List) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 
 	var err error
 
-	for _, b := range ast {
+	for idx, b := range ast {
 		if block.Dead {
 			ctx.logger.Warningf(b.Location(), "unreachable code")
 			break
@@ -28,11 +30,145 @@ func (ast List) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 		if err != nil {
 			return nil, nil, err
 		}
+		if block.Dead || idx+1 >= len(ast) {
+			continue
+		}
+
+		// Check if the statement terminated the enclosing
+		// breakable statements.
+		flags := listFlags(ctx)
+		if len(flags) == 0 {
+			continue
+		}
+		set, cond, err := flagsCond(block, gen, flags)
+		if err != nil {
+			return nil, nil, err
+		}
+		if set {
+			switch b.(type) {
+			case *Break, *Continue:
+				ctx.logger.Warningf(ast[idx+1].Location(), "unreachable code")
+			}
+			break
+		}
+		if cond != nil {
+			// Execute the rest of the statements only if the flags
+			// are not set.
+			return predicate(block, ctx, gen, *cond, flags, ast[idx+1:])
+		}
 	}
 
 	return block, nil, nil
 }
 
+// listFlags returns the flags that terminate the execution of the
+// current statement list. These are the flags of the innermost
+// breakable statements up to and including the innermost loop.
+func listFlags(ctx *Codegen) []string {
+	var flags []string
+
+	breakables := ctx.Breakables()
+	for i := len(breakables) - 1; i >= 0; i-- {
+		b := breakables[i]
+		flags = append(flags, b.Break)
+		if len(b.Continue) > 0 {
+			flags = append(flags, b.Continue)
+			break
+		}
+	}
+	return flags
+}
+
+// setFlag binds the breakable statement flag to the constant value.
+func setFlag(block *ssa.Block, gen *ssa.Generator, name string,
+	value bool) error {
+
+	v, err := ssa.Constant(gen, value)
+	if err != nil {
+		return err
+	}
+	gen.AddConstant(v)
+
+	lValue := v
+	lValue.Name = name
+	block.Bindings.Set(lValue, &v)
+
+	return nil
+}
+
+// flagsCond returns the disjunction of the argument flags. The
+// function returns true if any of the flags is constant true. If the
+// flags depend on secret values, the function returns the condition
+// variable that holds their disjunction.
+func flagsCond(block *ssa.Block, gen *ssa.Generator, flags []string) (
+	bool, *ssa.Variable, error) {
+
+	var cond *ssa.Variable
+
+	for _, name := range flags {
+		b, ok := block.Bindings.Get(name)
+		if !ok {
+			continue
+		}
+		v := b.Value(block, gen)
+		if v.Const {
+			set, ok := v.ConstValue.(bool)
+			if ok && set {
+				return true, nil, nil
+			}
+			continue
+		}
+		if cond == nil {
+			cond = &v
+			continue
+		}
+		o := gen.AnonVar(types.BoolType())
+		instr, err := ssa.NewOrInstr(*cond, v, o)
+		if err != nil {
+			return false, nil, err
+		}
+		block.AddInstr(instr)
+		cond = &o
+	}
+	return false, cond, nil
+}
+
+// predicate generates the statements so that they are executed only
+// if the flags condition cond is false. The statements are generated
+// in a branch where the flags are cleared and their bindings are
+// merged with the current bindings with phi-selectors.
+func predicate(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
+	cond ssa.Variable, flags []string, body List) (
+	*ssa.Block, []ssa.Variable, error) {
+
+	active := gen.AnonVar(types.BoolType())
+	instr, err := ssa.NewNotInstr(cond, active)
+	if err != nil {
+		return nil, nil, err
+	}
+	block.AddInstr(instr)
+	block.BranchCond = active
+
+	tBlock := gen.BranchBlock(block)
+	for _, flag := range flags {
+		err = setFlag(tBlock, gen, flag, false)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	tNext, _, err := body.SSA(tBlock, ctx, gen)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tNext.Dead {
+		return gen.NextBlock(block), nil, nil
+	}
+	tNext.Bindings = tNext.Bindings.Merge(active, block.Bindings)
+	block.SetNext(tNext)
+
+	return tNext, nil, nil
+}
+
 // SSA implements the compiler.ast.AST.SSA for function definitions.
 func (ast *Func) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 	*ssa.Block, []ssa.Variable, error) {
@@ -92,7 +228,10 @@ func (ast *ConstantDef) SSA(block *ssa.Block, ctx *Codegen,
 		return nil, nil, err
 	}
 
-	env := NewEnv(block)
+	env, err := ast.initEnv(NewEnv(block), gen)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	constVal, ok, err := ast.Init.Eval(env, ctx, gen)
 	if err != nil {
@@ -140,25 +279,20 @@ func (ast *VariableDef) SSA(block *ssa.Block, ctx *Codegen,
 	}
 
 	for _, n := range ast.Names {
-		lValue, err := gen.NewVar(n, typeInfo, ctx.Scope())
-		if err != nil {
-			return nil, nil, err
-		}
-		block.Bindings.Set(lValue, nil)
-
 		var init ssa.Variable
 		if ast.Init == nil {
 			var initVal interface{}
-			switch lValue.Type.Type {
+			switch typeInfo.Type {
 			case types.Bool:
 				initVal = false
-			case types.Int, types.Uint:
+			case types.Int, types.Uint, types.Float, types.Fixed,
+				types.Array, types.Struct:
 				initVal = int32(0)
 			case types.String:
 				initVal = ""
 			default:
 				return nil, nil, ctx.logger.Errorf(ast.Loc,
-					"unsupported variable type %s", lValue.Type.Type)
+					"unsupported variable type %s", typeInfo.Type)
 			}
 			init, err = ssa.Constant(gen, initVal)
 			if err != nil {
@@ -177,6 +311,25 @@ func (ast *VariableDef) SSA(block *ssa.Block, ctx *Codegen,
 			}
 			init = v[0]
 		}
+
+		// Variables without type get the type of their init value.
+		t := typeInfo
+		if t.Undefined() {
+			t = init.Type
+		}
+		lValue, err := gen.NewVar(n, t, ctx.Scope())
+		if err != nil {
+			return nil, nil, err
+		}
+		block.Bindings.Set(lValue, nil)
+
+		if ast.Init != nil {
+			init, err = fitConst(gen, init, lValue.Type)
+			if err != nil {
+				return nil, nil, ctx.logger.Errorf(ast.Init.Location(),
+					"%s", err)
+			}
+		}
 		block.AddInstr(ssa.NewMovInstr(init, lValue))
 	}
 	return block, nil, nil
@@ -190,17 +343,23 @@ func (ast *Assign) SSA(block *ssa.Block, ctx *Codegen,
 	var err error
 
 	for _, expr := range ast.Exprs {
-		// Check if init value is constant.
+		// Check if init value is constant. Type conversions define
+		// values of their target type and they are not folded into
+		// constants.
 		env := NewEnv(block)
 		constVal, ok, err := expr.Eval(env, ctx, gen)
 		if err != nil {
 			return nil, nil, err
 		}
+		if ok && isConversion(expr, env, ctx, gen) {
+			ok = false
+		}
 		if ok {
 			constVar, err := ssa.Constant(gen, constVal)
 			if err != nil {
 				return nil, nil, err
 			}
+			gen.AddConstant(constVar)
 			values = append(values, constVar)
 		} else {
 			var v []ssa.Variable
@@ -222,40 +381,215 @@ func (ast *Assign) SSA(block *ssa.Block, ctx *Codegen,
 	}
 
 	for idx, lv := range ast.LValues {
-		ref, ok := lv.(*VariableRef)
-		if !ok {
-			return nil, nil, ctx.logger.Errorf(ast.Loc,
-				"cannot assign to %s", lv)
+		block, err = ast.assign(block, ctx, gen, lv, values[idx])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return block, values, nil
+}
+
+// assign assigns the value to the lvalue lv.
+func (ast *Assign) assign(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
+	lv AST, value ssa.Variable) (*ssa.Block, error) {
+
+	switch lv := lv.(type) {
+	case *VariableRef:
+		_, ok := block.Bindings.Get(lv.Name.Package)
+		if ok {
+			// Struct field.
+			return ast.assign(block, ctx, gen, &Selector{
+				Loc: lv.Loc,
+				Expr: &VariableRef{
+					Loc: lv.Loc,
+					Name: Identifier{
+						Name: lv.Name.Package,
+					},
+				},
+				Name: lv.Name.Name,
+			}, value)
 		}
 		// XXX package.name below
 
 		var lValue ssa.Variable
-		b, ok := block.Bindings.Get(ref.Name.Name)
+		var err error
+		b, ok := block.Bindings.Get(lv.Name.Name)
 		if ast.Define {
-			if ok {
-				return nil, nil, ctx.logger.Errorf(ast.Loc,
+			if ok && b.Scope == ctx.Scope() {
+				return nil, ctx.logger.Errorf(ast.Loc,
 					"no new variables on left side of :=")
 			}
-			lValue, err = gen.NewVar(ref.Name.Name, values[0].Type, ctx.Scope())
+			lValue, err = gen.NewVar(lv.Name.Name, value.Type, ctx.Scope())
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 		} else {
 			if !ok {
-				return nil, nil, ctx.logger.Errorf(ast.Loc,
-					"undefined: %s", ref.Name)
+				return nil, ctx.logger.Errorf(ast.Loc,
+					"undefined: %s", lv.Name)
+			}
+			if b.Scope == 0 {
+				return nil, ctx.logger.Errorf(ast.Loc,
+					"cannot assign to %s (read-only)", lv.Name)
 			}
 			lValue, err = gen.NewVar(b.Name, b.Type, ctx.Scope())
 			if err != nil {
-				return nil, nil, err
+				return nil, err
+			}
+			value, err = fitConst(gen, value, b.Type)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		block.AddInstr(ssa.NewMovInstr(value, lValue))
+		block.Bindings.Set(lValue, &value)
+		return block, nil
+
+	case *Index:
+		if ast.Define {
+			return nil, ctx.logger.Errorf(ast.Loc,
+				"non-name %s on left side of :=", lv)
+		}
+		block, arrs, err := lv.Expr.SSA(block, ctx, gen)
+		if err != nil {
+			return nil, err
+		}
+		if len(arrs) != 1 {
+			return nil, ctx.logger.Errorf(lv.Loc, "invalid expression")
+		}
+		arr := arrs[0]
+		if arr.Type.Type != types.Array {
+			return nil, ctx.logger.Errorf(lv.Loc,
+				"invalid operation: %s (type %s does not support indexing)",
+				lv, arr.Type)
+		}
+		elType := *arr.Type.ElementType
+		value, err = fitConst(gen, value, elType)
+		if err != nil {
+			return nil, err
+		}
+		err = constFits(ctx, ast.Loc, value, elType)
+		if err != nil {
+			return nil, err
+		}
+		if !value.Const && !elType.Equal(value.Type) {
+			return nil, ctx.logger.Errorf(ast.Loc,
+				"cannot use %s (type %s) as type %s in assignment",
+				value, value.Type, elType)
+		}
+		block, idx, index, err := arrayIndex(block, ctx, gen, lv.Index,
+			arr.Type)
+		if err != nil {
+			return nil, err
+		}
+		if index == nil {
+			return ast.update(block, ctx, gen, lv.Expr, arr, value,
+				idx*elType.Bits, (idx+1)*elType.Bits)
+		}
+		t := gen.AnonVar(arr.Type)
+		block.AddInstr(ssa.NewAsetInstr(arr, *index, value, t))
+		return ast.assign(block, ctx, gen, lv.Expr, t)
+
+	case *Selector:
+		if ast.Define {
+			return nil, ctx.logger.Errorf(ast.Loc,
+				"non-name %s on left side of :=", lv)
+		}
+		block, exprs, err := lv.Expr.SSA(block, ctx, gen)
+		if err != nil {
+			return nil, err
+		}
+		if len(exprs) != 1 {
+			return nil, ctx.logger.Errorf(lv.Loc, "invalid expression")
+		}
+		field, err := structField(ctx, lv.Loc, exprs[0], lv.Expr.String(),
+			lv.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err = fitConst(gen, value, field.Type)
+		if err != nil {
+			return nil, err
+		}
+		err = constFits(ctx, ast.Loc, value, field.Type)
+		if err != nil {
+			return nil, err
+		}
+		if !value.Const && !field.Type.Equal(value.Type) {
+			return nil, ctx.logger.Errorf(ast.Loc,
+				"cannot use %s (type %s) as type %s in assignment",
+				value, value.Type, field.Type)
+		}
+		return ast.update(block, ctx, gen, lv.Expr, exprs[0], value,
+			field.Type.Offset, field.Type.Offset+field.Type.Bits)
+
+	case *Slice:
+		if ast.Define {
+			return nil, ctx.logger.Errorf(ast.Loc,
+				"non-name %s on left side of :=", lv)
+		}
+		block, exprs, err := lv.Expr.SSA(block, ctx, gen)
+		if err != nil {
+			return nil, err
+		}
+		if len(exprs) != 1 {
+			return nil, ctx.logger.Errorf(lv.Loc, "invalid expression")
+		}
+		bits := exprs[0].Type.Bits
+		from, to := 0, bits
+		if lv.From != nil {
+			from, err = constIntVal(lv.From, NewEnv(block), ctx, gen)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if lv.To != nil {
+			to, err = constIntVal(lv.To, NewEnv(block), ctx, gen)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if from < 0 || from >= to || to > bits {
+			return nil, ctx.logger.Errorf(lv.Loc,
+				"slice bounds out of range [%d:%d]", from, to)
+		}
+		if value.Const {
+			if value.Type.MinBits > to-from {
+				return nil, ctx.logger.Errorf(ast.Loc,
+					"constant %s overflows %d-bit slice", value, to-from)
 			}
+		} else if value.Type.Bits > to-from {
+			return nil, ctx.logger.Errorf(ast.Loc,
+				"cannot use %s (type %s) as %d-bit slice in assignment",
+				value, value.Type, to-from)
 		}
+		return ast.update(block, ctx, gen, lv.Expr, exprs[0], value, from, to)
 
-		block.AddInstr(ssa.NewMovInstr(values[idx], lValue))
-		block.Bindings.Set(lValue, &values[idx])
+	default:
+		return nil, ctx.logger.Errorf(ast.Loc, "cannot assign to %s", lv)
 	}
+}
 
-	return block, values, nil
+// update assigns the value to the bits from-to of the base value
+// and stores the updated value into the lvalue lv.
+func (ast *Assign) update(block *ssa.Block, ctx *Codegen,
+	gen *ssa.Generator, lv AST, base, value ssa.Variable, from, to int) (
+	*ssa.Block, error) {
+
+	fromConst, err := ssa.Constant(gen, int32(from))
+	if err != nil {
+		return nil, err
+	}
+	toConst, err := ssa.Constant(gen, int32(to))
+	if err != nil {
+		return nil, err
+	}
+	t := gen.AnonVar(base.Type)
+	block.AddInstr(ssa.NewAmovInstr(value, base, fromConst, toConst, t))
+
+	return ast.assign(block, ctx, gen, lv, t)
 }
 
 // SSA implements the compiler.ast.AST.SSA for if statements.
@@ -355,17 +689,144 @@ func (ast *If) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 	return next, nil, nil
 }
 
+// SSA implements the compiler.ast.AST.SSA for switch statements. The
+// switch is lowered into an if-else chain so that constant cases are
+// folded at compile time and secret cases are merged with phi
+// functions.
+func (ast *Switch) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
+	*ssa.Block, []ssa.Variable, error) {
+
+	// Evaluate the tag expression only once.
+	var tag AST
+	if ast.Expr != nil {
+		constVal, ok, err := ast.Expr.Eval(NewEnv(block), ctx, gen)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			tag = &Constant{
+				Loc:   ast.Expr.Location(),
+				Value: constVal,
+			}
+		} else {
+			var v []ssa.Variable
+			block, v, err = ast.Expr.SSA(block, ctx, gen)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(v) == 0 {
+				return nil, nil, ctx.logger.Errorf(ast.Expr.Location(),
+					"%s used as value", ast.Expr)
+			} else if len(v) > 1 {
+				return nil, nil, ctx.logger.Errorf(ast.Expr.Location(),
+					"multiple-value %s used in single-value context",
+					ast.Expr)
+			}
+			tag = &valueRef{
+				Loc:   ast.Expr.Location(),
+				Value: v[0],
+			}
+		}
+	}
+
+	var root, tail *If
+	var def List
+
+	for _, c := range ast.Cases {
+		if c.Exprs == nil {
+			def = c.Body
+			continue
+		}
+		var cond AST
+		for _, expr := range c.Exprs {
+			if tag != nil {
+				expr = &Binary{
+					Loc:   expr.Location(),
+					Left:  tag,
+					Op:    BinaryEq,
+					Right: expr,
+				}
+			}
+			if cond == nil {
+				cond = expr
+			} else {
+				cond = &Binary{
+					Loc:   expr.Location(),
+					Left:  cond,
+					Op:    BinaryOr,
+					Right: expr,
+				}
+			}
+		}
+		branch := &If{
+			Loc:  c.Loc,
+			Expr: cond,
+			True: c.Body,
+		}
+		if tail == nil {
+			root = branch
+		} else {
+			tail.False = List{branch}
+		}
+		tail = branch
+	}
+	breakable := ctx.PushBreakable(false)
+
+	err := setFlag(block, gen, breakable.Break, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if root == nil {
+		block, _, err = def.SSA(block, ctx, gen)
+	} else {
+		tail.False = def
+		block, _, err = root.SSA(block, ctx, gen)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx.PopBreakable()
+
+	return block, nil, nil
+}
+
 // SSA implements the compiler.ast.AST.SSA for call expressions.
 func (ast *Call) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 	*ssa.Block, []ssa.Variable, error) {
 
-	// Generate call values.
+	// Evaluate function calls with constant arguments at compile
+	// time.
+	constVals, ok, err := ast.evalCall(NewEnv(block), ctx, gen)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ok {
+		var result []ssa.Variable
+		for _, val := range constVals {
+			v, err := ssa.Constant(gen, val)
+			if err != nil {
+				return nil, nil, err
+			}
+			gen.AddConstant(v)
+			result = append(result, v)
+		}
+		return block, result, nil
+	}
+
+	// Generate call values. The method receiver is the first call
+	// value.
+
+	exprs := ast.Exprs
+	recv := ast.receiver(NewEnv(block))
+	if recv != nil {
+		exprs = append([]AST{recv}, exprs...)
+	}
 
 	var callValues [][]ssa.Variable
 	var v []ssa.Variable
-	var err error
 
-	for _, expr := range ast.Exprs {
+	for _, expr := range exprs {
 		block, v, err = expr.SSA(block, ctx, gen)
 		if err != nil {
 			return nil, nil, err
@@ -375,18 +836,30 @@ func (ast *Call) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 
 	// Resolve called.
 	var pkg *Package
-	if len(ast.Name.Package) > 0 {
-		var ok bool
-		pkg, ok = ctx.Packages[ast.Name.Package]
-		if !ok {
-			return nil, nil, ctx.logger.Errorf(ast.Loc,
-				"package '%s' not found", ast.Name.Package)
+	var called *Func
+	if recv != nil {
+		pkg, called, err = ast.method(ctx, recv, callValues[0])
+		if err != nil {
+			return nil, nil, err
 		}
+		ok = true
 	} else {
-		pkg = ctx.Package
+		if len(ast.Name.Package) > 0 {
+			pkg, ok = ctx.Packages[ast.Name.Package]
+			if !ok {
+				return nil, nil, ctx.logger.Errorf(ast.Loc,
+					"package '%s' not found", ast.Name.Package)
+			}
+		} else {
+			pkg = ctx.Package
+		}
+		called, ok = pkg.Functions[ast.Name.Name]
 	}
-	called, ok := pkg.Functions[ast.Name.Name]
 	if !ok {
+		if len(ast.TypeArgs) > 0 {
+			return nil, nil, ctx.logger.Errorf(ast.Loc,
+				"%s is not a generic function", ast.Name)
+		}
 		// Check builtin functions.
 		for _, bi := range builtins {
 			if bi.Name != ast.Name.Name {
@@ -405,18 +878,17 @@ func (ast *Call) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 		}
 
 		// Resolve name as type.
-		typeName := &TypeInfo{
-			Type: TypeName,
-			Name: ast.Name,
-		}
-		typeInfo, err := typeName.Resolve(NewEnv(block), ctx, gen)
+		typeInfo, ok, err := ast.conversionType(NewEnv(block), ctx, gen)
 		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
 			return nil, nil, ctx.logger.Errorf(ast.Loc, "undefined: %s",
 				ast.Name)
 		}
 		if len(callValues) != 1 {
-			return nil, nil, ctx.logger.Errorf(ast.Loc, "undefined: %s",
-				ast.Name)
+			return nil, nil, ctx.logger.Errorf(ast.Loc,
+				"invalid amount of arguments in conversion to %s", typeInfo)
 		}
 		if len(callValues[0]) == 0 {
 			return nil, nil, ctx.logger.Errorf(ast.Exprs[0].Location(),
@@ -427,29 +899,30 @@ func (ast *Call) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 				"multiple-value %s in single-value context", ast.Exprs[0])
 		}
 
-		// Convert value to type
-		t := gen.AnonVar(typeInfo)
-		block.AddInstr(ssa.NewMovInstr(callValues[0][0], t))
+		return ast.convert(block, ctx, gen, callValues[0][0], typeInfo)
+	}
 
-		return block, []ssa.Variable{t}, nil
+	params := called.Args
+	if called.Receiver != nil {
+		params = append([]*Variable{called.Receiver}, params...)
 	}
 
 	var args []ssa.Variable
 
 	if len(callValues) == 0 {
-		if len(called.Args) != 0 {
+		if len(params) != 0 {
 			return nil, nil, ctx.logger.Errorf(ast.Loc,
 				"not enough arguments in call to %s", ast.Name)
 			// TODO \thave ()
 			// TODO \twant (int, int)
 		}
 	} else if len(callValues) == 1 {
-		if len(callValues[0]) < len(called.Args) {
+		if len(callValues[0]) < len(params) {
 			return nil, nil, ctx.logger.Errorf(ast.Loc,
 				"not enough arguments in call to %s", ast.Name)
 			// TODO \thave ()
 			// TODO \twant (int, int)
-		} else if len(callValues[0]) > len(called.Args) {
+		} else if len(callValues[0]) > len(params) {
 			return nil, nil, ctx.logger.Errorf(ast.Loc,
 				"too many arguments in call to %s", ast.Name)
 			// TODO \thave (int, int)
@@ -457,19 +930,19 @@ func (ast *Call) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 		}
 		args = callValues[0]
 	} else {
-		if len(callValues) < len(called.Args) {
+		if len(callValues) < len(params) {
 			return nil, nil, ctx.logger.Errorf(ast.Loc,
 				"not enough arguments in call to %s", ast.Name)
 			// TODO \thave ()
 			// TODO \twant (int, int)
-		} else if len(callValues) > len(called.Args) {
+		} else if len(callValues) > len(params) {
 			return nil, nil, ctx.logger.Errorf(ast.Loc,
 				"too many arguments in call to %s", ast.Name)
 			// TODO \thave (int, int)
 			// TODO \twant ()
 		} else {
 			for idx, ca := range callValues {
-				expr := ast.Exprs[idx]
+				expr := exprs[idx]
 				if len(ca) == 0 {
 					return nil, nil,
 						ctx.logger.Errorf(expr.Location(),
@@ -484,15 +957,32 @@ func (ast *Call) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 		}
 	}
 
+	// Bound the recursion. Each compilation, excluding main, is an
+	// inlined call.
+	if len(ctx.Stack) > ctx.MaxInlineDepth {
+		return nil, nil, ctx.logger.Errorf(ast.Loc,
+			"maximum inline depth %d exceeded: %s", ctx.MaxInlineDepth,
+			ctx.CallChain(called))
+	}
+
 	// Return block.
 	rblock := gen.Block()
 	rblock.Bindings = block.Bindings.Clone()
 
 	ctx.PushCompilation(gen.Block(), gen.Block(), rblock, called)
+	ctx.Start().Bindings = pkg.Bindings.Clone()
+
+	// Instantiate generic functions.
+	if len(called.TypeParams) > 0 || len(ast.TypeArgs) > 0 {
+		err = ast.instantiate(NewEnv(block), ctx, gen, called, args)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
 	// Define arguments.
-	for idx, arg := range called.Args {
-		typeInfo, err := arg.Type.Resolve(NewEnv(block), ctx, gen)
+	for idx, arg := range params {
+		typeInfo, err := arg.Type.Resolve(NewEnv(ctx.Start()), ctx, gen)
 		if err != nil {
 			return nil, nil, ctx.logger.Errorf(arg.Loc,
 				"invalid argument type: %s", err)
@@ -504,6 +994,10 @@ func (ast *Call) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 		if err != nil {
 			return nil, nil, err
 		}
+		args[idx], err = fitConst(gen, args[idx], typeInfo)
+		if err != nil {
+			return nil, nil, err
+		}
 		if !a.TypeCompatible(args[idx]) {
 			return nil, nil, ctx.logger.Errorf(ast.Location(),
 				"invalid value %v for argument %d of %s",
@@ -530,16 +1024,278 @@ func (ast *Call) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 	return block, returnValues, nil
 }
 
-// SSA implements the compiler.ast.AST.SSA for return statements.
-func (ast *Return) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
-	*ssa.Block, []ssa.Variable, error) {
+// receiver returns the method receiver expression of the call or nil
+// if the call is not a method call.
+func (ast *Call) receiver(env *Env) AST {
+	if ast.Recv != nil {
+		return ast.Recv
+	}
+	if len(ast.Name.Package) == 0 {
+		return nil
+	}
+	_, ok := env.Get(ast.Name.Package)
+	if !ok {
+		return nil
+	}
+	return &VariableRef{
+		Loc: ast.Loc,
+		Name: Identifier{
+			Name: ast.Name.Package,
+		},
+	}
+}
 
-	if ctx.Func() == nil {
-		return nil, nil, ctx.logger.Errorf(ast.Loc, "return outside function")
+// method resolves the called method and its package from the type of
+// the receiver value.
+func (ast *Call) method(ctx *Codegen, recv AST, values []ssa.Variable) (
+	*Package, *Func, error) {
+
+	if len(values) != 1 {
+		return nil, nil, ctx.logger.Errorf(recv.Location(),
+			"multiple-value %s in single-value context", recv)
+	}
+	t := values[0].Type
+	typeName := t.String()
+	if len(t.Name) > 0 {
+		typeName = t.Name
+		pkg, ok := ctx.Packages[t.Package]
+		if !ok && t.Package == ctx.Package.Name {
+			pkg, ok = ctx.Package, true
+		}
+		if ok {
+			m, ok := pkg.Methods[fmt.Sprintf("%s.%s", t.Name, ast.Name.Name)]
+			if ok {
+				return pkg, m, nil
+			}
+		}
 	}
+	return nil, nil, ctx.logger.Errorf(ast.Loc,
+		"%s.%s undefined (type %s has no field or method %s)",
+		recv, ast.Name.Name, typeName, ast.Name.Name)
+}
 
-	var rValues [][]ssa.Variable
-	var result []ssa.Variable
+// conversionType resolves the call target as a type. The function
+// returns false if the call is not a type conversion.
+func (ast *Call) conversionType(env *Env, ctx *Codegen,
+	gen *ssa.Generator) (types.Info, bool, error) {
+
+	if ast.Type != nil {
+		t, err := ast.Type.Resolve(env, ctx, gen)
+		if err != nil {
+			return t, false, ctx.logger.Errorf(ast.Loc, "%s", err)
+		}
+		return t, true, nil
+	}
+	if ast.receiver(env) != nil {
+		return types.Info{}, false, nil
+	}
+	typeName := &TypeInfo{
+		Type: TypeName,
+		Name: ast.Name,
+	}
+	t, err := typeName.Resolve(env, ctx, gen)
+	if err != nil {
+		return t, false, nil
+	}
+	return t, true, nil
+}
+
+// convert converts the value v to the type t. Integer values are
+// sign-extended if the source type is signed and zero-extended
+// otherwise. Values larger than the target type are truncated.
+// Constant values are converted at compile time.
+func (ast *Call) convert(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
+	v ssa.Variable, t types.Info) (*ssa.Block, []ssa.Variable, error) {
+
+	if t.Type == types.Fixed || v.Type.Type == types.Fixed {
+		return ast.convertFixed(block, ctx, gen, v, t)
+	}
+	if t.Type == types.Float || v.Type.Type == types.Float {
+		return ast.convertFloat(block, ctx, gen, v, t)
+	}
+	if t.Bits == 0 {
+		t.Bits = v.Type.Bits
+	}
+	if !convertible(v.Type, t) {
+		return nil, nil, ctx.logger.Errorf(ast.Loc,
+			"cannot convert %s (type %s) to type %s", ast.Exprs[0], v.Type, t)
+	}
+	if v.Const {
+		val, ok, err := convertConst(v.ConstValue, t)
+		_, variable, _ := evalType(ast.Exprs[0], NewEnv(block), ctx, gen)
+		if variable && (t.Type == types.Int || t.Type == types.Uint) {
+			// Conversions of variables wrap around.
+			val, ok, err = wrapValue(v.ConstValue, t), true, nil
+		}
+		if err != nil {
+			return nil, nil, ctx.logger.Errorf(ast.Exprs[0].Location(),
+				"%s", err)
+		}
+		if ok {
+			c, err := ssa.Constant(gen, val)
+			if err != nil {
+				return nil, nil, err
+			}
+			gen.AddConstant(c)
+			v = c
+		}
+	}
+
+	o := gen.AnonVar(t)
+	if v.Type.Type == types.Int && !v.Const && t.Bits > v.Type.Bits &&
+		(t.Type == types.Int || t.Type == types.Uint) {
+		block.AddInstr(ssa.NewSmovInstr(v, o))
+	} else {
+		block.AddInstr(ssa.NewMovInstr(v, o))
+	}
+
+	return block, []ssa.Variable{o}, nil
+}
+
+// convertible tests if values of the type from can be converted to
+// the type to. Booleans convert to and from 1-bit integers and
+// strings to and from byte arrays of the same size.
+func convertible(from, to types.Info) bool {
+	isInt := func(t types.Info) bool {
+		return t.Type == types.Int || t.Type == types.Uint
+	}
+	isBytes := func(t types.Info) bool {
+		return t.Type == types.Array && isInt(*t.ElementType) &&
+			t.ElementType.Bits == 8
+	}
+
+	switch {
+	case from.Type == types.Undefined:
+		// Native circuit results.
+		return true
+	case isInt(from) && isInt(to):
+		return true
+	case from.Type == types.Bool && isInt(to):
+		return to.Bits == 1
+	case isInt(from) && to.Type == types.Bool:
+		return from.Bits == 1
+	case from.Type == types.String && to.Type == types.String:
+		return true
+	case from.Type == types.String && isBytes(to),
+		isBytes(from) && to.Type == types.String:
+		return from.Bits == to.Bits
+	case from.Type == types.Array && to.Type == types.Array:
+		return from.Equal(to)
+	default:
+		return from.Type == to.Type && from.Bits == to.Bits
+	}
+}
+
+// convertFloat converts the value v to or from the floating point type
+// t.
+func (ast *Call) convertFloat(block *ssa.Block, ctx *Codegen,
+	gen *ssa.Generator, v ssa.Variable, t types.Info) (
+	*ssa.Block, []ssa.Variable, error) {
+
+	if t.Type == types.Float && t.Bits == 0 {
+		if v.Type.Type == types.Float {
+			t.Bits = v.Type.Bits
+		} else {
+			t.Bits = 64
+		}
+	}
+	if v.Const {
+		switch t.Type {
+		case types.Float:
+			c, err := fitConst(gen, v, t)
+			if err != nil {
+				return nil, nil, ctx.logger.Errorf(ast.Loc, "%s", err)
+			}
+			if c.Type.Equal(t) {
+				return block, []ssa.Variable{c}, nil
+			}
+
+		case types.Int, types.Uint:
+			f, err := ssa.FloatValue(v.ConstValue, 64)
+			if err != nil {
+				return nil, nil, ctx.logger.Errorf(ast.Loc, "%s", err)
+			}
+			val := f.(float64)
+			if val != math.Trunc(val) {
+				return nil, nil, ctx.logger.Errorf(ast.Exprs[0].Location(),
+					"constant %v truncated to integer", val)
+			}
+			var cv interface{}
+			if val >= math.MinInt32 && val <= math.MaxInt32 {
+				cv = int32(val)
+			} else if val >= 0 && val < math.MaxUint64 {
+				cv = uint64(val)
+			} else {
+				return nil, nil, ctx.logger.Errorf(ast.Exprs[0].Location(),
+					"constant %v overflows %s", val, t)
+			}
+			c, err := ssa.Constant(gen, cv)
+			if err != nil {
+				return nil, nil, err
+			}
+			gen.AddConstant(c)
+			o := gen.AnonVar(t)
+			block.AddInstr(ssa.NewMovInstr(c, o))
+			return block, []ssa.Variable{o}, nil
+		}
+	}
+	if v.Type.Equal(t) {
+		return block, []ssa.Variable{v}, nil
+	}
+	o := gen.AnonVar(t)
+	instr, err := ssa.NewFloatConvInstr(v, o)
+	if err != nil {
+		return nil, nil, ctx.logger.Errorf(ast.Loc,
+			"cannot convert %s (type %s) to type %s", ast.Exprs[0], v.Type, t)
+	}
+	block.AddInstr(instr)
+
+	return block, []ssa.Variable{o}, nil
+}
+
+// convertFixed converts the value v to or from the fixed-point type
+// t. Numeric constants are converted at compile time.
+func (ast *Call) convertFixed(block *ssa.Block, ctx *Codegen,
+	gen *ssa.Generator, v ssa.Variable, t types.Info) (
+	*ssa.Block, []ssa.Variable, error) {
+
+	if t.Bits == 0 {
+		t.Bits = v.Type.Bits
+	}
+	if v.Const && t.Type == types.Fixed {
+		c, err := fitConst(gen, v, t)
+		if err != nil {
+			return nil, nil, ctx.logger.Errorf(ast.Exprs[0].Location(),
+				"%s", err)
+		}
+		if c.Type.Equal(t) {
+			return block, []ssa.Variable{c}, nil
+		}
+	}
+	if v.Type.Equal(t) {
+		return block, []ssa.Variable{v}, nil
+	}
+	o := gen.AnonVar(t)
+	instr, err := ssa.NewFixedConvInstr(v, o)
+	if err != nil {
+		return nil, nil, ctx.logger.Errorf(ast.Loc,
+			"cannot convert %s (type %s) to type %s", ast.Exprs[0], v.Type, t)
+	}
+	block.AddInstr(instr)
+
+	return block, []ssa.Variable{o}, nil
+}
+
+// SSA implements the compiler.ast.AST.SSA for return statements.
+func (ast *Return) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
+	*ssa.Block, []ssa.Variable, error) {
+
+	if ctx.Func() == nil {
+		return nil, nil, ctx.logger.Errorf(ast.Loc, "return outside function")
+	}
+
+	var rValues [][]ssa.Variable
+	var result []ssa.Variable
 	var v []ssa.Variable
 	var err error
 
@@ -615,6 +1371,10 @@ func (ast *Return) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 		if result[idx].Type.Type == types.Undefined {
 			result[idx].Type.Type = typeInfo.Type
 		}
+		result[idx], err = fitConst(gen, result[idx], typeInfo)
+		if err != nil {
+			return nil, nil, err
+		}
 
 		if !v.TypeCompatible(result[idx]) {
 			return nil, nil, ctx.logger.Errorf(ast.Location(),
@@ -636,6 +1396,13 @@ func (ast *Return) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 func (ast *For) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 	*ssa.Block, []ssa.Variable, error) {
 
+	breakable := ctx.PushBreakable(true)
+
+	err := setFlag(block, gen, breakable.Break, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Use the same env for the whole for-loop unrolling.
 	env := NewEnv(block)
 
@@ -649,29 +1416,85 @@ func (ast *For) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 			"init statement is not compile-time constant: %s", err)
 	}
 
+	flags := []string{breakable.Break}
+
 	// Expand body as long as condition is true.
-	for {
+	for count := 0; ; count++ {
+		block.Bindings = env.Bindings
+
+		set, _, err := flagsCond(block, gen, flags)
+		if err != nil {
+			return nil, nil, err
+		}
+		if set {
+			// Loop terminated with break.
+			break
+		}
 		constVal, ok, err := ast.Cond.Eval(env, ctx, gen)
 		if err != nil {
 			return nil, nil, err
 		}
-		if !ok {
-			return nil, nil, ctx.logger.Errorf(ast.Cond.Location(),
-				"condition is not compile-time constant: %s", ast.Cond)
+		if ok {
+			val, ok := constVal.(bool)
+			if !ok {
+				return nil, nil, ctx.logger.Errorf(ast.Cond.Location(),
+					"condition is not boolean expression")
+			}
+			if !val {
+				// Loop completed.
+				break
+			}
+			if ast.Max > 0 && count >= ast.Max {
+				return nil, nil, ctx.logger.Errorf(ast.Loc,
+					"loop exceeds the maximum iteration count %d", ast.Max)
+			}
+		} else {
+			if ast.Max == 0 {
+				return nil, nil, ctx.logger.Errorf(ast.Cond.Location(),
+					"condition is not compile-time constant: %s", ast.Cond)
+			}
+			if count >= ast.Max {
+				// Loop bound reached.
+				break
+			}
+			// Terminate the loop when the secret condition is false.
+			terminate := &If{
+				Loc: ast.Cond.Location(),
+				Expr: &Unary{
+					Loc:  ast.Cond.Location(),
+					Op:   UnaryNot,
+					Expr: ast.Cond,
+				},
+				True: List{
+					&Break{
+						Loc: ast.Cond.Location(),
+					},
+				},
+			}
+			block, _, err = terminate.SSA(block, ctx, gen)
+			if err != nil {
+				return nil, nil, err
+			}
 		}
-		val, ok := constVal.(bool)
-		if !ok {
-			return nil, nil, ctx.logger.Errorf(ast.Cond.Location(),
-				"condition is not boolean expression")
+
+		err = setFlag(block, gen, breakable.Continue, false)
+		if err != nil {
+			return nil, nil, err
 		}
-		if !val {
-			// Loop completed.
+		set, cond, err := flagsCond(block, gen, flags)
+		if err != nil {
+			return nil, nil, err
+		}
+		if set {
 			break
 		}
-		block.Bindings = env.Bindings
 
 		// Expand block.
-		block, _, err = ast.Body.SSA(block, ctx, gen)
+		if cond != nil {
+			block, _, err = predicate(block, ctx, gen, *cond, flags, ast.Body)
+		} else {
+			block, _, err = ast.Body.SSA(block, ctx, gen)
+		}
 		if err != nil {
 			return nil, nil, err
 		}
@@ -687,10 +1510,45 @@ func (ast *For) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 				"increment statement is not compile-time constant: %s", ast.Inc)
 		}
 	}
+	ctx.PopBreakable()
 
 	return block, nil, nil
 }
 
+// SSA implements the compiler.ast.AST.SSA for break statements.
+func (ast *Break) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
+	*ssa.Block, []ssa.Variable, error) {
+
+	breakables := ctx.Breakables()
+	if len(breakables) == 0 {
+		return nil, nil, ctx.logger.Errorf(ast.Loc,
+			"break is not in a loop or switch")
+	}
+	err := setFlag(block, gen, breakables[len(breakables)-1].Break, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, nil, nil
+}
+
+// SSA implements the compiler.ast.AST.SSA for continue statements.
+func (ast *Continue) SSA(block *ssa.Block, ctx *Codegen,
+	gen *ssa.Generator) (*ssa.Block, []ssa.Variable, error) {
+
+	breakables := ctx.Breakables()
+	for i := len(breakables) - 1; i >= 0; i-- {
+		if len(breakables[i].Continue) == 0 {
+			continue
+		}
+		err := setFlag(block, gen, breakables[i].Continue, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		return block, nil, nil
+	}
+	return nil, nil, ctx.logger.Errorf(ast.Loc, "continue is not in a loop")
+}
+
 // SSA implements the compiler.ast.AST.SSA for binary expressions.
 func (ast *Binary) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 	*ssa.Block, []ssa.Variable, error) {
@@ -743,10 +1601,43 @@ func (ast *Binary) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 	l := lArr[0]
 	r := rArr[0]
 
-	if !l.TypeCompatible(r) {
-		return nil, nil,
-			ctx.logger.Errorf(ast.Loc, "invalid types: %s %s %s",
-				l.Type, ast.Op, r.Type)
+	// Convert constants to the type of the float or fixed operand.
+	if isFractional(r.Type) && !r.Const {
+		l, err = fitConst(gen, l, r.Type)
+		if err != nil {
+			return nil, nil, ctx.logger.Errorf(ast.Left.Location(), "%s", err)
+		}
+	}
+	if isFractional(l.Type) && !l.Const {
+		r, err = fitConst(gen, r, l.Type)
+		if err != nil {
+			return nil, nil, ctx.logger.Errorf(ast.Right.Location(), "%s", err)
+		}
+	}
+
+	switch ast.Op {
+	case BinaryLshift, BinaryRshift:
+		// The shift count can be of any integer type.
+		if r.Type.Type != types.Int && r.Type.Type != types.Uint {
+			return nil, nil, ctx.logger.Errorf(ast.Right.Location(),
+				"invalid shift count %s (type %s)", ast.Right, r.Type)
+		}
+
+	default:
+		if !l.TypeCompatible(r) {
+			return nil, nil,
+				ctx.logger.Errorf(ast.Loc, "invalid types: %s %s %s",
+					l.Type, ast.Op, r.Type)
+		}
+	}
+	if isFractional(l.Type) {
+		switch ast.Op {
+		case BinaryMod, BinaryLshift, BinaryRshift, BinaryBand, BinaryBclear,
+			BinaryBor, BinaryBxor, BinaryAnd, BinaryOr:
+			return nil, nil, ctx.logger.Errorf(ast.Loc,
+				"invalid operation: operator %s not defined for %s (%s)",
+				ast.Op, ast.Left, l.Type)
+		}
 	}
 
 	// Resolve target type.
@@ -822,6 +1713,228 @@ func (ast *Binary) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 	return block, []ssa.Variable{t}, nil
 }
 
+// SSA implements the compiler.ast.AST.SSA for unary expressions.
+func (ast *Unary) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
+	*ssa.Block, []ssa.Variable, error) {
+
+	// Check constant folding.
+	constVal, ok, err := ast.Eval(NewEnv(block), ctx, gen)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ok {
+		if ctx.Verbose {
+			fmt.Printf("ConstFold: %s%v => %v\n", ast.Op, ast.Expr, constVal)
+		}
+		v, err := ssa.Constant(gen, constVal)
+		if err != nil {
+			return nil, nil, err
+		}
+		gen.AddConstant(v)
+		return block, []ssa.Variable{v}, nil
+	}
+
+	block, exprs, err := ast.Expr.SSA(block, ctx, gen)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(exprs) == 0 {
+		return nil, nil, ctx.logger.Errorf(ast.Expr.Location(),
+			"%s used as value", ast.Expr)
+	}
+	if len(exprs) > 1 {
+		return nil, nil, ctx.logger.Errorf(ast.Expr.Location(),
+			"multiple-value %s in single-value context", ast.Expr)
+	}
+	expr := exprs[0]
+
+	var valid bool
+	switch ast.Op {
+	case UnaryPlus, UnaryMinus:
+		valid = expr.Type.Type == types.Int || expr.Type.Type == types.Uint ||
+			isFractional(expr.Type)
+	case UnaryBnot:
+		valid = expr.Type.Type == types.Int || expr.Type.Type == types.Uint
+	case UnaryNot:
+		valid = expr.Type.Type == types.Bool
+	}
+	if !valid {
+		return nil, nil, ctx.logger.Errorf(ast.Loc,
+			"invalid operation: operator %s not defined for %s (%s)",
+			ast.Op, ast.Expr, expr.Type)
+	}
+	if ast.Op == UnaryPlus {
+		return block, []ssa.Variable{expr}, nil
+	}
+
+	t := gen.AnonVar(expr.Type)
+
+	var instr ssa.Instr
+	switch ast.Op {
+	case UnaryMinus:
+		instr, err = ssa.NewNegInstr(expr.Type, expr, t)
+	case UnaryNot:
+		instr, err = ssa.NewNotInstr(expr, t)
+	case UnaryBnot:
+		instr, err = ssa.NewBnotInstr(expr, t)
+	default:
+		return nil, nil, ctx.logger.Errorf(ast.Loc,
+			"Unary.SSA '%s' not implemented yet", ast.Op)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block.AddInstr(instr)
+
+	return block, []ssa.Variable{t}, nil
+}
+
+// SSA implements the compiler.ast.AST.SSA for index expressions.
+func (ast *Index) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
+	*ssa.Block, []ssa.Variable, error) {
+
+	block, exprs, err := ast.Expr.SSA(block, ctx, gen)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(exprs) != 1 {
+		return nil, nil, ctx.logger.Errorf(ast.Loc, "invalid expression")
+	}
+	arr := exprs[0]
+	if arr.Type.Type != types.Array {
+		return nil, nil, ctx.logger.Errorf(ast.Loc,
+			"invalid operation: %s (type %s does not support indexing)",
+			ast, arr.Type)
+	}
+	elType := *arr.Type.ElementType
+
+	block, idx, index, err := arrayIndex(block, ctx, gen, ast.Index, arr.Type)
+	if err != nil {
+		return nil, nil, err
+	}
+	t := gen.AnonVar(elType)
+
+	if index == nil {
+		// Constant index selects the element wires.
+		fromConst, err := ssa.Constant(gen, int32(idx*elType.Bits))
+		if err != nil {
+			return nil, nil, err
+		}
+		toConst, err := ssa.Constant(gen, int32((idx+1)*elType.Bits))
+		if err != nil {
+			return nil, nil, err
+		}
+		block.AddInstr(ssa.NewSliceInstr(arr, fromConst, toConst, t))
+	} else {
+		block.AddInstr(ssa.NewAgetInstr(arr, *index, t))
+	}
+
+	return block, []ssa.Variable{t}, nil
+}
+
+// arrayIndex evaluates the index expression for an array of type
+// arrType. If the index is constant, the function returns the
+// constant index and a nil index variable. Otherwise it returns the
+// variable holding the index value.
+func arrayIndex(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
+	index AST, arrType types.Info) (*ssa.Block, int, *ssa.Variable, error) {
+
+	constVal, ok, err := index.Eval(NewEnv(block), ctx, gen)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if ok {
+		idx, err := intVal(constVal)
+		if err != nil {
+			return nil, 0, nil, ctx.logger.Errorf(index.Location(),
+				"invalid array index %s", index)
+		}
+		if idx < 0 || idx >= arrType.ArraySize {
+			return nil, 0, nil, ctx.logger.Errorf(index.Location(),
+				"invalid array index %d (out of bounds for %d-element array)",
+				idx, arrType.ArraySize)
+		}
+		return block, idx, nil, nil
+	}
+
+	block, v, err := index.SSA(block, ctx, gen)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if len(v) != 1 {
+		return nil, 0, nil, ctx.logger.Errorf(index.Location(),
+			"invalid array index %s", index)
+	}
+	if v[0].Type.Type != types.Int && v[0].Type.Type != types.Uint {
+		return nil, 0, nil, ctx.logger.Errorf(index.Location(),
+			"invalid array index %s (type %s)", index, v[0].Type)
+	}
+	return block, 0, &v[0], nil
+}
+
+// SSA implements the compiler.ast.AST.SSA for selector expressions.
+func (ast *Selector) SSA(block *ssa.Block, ctx *Codegen,
+	gen *ssa.Generator) (*ssa.Block, []ssa.Variable, error) {
+
+	block, exprs, err := ast.Expr.SSA(block, ctx, gen)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(exprs) != 1 {
+		return nil, nil, ctx.logger.Errorf(ast.Loc, "invalid expression")
+	}
+	return selectField(block, ctx, gen, ast.Loc, exprs[0],
+		ast.Expr.String(), ast.Name)
+}
+
+// structField returns the field name of the struct value.
+func structField(ctx *Codegen, loc utils.Point, value ssa.Variable,
+	owner, name string) (types.StructField, error) {
+
+	if value.Type.Type != types.Struct {
+		return types.StructField{}, ctx.logger.Errorf(loc,
+			"%s.%s undefined", owner, name)
+	}
+	for _, f := range value.Type.Struct {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	return types.StructField{}, ctx.logger.Errorf(loc,
+		"%s.%s undefined (type %s has no field or method %s)",
+		owner, name, value.Type, name)
+}
+
+// selectField selects the field name from the struct value.
+func selectField(block *ssa.Block, ctx *Codegen, gen *ssa.Generator,
+	loc utils.Point, value ssa.Variable, owner, name string) (
+	*ssa.Block, []ssa.Variable, error) {
+
+	field, err := structField(ctx, loc, value, owner, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fieldType := field.Type
+	fieldType.MinBits = fieldType.Bits
+	fieldType.Offset = 0
+	t := gen.AnonVar(fieldType)
+
+	fromConst, err := ssa.Constant(gen, int32(field.Type.Offset))
+	if err != nil {
+		return nil, nil, err
+	}
+	toConst, err := ssa.Constant(gen,
+		int32(field.Type.Offset+field.Type.Bits))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block.AddInstr(ssa.NewSliceInstr(value, fromConst, toConst, t))
+	return block, []ssa.Variable{t}, nil
+}
+
 // SSA implements the compiler.ast.AST.SSA for slice expressions.
 func (ast *Slice) SSA(block *ssa.Block, ctx *Codegen, gen *ssa.Generator) (
 	*ssa.Block, []ssa.Variable, error) {
@@ -911,43 +2024,8 @@ func (ast *VariableRef) SSA(block *ssa.Block, ctx *Codegen,
 	b, ok = block.Bindings.Get(ast.Name.Package)
 	if ok {
 		// Selector.
-		value := b.Value(block, gen)
-		if value.Type.Type != types.Struct {
-			return nil, nil, ctx.logger.Errorf(ast.Loc,
-				"%s.%s undefined", ast.Name.Package, ast.Name.Name)
-		}
-
-		var field *types.StructField
-		for _, f := range value.Type.Struct {
-			if f.Name == ast.Name.Name {
-				field = &f
-				break
-			}
-		}
-		if field == nil {
-			return nil, nil, ctx.logger.Errorf(ast.Loc,
-				"%s.%s undefined (type %s has no field or method %s)",
-				ast.Name.Package, ast.Name.Name, value.Type, ast.Name.Name)
-		}
-
-		t := gen.AnonVar(types.Info{
-			Type:    field.Type.Type,
-			Bits:    field.Type.Bits,
-			MinBits: field.Type.Bits,
-		})
-
-		fromConst, err := ssa.Constant(gen, int32(field.Type.Offset))
-		if err != nil {
-			return nil, nil, err
-		}
-		toConst, err := ssa.Constant(gen,
-			int32(field.Type.Offset+field.Type.Bits))
-		if err != nil {
-			return nil, nil, err
-		}
-
-		block.AddInstr(ssa.NewSliceInstr(value, fromConst, toConst, t))
-		return block, []ssa.Variable{t}, nil
+		return selectField(block, ctx, gen, ast.Loc, b.Value(block, gen),
+			ast.Name.Package, ast.Name.Name)
 	}
 
 	if len(ast.Name.Package) > 0 {
@@ -981,6 +2059,299 @@ func (ast *VariableRef) SSA(block *ssa.Block, ctx *Codegen,
 	return block, []ssa.Variable{value}, nil
 }
 
+// SSA implements the compiler.ast.AST.SSA for value references.
+func (ast *valueRef) SSA(block *ssa.Block, ctx *Codegen,
+	gen *ssa.Generator) (*ssa.Block, []ssa.Variable, error) {
+	return block, []ssa.Variable{ast.Value}, nil
+}
+
+// SSA implements the compiler.ast.AST.SSA for composite literals.
+func (ast *CompositeLit) SSA(block *ssa.Block, ctx *Codegen,
+	gen *ssa.Generator) (*ssa.Block, []ssa.Variable, error) {
+	return ast.ssa(block, ctx, gen, nil)
+}
+
+func (ast *CompositeLit) ssa(block *ssa.Block, ctx *Codegen,
+	gen *ssa.Generator, elided *types.Info) (
+	*ssa.Block, []ssa.Variable, error) {
+
+	env := NewEnv(block)
+	constVal, ok, err := ast.eval(env, ctx, gen, elided)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ok {
+		v, err := ssa.Constant(gen, constVal)
+		if err != nil {
+			return nil, nil, err
+		}
+		gen.AddConstant(v)
+		return block, []ssa.Variable{v}, nil
+	}
+
+	t, elements, err := ast.elements(env, ctx, gen, elided)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Collect constant elements into the base value and compute
+	// the non-constant elements.
+	base := ssa.NewCompositeValue(t)
+	var indices []int
+	var values []ssa.Variable
+
+	for idx, el := range elements {
+		if el == nil {
+			continue
+		}
+		elType, _ := compositeElement(t, idx)
+		val, ok, err := evalElement(NewEnv(block), ctx, gen, el, elType)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			base.Elements[idx] = val
+			continue
+		}
+		var v []ssa.Variable
+		lit, ok := el.(*CompositeLit)
+		if ok {
+			block, v, err = lit.ssa(block, ctx, gen, &elType)
+		} else {
+			block, v, err = el.SSA(block, ctx, gen)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(v) != 1 {
+			return nil, nil, ctx.logger.Errorf(el.Location(),
+				"multiple-value %s used in single-value context", el)
+		}
+		err = checkElement(ctx, el, v[0], elType)
+		if err != nil {
+			return nil, nil, err
+		}
+		indices = append(indices, idx)
+		values = append(values, v[0])
+	}
+
+	result, err := ssa.Constant(gen, base)
+	if err != nil {
+		return nil, nil, err
+	}
+	gen.AddConstant(result)
+
+	for i, v := range values {
+		elType, offset := compositeElement(t, indices[i])
+		fromConst, err := ssa.Constant(gen, int32(offset))
+		if err != nil {
+			return nil, nil, err
+		}
+		toConst, err := ssa.Constant(gen, int32(offset+elType.Bits))
+		if err != nil {
+			return nil, nil, err
+		}
+		tmp := gen.AnonVar(t)
+		block.AddInstr(ssa.NewAmovInstr(v, result, fromConst, toConst, tmp))
+		result = tmp
+	}
+
+	return block, []ssa.Variable{result}, nil
+}
+
+// elements resolves the type of the composite literal and maps the
+// literal elements to the struct fields or array elements of the
+// type. The elided type is used if the literal does not specify its
+// type. The unset elements are nil.
+func (ast *CompositeLit) elements(env *Env, ctx *Codegen,
+	gen *ssa.Generator, elided *types.Info) (types.Info, []AST, error) {
+
+	var t types.Info
+	var err error
+
+	if ast.Type != nil {
+		t, err = ast.Type.Resolve(env, ctx, gen)
+		if err != nil {
+			return t, nil, ctx.logger.Errorf(ast.Loc, "%s", err)
+		}
+	} else if elided != nil {
+		t = *elided
+	} else {
+		return t, nil, ctx.logger.Errorf(ast.Loc,
+			"missing type in composite literal")
+	}
+
+	switch t.Type {
+	case types.Struct:
+		result := make([]AST, len(t.Struct))
+		if len(ast.Value) > 0 && ast.Value[0].Key == nil {
+			for idx, el := range ast.Value {
+				if el.Key != nil {
+					return t, nil, ctx.logger.Errorf(el.Key.Location(),
+						"mixture of field:value and value elements in struct literal")
+				}
+				if idx >= len(result) {
+					return t, nil, ctx.logger.Errorf(el.Element.Location(),
+						"too many values in %s", ast)
+				}
+				result[idx] = el.Element
+			}
+			if len(ast.Value) < len(result) {
+				return t, nil, ctx.logger.Errorf(ast.Loc,
+					"too few values in %s", ast)
+			}
+			return t, result, nil
+		}
+		for _, el := range ast.Value {
+			if el.Key == nil {
+				return t, nil, ctx.logger.Errorf(el.Element.Location(),
+					"mixture of field:value and value elements in struct literal")
+			}
+			ref, ok := el.Key.(*VariableRef)
+			if !ok || len(ref.Name.Package) > 0 {
+				return t, nil, ctx.logger.Errorf(el.Key.Location(),
+					"invalid field name %s in struct literal", el.Key)
+			}
+			idx := -1
+			for i, f := range t.Struct {
+				if f.Name == ref.Name.Name {
+					idx = i
+					break
+				}
+			}
+			if idx < 0 {
+				return t, nil, ctx.logger.Errorf(el.Key.Location(),
+					"unknown field '%s' in struct literal of type %s",
+					ref.Name.Name, t)
+			}
+			if result[idx] != nil {
+				return t, nil, ctx.logger.Errorf(el.Key.Location(),
+					"duplicate field name %s in struct literal",
+					ref.Name.Name)
+			}
+			result[idx] = el.Element
+		}
+		return t, result, nil
+
+	case types.Array:
+		result := make([]AST, t.ArraySize)
+		var idx int
+		for _, el := range ast.Value {
+			if el.Key != nil {
+				idx, err = constIntVal(el.Key, env, ctx, gen)
+				if err != nil {
+					return t, nil, err
+				}
+			}
+			if idx < 0 || idx >= t.ArraySize {
+				return t, nil, ctx.logger.Errorf(el.Element.Location(),
+					"array index %d out of bounds [0:%d]", idx, t.ArraySize)
+			}
+			if result[idx] != nil {
+				return t, nil, ctx.logger.Errorf(el.Element.Location(),
+					"duplicate index %d in array literal", idx)
+			}
+			result[idx] = el.Element
+			idx++
+		}
+		return t, result, nil
+
+	default:
+		return t, nil, ctx.logger.Errorf(ast.Loc,
+			"invalid type for composite literal: %s", t)
+	}
+}
+
+// compositeElement returns the type and bit offset of the element idx
+// of the struct or array type t.
+func compositeElement(t types.Info, idx int) (types.Info, int) {
+	if t.Type == types.Array {
+		return *t.ElementType, idx * t.ElementType.Bits
+	}
+	field := t.Struct[idx].Type
+	offset := field.Offset
+	field.Offset = 0
+	return field, offset
+}
+
+// checkElement checks that the value v of the composite literal
+// element el can be assigned to the element type t.
+func checkElement(ctx *Codegen, el AST, v ssa.Variable, t types.Info) error {
+	if v.Const && (t.Type == types.Struct || t.Type == types.Array) {
+		if t.Equal(v.Type) {
+			return nil
+		}
+	} else if ssa.LValueFor(t, v) {
+		return nil
+	}
+	return ctx.logger.Errorf(el.Location(),
+		"cannot use %s (type %s) as type %s in composite literal",
+		el, v.Type, t)
+}
+
+// isFractional tests if the type t is a floating point or a
+// fixed-point type.
+func isFractional(t types.Info) bool {
+	return t.Type == types.Float || t.Type == types.Fixed
+}
+
+// fitConst converts the numeric constant v to a constant of the
+// floating point or fixed-point type t. Other values are returned
+// unmodified.
+func fitConst(gen *ssa.Generator, v ssa.Variable, t types.Info) (
+	ssa.Variable, error) {
+
+	if !v.Const || !isFractional(t) || v.Type.Equal(t) {
+		return v, nil
+	}
+	switch v.Type.Type {
+	case types.Int, types.Uint, types.Float, types.Fixed:
+	default:
+		return v, nil
+	}
+	var val interface{}
+	var err error
+	if t.Type == types.Float {
+		val, err = ssa.FloatValue(v.ConstValue, t.Bits)
+	} else {
+		val, err = ssa.FixedValue(v.ConstValue, t)
+	}
+	if err != nil {
+		return v, err
+	}
+	c, err := ssa.Constant(gen, val)
+	if err != nil {
+		return v, err
+	}
+	gen.AddConstant(c)
+	return c, nil
+}
+
+// constFits checks that the integer constant v fits in the integer
+// type t.
+func constFits(ctx *Codegen, loc utils.Point, v ssa.Variable,
+	t types.Info) error {
+
+	if !v.Const {
+		return nil
+	}
+	switch v.Type.Type {
+	case types.Int, types.Uint:
+	default:
+		return nil
+	}
+	switch t.Type {
+	case types.Int, types.Uint:
+	default:
+		return nil
+	}
+	_, _, err := convertConst(v.ConstValue, t)
+	if err != nil {
+		return ctx.logger.Errorf(loc, "%s", err)
+	}
+	return nil
+}
+
 // SSA implements the compiler.ast.AST.SSA for constant values.
 func (ast *Constant) SSA(block *ssa.Block, ctx *Codegen,
 	gen *ssa.Generator) (*ssa.Block, []ssa.Variable, error) {
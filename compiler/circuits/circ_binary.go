@@ -61,3 +61,16 @@ func NewBinaryXOR(compiler *Compiler, x, y, r []*Wire) error {
 	}
 	return nil
 }
+
+// NewBinaryNOT creates a new binary NOT circuit implementing r=^x.
+func NewBinaryNOT(compiler *Compiler, x, r []*Wire) error {
+	if len(x) > len(r) {
+		return fmt.Errorf("invalid binary not arguments: x=%d, r=%d",
+			len(x), len(r))
+	}
+	x = compiler.ZeroExtend(x, len(r))
+	for i := 0; i < len(x); i++ {
+		compiler.INV(x[i], r[i])
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package circuits
+
+import (
+	"fmt"
+)
+
+// NewPopcount creates a circuit that counts the number of one bits
+// in x and returns the count in r.
+func NewPopcount(cc *Compiler, x, r []*Wire) error {
+	if len(x) == 0 {
+		return fmt.Errorf("invalid popcount arguments: x=%d", len(x))
+	}
+	var arr [][]*Wire
+	for i := 0; i < len(x); i++ {
+		arr = append(arr, []*Wire{x[i]})
+	}
+	for len(arr) > 2 {
+		var n [][]*Wire
+		for i := 0; i < len(arr); i += 2 {
+			if i+1 < len(arr) {
+				result := MakeWires(len(arr[i]) + 1)
+				err := NewAdder(cc, arr[i], arr[i+1], result)
+				if err != nil {
+					return err
+				}
+				n = append(n, result)
+			} else {
+				n = append(n, arr[i])
+			}
+		}
+		arr = n
+	}
+	if len(arr) == 1 {
+		return assignWires(cc, arr[0], r)
+	}
+	result := MakeWires(len(arr[0]) + 1)
+	err := NewAdder(cc, arr[0], arr[1], result)
+	if err != nil {
+		return err
+	}
+	return assignWires(cc, result, r)
+}
+
+// NewLeadingZeros creates a circuit that counts the number of
+// leading zero bits in x and returns the count in r.
+func NewLeadingZeros(cc *Compiler, x, r []*Wire) error {
+	if len(x) == 0 {
+		return fmt.Errorf("invalid leading zeros arguments: x=%d", len(x))
+	}
+	lz, err := leadingZeros(cc, x)
+	if err != nil {
+		return err
+	}
+	return assignWires(cc, lz, r)
+}
+
+// NewTrailingZeros creates a circuit that counts the number of
+// trailing zero bits in x and returns the count in r.
+func NewTrailingZeros(cc *Compiler, x, r []*Wire) error {
+	if len(x) == 0 {
+		return fmt.Errorf("invalid trailing zeros arguments: x=%d", len(x))
+	}
+	tz, err := leadingZeros(cc, reverseWires(x))
+	if err != nil {
+		return err
+	}
+	return assignWires(cc, tz, r)
+}
+
+// NewBitReverse creates a circuit that reverses the order of the bits
+// of x.
+func NewBitReverse(cc *Compiler, x, r []*Wire) error {
+	if len(x) != len(r) {
+		return fmt.Errorf("invalid bit reverse arguments: x=%d, r=%d",
+			len(x), len(r))
+	}
+	return assignWires(cc, reverseWires(x), r)
+}
+
+// NewMin creates a circuit that returns the smaller of the unsigned
+// values x and y.
+func NewMin(cc *Compiler, x, y, r []*Wire) error {
+	return minMax(cc, false, false, x, y, r)
+}
+
+// NewMax creates a circuit that returns the larger of the unsigned
+// values x and y.
+func NewMax(cc *Compiler, x, y, r []*Wire) error {
+	return minMax(cc, false, true, x, y, r)
+}
+
+// NewSignedMin creates a circuit that returns the smaller of the
+// signed values x and y.
+func NewSignedMin(cc *Compiler, x, y, r []*Wire) error {
+	return minMax(cc, true, false, x, y, r)
+}
+
+// NewSignedMax creates a circuit that returns the larger of the
+// signed values x and y.
+func NewSignedMax(cc *Compiler, x, y, r []*Wire) error {
+	return minMax(cc, true, true, x, y, r)
+}
+
+// minMax selects the smaller or the larger of x and y to r. The
+// arguments are zero-extended or truncated to the size of r.
+func minMax(cc *Compiler, signed, max bool, x, y, r []*Wire) error {
+	if len(x) == 0 || len(y) == 0 || len(r) == 0 {
+		return fmt.Errorf("invalid min/max arguments: x=%d, y=%d, r=%d",
+			len(x), len(y), len(r))
+	}
+	n := len(r)
+	x = cc.ZeroExtend(x, n)[:n]
+	y = cc.ZeroExtend(y, n)[:n]
+	gt := []*Wire{NewWire()}
+	var err error
+	if signed {
+		err = NewSignedGtComparator(cc, x, y, gt)
+	} else {
+		err = NewGtComparator(cc, x, y, gt)
+	}
+	if err != nil {
+		return err
+	}
+	if max {
+		return NewMUX(cc, gt, x, y, r)
+	}
+	return NewMUX(cc, gt, y, x, r)
+}
+
+// NewAbs creates a circuit that returns the absolute value of the
+// signed value x.
+func NewAbs(cc *Compiler, x, r []*Wire) error {
+	if len(x) == 0 {
+		return fmt.Errorf("invalid abs arguments: x=%d", len(x))
+	}
+	abs, err := absWires(cc, x)
+	if err != nil {
+		return err
+	}
+	return assignWires(cc, abs, r)
+}
+
+// reverseWires returns the wires x in the reverse order.
+func reverseWires(x []*Wire) []*Wire {
+	r := make([]*Wire, len(x))
+	for i := 0; i < len(x); i++ {
+		r[i] = x[len(x)-1-i]
+	}
+	return r
+}
+
+// assignWires assigns the wires x to r. The value x is zero-extended
+// or truncated to the size of r.
+func assignWires(cc *Compiler, x, r []*Wire) error {
+	for i := 0; i < len(r); i++ {
+		if i < len(x) {
+			cc.ID(x[i], r[i])
+		} else {
+			cc.ID(cc.ZeroWire(), r[i])
+		}
+	}
+	return nil
+}
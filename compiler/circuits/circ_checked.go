@@ -0,0 +1,205 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package circuits
+
+import (
+	"fmt"
+)
+
+// NewCheckedAdder creates an adder circuit implementing r=x+y. The
+// overflow wire ov is set if the sum does not fit into r.
+func NewCheckedAdder(cc *Compiler, signed bool, x, y, r []*Wire,
+	ov *Wire) error {
+
+	x, y, err := checkedArgs(cc, x, y, r)
+	if err != nil {
+		return err
+	}
+	n := len(r)
+	sum := MakeWires(n + 1)
+	err = NewAdder(cc, x, y, sum)
+	if err != nil {
+		return err
+	}
+	if signed {
+		// Operands of the same sign and the sum has a different sign.
+		same := notWire(cc, xorWire(cc, x[n-1], y[n-1]))
+		cc.ID(andWire(cc, same, xorWire(cc, sum[n-1], x[n-1])), ov)
+	} else {
+		cc.ID(sum[n], ov)
+	}
+	return assignWires(cc, sum[:n], r)
+}
+
+// NewCheckedSubtractor creates a subtractor circuit implementing
+// r=x-y. The overflow wire ov is set if the difference does not fit
+// into r.
+func NewCheckedSubtractor(cc *Compiler, signed bool, x, y, r []*Wire,
+	ov *Wire) error {
+
+	x, y, err := checkedArgs(cc, x, y, r)
+	if err != nil {
+		return err
+	}
+	n := len(r)
+	diff := MakeWires(n + 1)
+	err = NewSubtractor(cc, x, y, diff)
+	if err != nil {
+		return err
+	}
+	if signed {
+		// Operands of different signs and the difference has a
+		// different sign than x.
+		differ := xorWire(cc, x[n-1], y[n-1])
+		cc.ID(andWire(cc, differ, xorWire(cc, diff[n-1], x[n-1])), ov)
+	} else {
+		// The borrow bit.
+		cc.ID(diff[n], ov)
+	}
+	return assignWires(cc, diff[:n], r)
+}
+
+// NewCheckedMultiplier creates a multiplier circuit implementing
+// r=x*y. The overflow wire ov is set if the product does not fit
+// into r.
+func NewCheckedMultiplier(cc *Compiler, signed bool, x, y, r []*Wire,
+	ov *Wire) error {
+
+	x, y, err := checkedArgs(cc, x, y, r)
+	if err != nil {
+		return err
+	}
+	n := len(r)
+	if !signed {
+		product := MakeWires(2 * n)
+		err = NewMultiplier(cc, cc.Params.CircMultArrayTreshold, x, y,
+			product)
+		if err != nil {
+			return err
+		}
+		cc.ID(orReduce(cc, product[n:]), ov)
+		return assignWires(cc, product[:n], r)
+	}
+
+	// Multiply the magnitudes and negate the result if the operand
+	// signs differ.
+	ax, err := absWires(cc, x)
+	if err != nil {
+		return err
+	}
+	ay, err := absWires(cc, y)
+	if err != nil {
+		return err
+	}
+	product := MakeWires(2 * n)
+	err = NewMultiplier(cc, cc.Params.CircMultArrayTreshold, ax, ay, product)
+	if err != nil {
+		return err
+	}
+	neg := xorWire(cc, x[n-1], y[n-1])
+
+	// Positive products must be smaller than 2^(n-1) and negative
+	// products can be equal to 2^(n-1).
+	high := orReduce(cc, product[n:])
+	top := andWire(cc, product[n-1],
+		orWire(cc, notWire(cc, neg), orReduce(cc, product[:n-1])))
+	cc.ID(orWire(cc, high, top), ov)
+
+	negated := MakeWires(n)
+	err = NewNegator(cc, product[:n], negated)
+	if err != nil {
+		return err
+	}
+	return NewMUX(cc, []*Wire{neg}, negated, product[:n], r)
+}
+
+// NewSaturatingAdder creates an adder circuit implementing r=x+y
+// where the sum is clamped to the range of r.
+func NewSaturatingAdder(cc *Compiler, signed bool, x, y, r []*Wire) error {
+	return saturate(cc, signed, false, x, y, r, NewCheckedAdder,
+		func(x, y []*Wire) *Wire {
+			return x[len(x)-1]
+		})
+}
+
+// NewSaturatingSubtractor creates a subtractor circuit implementing
+// r=x-y where the difference is clamped to the range of r.
+func NewSaturatingSubtractor(cc *Compiler, signed bool, x, y,
+	r []*Wire) error {
+
+	return saturate(cc, signed, true, x, y, r, NewCheckedSubtractor,
+		func(x, y []*Wire) *Wire {
+			return x[len(x)-1]
+		})
+}
+
+// NewSaturatingMultiplier creates a multiplier circuit implementing
+// r=x*y where the product is clamped to the range of r.
+func NewSaturatingMultiplier(cc *Compiler, signed bool, x, y,
+	r []*Wire) error {
+
+	return saturate(cc, signed, false, x, y, r, NewCheckedMultiplier,
+		func(x, y []*Wire) *Wire {
+			return xorWire(cc, x[len(x)-1], y[len(y)-1])
+		})
+}
+
+// saturate computes the checked operation op and selects the minimum
+// or the maximum value of r if the operation overflows. For signed
+// values, the function neg tells if the overflowed result is below
+// the minimum value. Unsigned operations overflow only above the
+// maximum value unless underflow is set.
+func saturate(cc *Compiler, signed, underflow bool, x, y, r []*Wire,
+	op func(cc *Compiler, signed bool, x, y, r []*Wire, ov *Wire) error,
+	neg func(x, y []*Wire) *Wire) error {
+
+	x, y, err := checkedArgs(cc, x, y, r)
+	if err != nil {
+		return err
+	}
+	n := len(r)
+	result := MakeWires(n)
+	ov := NewWire()
+	err = op(cc, signed, x, y, result, ov)
+	if err != nil {
+		return err
+	}
+
+	// The minimum value is 0b10...0 for signed and 0 for unsigned
+	// values. The maximum value is 0b01...1 for signed and 0b11...1
+	// for unsigned values.
+	limit := make([]*Wire, n)
+	if signed {
+		below := neg(x, y)
+		above := notWire(cc, below)
+		for i := 0; i < n-1; i++ {
+			limit[i] = above
+		}
+		limit[n-1] = below
+	} else {
+		bit := cc.OneWire()
+		if underflow {
+			bit = cc.ZeroWire()
+		}
+		for i := 0; i < n; i++ {
+			limit[i] = bit
+		}
+	}
+	return NewMUX(cc, []*Wire{ov}, limit, result, r)
+}
+
+// checkedArgs zero-extends or truncates the arguments x and y to the
+// size of r.
+func checkedArgs(cc *Compiler, x, y, r []*Wire) ([]*Wire, []*Wire, error) {
+	if len(x) == 0 || len(y) == 0 || len(r) == 0 {
+		return nil, nil, fmt.Errorf(
+			"invalid checked arguments: x=%d, y=%d, r=%d",
+			len(x), len(y), len(r))
+	}
+	n := len(r)
+	return cc.ZeroExtend(x, n)[:n], cc.ZeroExtend(y, n)[:n], nil
+}
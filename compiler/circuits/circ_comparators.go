@@ -59,6 +59,48 @@ func NewLeComparator(compiler *Compiler, x, y, r []*Wire) error {
 	return comparator(compiler, compiler.OneWire(), y, x, r)
 }
 
+// signedComparator tests if x>y if cin=0, and x>=y if cin=1 for the
+// two's complement signed values x and y. The comparison inverts the
+// sign bits and compares the values as unsigned.
+func signedComparator(compiler *Compiler, cin *Wire, x, y, r []*Wire) error {
+	x, y = compiler.ZeroPad(x, y)
+	if len(x) == 0 {
+		return fmt.Errorf("invalid signed comparator arguments: x=%d",
+			len(x))
+	}
+	n := len(x)
+	sx := make([]*Wire, n)
+	sy := make([]*Wire, n)
+	copy(sx, x[:n-1])
+	copy(sy, y[:n-1])
+	sx[n-1] = NewWire()
+	compiler.INV(x[n-1], sx[n-1])
+	sy[n-1] = NewWire()
+	compiler.INV(y[n-1], sy[n-1])
+
+	return comparator(compiler, cin, sx, sy, r)
+}
+
+// NewSignedGtComparator tests if x>y for signed values.
+func NewSignedGtComparator(compiler *Compiler, x, y, r []*Wire) error {
+	return signedComparator(compiler, compiler.ZeroWire(), x, y, r)
+}
+
+// NewSignedGeComparator tests if x>=y for signed values.
+func NewSignedGeComparator(compiler *Compiler, x, y, r []*Wire) error {
+	return signedComparator(compiler, compiler.OneWire(), x, y, r)
+}
+
+// NewSignedLtComparator tests if x<y for signed values.
+func NewSignedLtComparator(compiler *Compiler, x, y, r []*Wire) error {
+	return signedComparator(compiler, compiler.ZeroWire(), y, x, r)
+}
+
+// NewSignedLeComparator tests if x<=y for signed values.
+func NewSignedLeComparator(compiler *Compiler, x, y, r []*Wire) error {
+	return signedComparator(compiler, compiler.OneWire(), y, x, r)
+}
+
 // NewNeqComparator tewsts if x!=y.
 func NewNeqComparator(compiler *Compiler, x, y, r []*Wire) error {
 	x, y = compiler.ZeroPad(x, y)
@@ -129,6 +171,17 @@ func NewLogicalOR(compiler *Compiler, x, y, r []*Wire) error {
 	return nil
 }
 
+// NewLogicalNOT implements logical NOT implementing r=!x.  The input
+// and output wires must be 1 bit wide.
+func NewLogicalNOT(compiler *Compiler, x, r []*Wire) error {
+	if len(x) != 1 || len(r) != 1 {
+		return fmt.Errorf("invalid logical not arguments: x=%d, r=%d",
+			len(x), len(r))
+	}
+	compiler.INV(x[0], r[0])
+	return nil
+}
+
 // NewBitSetTest tests if the index'th bit of x is set.
 func NewBitSetTest(compiler *Compiler, x []*Wire, index int, r []*Wire) error {
 	if len(r) != 1 {
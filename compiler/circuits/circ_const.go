@@ -0,0 +1,239 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package circuits
+
+import (
+	"fmt"
+	"math/big"
+)
+
+var bigOne = big.NewInt(1)
+
+// NewConstMultiplier creates a multiplier circuit implementing r=x*c
+// for the constant c. The product is computed with a shift-add
+// network over the canonical signed digit representation of c, which
+// needs one adder or subtractor for each non-zero digit. The product
+// is truncated to the size of r.
+func NewConstMultiplier(cc *Compiler, x []*Wire, c *big.Int, r []*Wire) error {
+	if len(x) == 0 || len(r) == 0 {
+		return fmt.Errorf("invalid constant multiplier arguments: x=%d, r=%d",
+			len(x), len(r))
+	}
+	n := len(r)
+	x = cc.ZeroExtend(x, n)[:n]
+
+	// The negative constants are in two's complement form modulo 2^n.
+	mod := new(big.Int).Lsh(bigOne, uint(n))
+	digits := csd(new(big.Int).Mod(c, mod))
+
+	// Add the positive digits first so that the accumulator must be
+	// negated only if all digits are negative.
+	var acc []*Wire
+	for _, sign := range []int{1, -1} {
+		for shift, digit := range digits {
+			if digit != sign || shift >= n {
+				continue
+			}
+			var err error
+			acc, err = addShifted(cc, acc, x, shift, sign < 0)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return assignWires(cc, acc, r)
+}
+
+// addShifted adds or subtracts x<<shift to the accumulator acc and
+// returns the result. The nil accumulator has the value 0. The shift
+// leaves the low bits of the accumulator unchanged so only the high
+// bits need adders.
+func addShifted(cc *Compiler, acc, x []*Wire, shift int, sub bool) (
+	[]*Wire, error) {
+
+	n := len(x)
+	result := make([]*Wire, n)
+	term := x[:n-shift]
+	high := result[shift:]
+
+	if acc == nil {
+		for i := 0; i < shift; i++ {
+			result[i] = cc.ZeroWire()
+		}
+		if !sub {
+			copy(high, term)
+			return result, nil
+		}
+		sum := MakeWires(len(term))
+		err := NewNegator(cc, term, sum)
+		if err != nil {
+			return nil, err
+		}
+		copy(high, sum)
+		return result, nil
+	}
+
+	copy(result, acc[:shift])
+	sum := MakeWires(len(term))
+	var err error
+	if sub {
+		err = NewSubtractor(cc, acc[shift:], term, sum)
+	} else {
+		err = NewAdder(cc, acc[shift:], term, sum)
+	}
+	if err != nil {
+		return nil, err
+	}
+	copy(high, sum)
+	return result, nil
+}
+
+// csd returns the canonical signed digit representation of the
+// non-negative value c, starting from the least significant digit.
+// The digits are -1, 0, or 1, and no two adjacent digits are
+// non-zero.
+func csd(c *big.Int) []int {
+	var digits []int
+	v := new(big.Int).Set(c)
+	for v.Sign() > 0 {
+		var digit int
+		if v.Bit(0) == 1 {
+			if v.Bit(1) == 1 {
+				digit = -1
+				v.Add(v, bigOne)
+			} else {
+				digit = 1
+				v.Sub(v, bigOne)
+			}
+		}
+		digits = append(digits, digit)
+		v.Rsh(v, 1)
+	}
+	return digits
+}
+
+// NewConstDivider creates a division circuit computing q=x/d and
+// r=x%d for the unsigned value x and the positive constant d. Either
+// q or r can be nil. The quotient is computed by multiplying x with
+// the reciprocal of d, and the remainder is computed as r=x-q*d.
+func NewConstDivider(cc *Compiler, x []*Wire, d *big.Int, q, r []*Wire) error {
+	if len(x) == 0 {
+		return fmt.Errorf("invalid constant divider arguments: x=%d", len(x))
+	}
+	if d.Sign() <= 0 {
+		return fmt.Errorf("invalid constant divisor %v", d)
+	}
+	n := len(x)
+
+	// Divide by the power of two factor of d with a shift.
+	var shift int
+	for d.Bit(shift) == 0 {
+		shift++
+	}
+	odd := new(big.Int).Rsh(d, uint(shift))
+
+	var quotient, rem []*Wire
+	if shift >= n || odd.BitLen() > n-shift {
+		// The divisor is larger than all values of x.
+		rem = x
+	} else if odd.Cmp(bigOne) == 0 {
+		quotient = x[shift:]
+		rem = x[:shift]
+	} else {
+		// With l=ceil(log2(odd)) and m=ceil(2^(bits+l)/odd), the
+		// quotient floor(x*m/2^(bits+l)) is exact for all values of
+		// x that fit in bits.
+		hi := x[shift:]
+		bits := len(hi)
+		l := odd.BitLen()
+		m := new(big.Int).Lsh(bigOne, uint(bits+l))
+		m.Add(m, odd)
+		m.Sub(m, bigOne)
+		m.Div(m, odd)
+
+		product := MakeWires(bits + m.BitLen())
+		err := NewConstMultiplier(cc, hi, m, product)
+		if err != nil {
+			return err
+		}
+		quotient = product[bits+l:]
+
+		if r != nil {
+			// The remainder is smaller than d so it is enough to
+			// compute the low bits of x-q*d.
+			size := d.BitLen()
+			if size > n {
+				size = n
+			}
+			qd := MakeWires(size)
+			err = NewConstMultiplier(cc, quotient, d, qd)
+			if err != nil {
+				return err
+			}
+			rem, err = subWires(cc, x[:size], qd, size)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if q != nil {
+		err := assignWires(cc, quotient, q)
+		if err != nil {
+			return err
+		}
+	}
+	if r != nil {
+		return assignWires(cc, rem, r)
+	}
+	return nil
+}
+
+// NewSignedConstDivider creates a division circuit computing q=x/d
+// and r=x%d for the two's complement signed value x and the non-zero
+// constant d. The quotient is truncated towards zero and the
+// remainder has the sign of x. Either q or r can be nil.
+func NewSignedConstDivider(cc *Compiler, x []*Wire, d *big.Int,
+	q, r []*Wire) error {
+
+	if len(x) == 0 {
+		return fmt.Errorf("invalid constant divider arguments: x=%d", len(x))
+	}
+	if d.Sign() == 0 {
+		return fmt.Errorf("invalid constant divisor %v", d)
+	}
+	n := len(x)
+
+	absX, err := absWires(cc, x)
+	if err != nil {
+		return err
+	}
+	var uq, ur []*Wire
+	if q != nil {
+		uq = MakeWires(n)
+	}
+	if r != nil {
+		ur = MakeWires(n)
+	}
+	err = NewConstDivider(cc, absX, new(big.Int).Abs(d), uq, ur)
+	if err != nil {
+		return err
+	}
+	if q != nil {
+		neg := x[n-1]
+		if d.Sign() < 0 {
+			neg = notWire(cc, neg)
+		}
+		err = negateIf(cc, neg, uq, q)
+		if err != nil {
+			return err
+		}
+	}
+	if r != nil {
+		return negateIf(cc, x[n-1], ur, r)
+	}
+	return nil
+}
@@ -84,3 +84,50 @@ func NewDivider(compiler *Compiler, a, b, q, r []*Wire) error {
 
 	return nil
 }
+
+// NewSignedDivider creates a division circuit for the two's
+// complement signed values a and b, computing the quotient q and the
+// remainder r. The quotient is truncated towards zero and the
+// remainder has the sign of a. Either q or r can be nil.
+func NewSignedDivider(compiler *Compiler, a, b, q, r []*Wire) error {
+	a, b = compiler.ZeroPad(a, b)
+	n := len(a)
+
+	absA, err := absWires(compiler, a)
+	if err != nil {
+		return err
+	}
+	absB, err := absWires(compiler, b)
+	if err != nil {
+		return err
+	}
+	uq := MakeWires(n)
+	ur := MakeWires(n)
+	err = NewDivider(compiler, absA, absB, uq, ur)
+	if err != nil {
+		return err
+	}
+	if q != nil {
+		err = negateIf(compiler, xorWire(compiler, a[n-1], b[n-1]), uq, q)
+		if err != nil {
+			return err
+		}
+	}
+	if r != nil {
+		err = negateIf(compiler, a[n-1], ur, r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// negateIf sets r to -x if neg is set and to x otherwise.
+func negateIf(compiler *Compiler, neg *Wire, x, r []*Wire) error {
+	nx := MakeWires(len(x))
+	err := NewNegator(compiler, x, nx)
+	if err != nil {
+		return err
+	}
+	return NewMUX(compiler, []*Wire{neg}, nx, x, r)
+}
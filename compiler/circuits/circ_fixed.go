@@ -0,0 +1,165 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package circuits
+
+import (
+	"fmt"
+)
+
+// Fixed-point values are two's complement integers scaled by
+// 2^-frac. Addition, subtraction, negation, and comparison use the
+// signed integer circuits; this file implements the operations that
+// must rescale their results.
+
+// NewFixedMultiplier creates a circuit implementing the fixed-point
+// multiplication r=x*y where the arguments have frac fractional
+// bits. The full product is rounded to the nearest value, ties away
+// from zero, and overflows wrap around.
+func NewFixedMultiplier(cc *Compiler, arrayTreshold, frac int,
+	x, y, r []*Wire) error {
+
+	n := len(r)
+	if n == 0 || frac < 0 || frac >= n {
+		return fmt.Errorf("invalid fixed multiplier arguments: r=%d, frac=%d",
+			n, frac)
+	}
+	x = signExtend(x, n)
+	y = signExtend(y, n)
+
+	// Unsigned product with the signed correction of the high half:
+	// x*y = ux*uy - 2^n*(xs*uy + ys*ux).
+	p := MakeWires(2 * n)
+	err := NewMultiplier(cc, arrayTreshold, x, y, p)
+	if err != nil {
+		return err
+	}
+	cx := make([]*Wire, n)
+	cy := make([]*Wire, n)
+	for i := 0; i < n; i++ {
+		cx[i] = andWire(cc, x[n-1], y[i])
+		cy[i] = andWire(cc, y[n-1], x[i])
+	}
+	hi, err := subWires(cc, p[n:], cx, n)
+	if err != nil {
+		return err
+	}
+	hi, err = subWires(cc, hi, cy, n)
+	if err != nil {
+		return err
+	}
+	prod := make([]*Wire, 0, 2*n)
+	prod = append(prod, p[:n]...)
+	prod = append(prod, hi...)
+
+	if frac == 0 {
+		for i := 0; i < n; i++ {
+			cc.ID(prod[i], r[i])
+		}
+		return nil
+	}
+
+	// Add 2^(frac-1) to positive and 2^(frac-1)-1 to negative
+	// products and drop the fraction. This rounds the ties away from
+	// zero.
+	neg := prod[2*n-1]
+	round := make([]*Wire, frac)
+	for i := 0; i < frac-1; i++ {
+		round[i] = neg
+	}
+	round[frac-1] = notWire(cc, neg)
+	sum, err := addWires(cc, prod[:frac+n], round, frac+n)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		cc.ID(sum[frac+i], r[i])
+	}
+	return nil
+}
+
+// NewFixedDivider creates a circuit implementing the fixed-point
+// division r=x/y where the arguments have frac fractional bits. The
+// quotient is rounded to the nearest value, ties away from zero. The
+// result is undefined if y is zero.
+func NewFixedDivider(cc *Compiler, frac int, x, y, r []*Wire) error {
+	n := len(r)
+	if n == 0 || frac < 0 || frac >= n {
+		return fmt.Errorf("invalid fixed divider arguments: r=%d, frac=%d",
+			n, frac)
+	}
+	x = signExtend(x, n)
+	y = signExtend(y, n)
+
+	ax, err := absWires(cc, x)
+	if err != nil {
+		return err
+	}
+	ay, err := absWires(cc, y)
+	if err != nil {
+		return err
+	}
+
+	// Compute 2*|x|*2^frac/|y| and round with the extra quotient bit.
+	w := n + frac + 1
+	q := MakeWires(w)
+	err = NewDivider(cc, cc.ShiftLeft(ax, w, frac+1), ay, q, nil)
+	if err != nil {
+		return err
+	}
+	q, err = addWires(cc, q, constWires(cc, 1, w), w)
+	if err != nil {
+		return err
+	}
+	return negateIf(cc, xorWire(cc, x[n-1], y[n-1]), q[1:n+1], r)
+}
+
+// NewFixedConverter creates a circuit converting the value x with
+// from fractional bits to the value r with to fractional bits. The
+// signed argument specifies if x is sign or zero extended. Dropped
+// fractional bits are truncated towards negative infinity and
+// overflows wrap around. Integers are fixed-point values with zero
+// fractional bits.
+func NewFixedConverter(cc *Compiler, signed bool, from, to int,
+	x, r []*Wire) error {
+
+	if len(x) == 0 || from < 0 || to < 0 {
+		return fmt.Errorf(
+			"invalid fixed converter arguments: x=%d, from=%d, to=%d",
+			len(x), from, to)
+	}
+	shift := to - from
+	for i := 0; i < len(r); i++ {
+		j := i - shift
+		var w *Wire
+		if j < 0 {
+			w = cc.ZeroWire()
+		} else if j < len(x) {
+			w = x[j]
+		} else if signed {
+			w = x[len(x)-1]
+		} else {
+			w = cc.ZeroWire()
+		}
+		cc.ID(w, r[i])
+	}
+	return nil
+}
+
+// absWires returns the absolute value of the signed value x.
+func absWires(cc *Compiler, x []*Wire) ([]*Wire, error) {
+	neg := MakeWires(len(x))
+	err := NewNegator(cc, x, neg)
+	if err != nil {
+		return nil, err
+	}
+	r := MakeWires(len(x))
+	err = NewMUX(cc, []*Wire{x[len(x)-1]}, neg, x, r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
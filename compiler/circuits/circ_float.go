@@ -0,0 +1,902 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package circuits
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/markkurossi/mpc/circuit"
+)
+
+// floatFormat defines an IEEE-754 binary floating point format.
+type floatFormat struct {
+	bits int
+	exp  int
+	frac int
+}
+
+func newFloatFormat(size int) (floatFormat, error) {
+	switch size {
+	case 32:
+		return floatFormat{bits: 32, exp: 8, frac: 23}, nil
+	case 64:
+		return floatFormat{bits: 64, exp: 11, frac: 52}, nil
+	default:
+		return floatFormat{}, fmt.Errorf("unsupported float size %d", size)
+	}
+}
+
+func (f floatFormat) bias() int64 {
+	return 1<<(f.exp-1) - 1
+}
+
+// ebits returns the size of the signed exponent values used in the
+// intermediate computations.
+func (f floatFormat) ebits() int {
+	return f.exp + 3
+}
+
+// floatValue holds an unpacked floating point value.
+type floatValue struct {
+	sign *Wire
+	// exp is the signed biased exponent.
+	exp []*Wire
+	// sig is the significand with the hidden bit.
+	sig  []*Wire
+	nan  *Wire
+	inf  *Wire
+	zero *Wire
+}
+
+// unpackFloat unpacks the floating point value x. Subnormal values
+// are normalized if normalize is true.
+func unpackFloat(cc *Compiler, f floatFormat, x []*Wire, normalize bool) (
+	*floatValue, error) {
+
+	frac := x[:f.frac]
+	exp := x[f.frac : f.frac+f.exp]
+
+	expOnes := andReduce(cc, exp)
+	expZero := notWire(cc, orReduce(cc, exp))
+	fracZero := notWire(cc, orReduce(cc, frac))
+
+	v := &floatValue{
+		sign: x[f.bits-1],
+		nan:  andWire(cc, expOnes, notWire(cc, fracZero)),
+		inf:  andWire(cc, expOnes, fracZero),
+		zero: andWire(cc, expZero, fracZero),
+	}
+
+	// Subnormal values have exponent 1 and no hidden bit.
+	v.exp = make([]*Wire, f.ebits())
+	v.exp[0] = orWire(cc, exp[0], expZero)
+	for i := 1; i < len(v.exp); i++ {
+		if i < f.exp {
+			v.exp[i] = exp[i]
+		} else {
+			v.exp[i] = cc.ZeroWire()
+		}
+	}
+	v.sig = make([]*Wire, f.frac+1)
+	copy(v.sig, frac)
+	v.sig[f.frac] = notWire(cc, expZero)
+
+	if normalize {
+		lz, err := leadingZeros(cc, v.sig)
+		if err != nil {
+			return nil, err
+		}
+		v.sig, err = shiftWiresLeft(cc, v.sig, lz)
+		if err != nil {
+			return nil, err
+		}
+		v.exp, err = subWires(cc, v.exp, lz, len(v.exp))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// roundFloat rounds the significand sig to the nearest even value and
+// packs the sign, exponent, and fraction into r. The most significant
+// bit of sig must be set and exp is its signed biased exponent. Tiny
+// results are denormalized and too large results overflow to
+// infinity.
+func roundFloat(cc *Compiler, f floatFormat, sign *Wire, exp, sig,
+	r []*Wire) error {
+
+	// Reduce the significand to the hidden bit, fraction, guard bit,
+	// and sticky bit.
+	n := f.frac + 3
+	m := make([]*Wire, n)
+	if len(sig) >= n {
+		d := len(sig) - n
+		m[0] = orReduce(cc, sig[:d+1])
+		copy(m[1:], sig[d+1:])
+	} else {
+		d := n - len(sig)
+		for i := 0; i < d; i++ {
+			m[i] = cc.ZeroWire()
+		}
+		copy(m[d:], sig)
+	}
+
+	w := len(exp)
+	tiny := orWire(cc, exp[w-1], notWire(cc, orReduce(cc, exp)))
+	count, err := subWires(cc, constWires(cc, 1, w), exp, w)
+	if err != nil {
+		return err
+	}
+	denorm, err := shiftRightSticky(cc, m, count)
+	if err != nil {
+		return err
+	}
+	rm := MakeWires(n)
+	err = NewMUX(cc, []*Wire{tiny}, denorm, m, rm)
+	if err != nil {
+		return err
+	}
+
+	// Pack the exponent and fraction and round. The rounding carry
+	// propagates from the fraction to the exponent.
+	packed := make([]*Wire, f.exp+f.frac)
+	copy(packed, rm[2:f.frac+2])
+	notTiny := notWire(cc, tiny)
+	for i := 0; i < f.exp; i++ {
+		packed[f.frac+i] = andWire(cc, exp[i], notTiny)
+	}
+	roundUp := andWire(cc, rm[1], orWire(cc, rm[0], rm[2]))
+	rounded, err := addWires(cc, packed, []*Wire{roundUp}, len(packed))
+	if err != nil {
+		return err
+	}
+
+	ge := NewWire()
+	err = NewGeComparator(cc, exp, constWires(cc, 1<<f.exp-1, w),
+		[]*Wire{ge})
+	if err != nil {
+		return err
+	}
+	overflow := andWire(cc, notWire(cc, exp[w-1]), ge)
+	inf := constWires(cc, (1<<f.exp-1)<<f.frac, f.exp+f.frac)
+	err = NewMUX(cc, []*Wire{overflow}, inf, rounded, r[:f.bits-1])
+	if err != nil {
+		return err
+	}
+	cc.ID(sign, r[f.bits-1])
+	return nil
+}
+
+// selectFloat sets r to NaN, infinity, or zero if the corresponding
+// condition is set and to value otherwise. Nil conditions are never
+// set.
+func selectFloat(cc *Compiler, f floatFormat, nan, inf, infSign,
+	zero, zeroSign *Wire, value, r []*Wire) error {
+
+	type special struct {
+		cond  *Wire
+		value []*Wire
+	}
+	specials := []special{
+		{
+			cond:  zero,
+			value: append(constWires(cc, 0, f.bits-1), zeroSign),
+		},
+		{
+			cond: inf,
+			value: append(constWires(cc, (1<<f.exp-1)<<f.frac, f.bits-1),
+				infSign),
+		},
+		{
+			cond:  nan,
+			value: constWires(cc, (1<<(f.exp+1)-1)<<(f.frac-1), f.bits),
+		},
+	}
+	var last int
+	for idx, s := range specials {
+		if s.cond != nil {
+			last = idx
+		}
+	}
+	for idx, s := range specials {
+		if s.cond == nil {
+			continue
+		}
+		var o []*Wire
+		if idx == last {
+			o = r
+		} else {
+			o = MakeWires(f.bits)
+		}
+		err := NewMUX(cc, []*Wire{s.cond}, s.value, value, o)
+		if err != nil {
+			return err
+		}
+		value = o
+	}
+	if value[0] != r[0] {
+		for i := 0; i < f.bits; i++ {
+			cc.ID(value[i], r[i])
+		}
+	}
+	return nil
+}
+
+func floatArgs(x, y, r []*Wire) (floatFormat, error) {
+	if len(x) != len(y) || len(x) != len(r) {
+		return floatFormat{}, fmt.Errorf(
+			"invalid float arguments: x=%d, y=%d, r=%d",
+			len(x), len(y), len(r))
+	}
+	return newFloatFormat(len(r))
+}
+
+// NewFloatAdder creates a floating point adder circuit implementing
+// r=x+y.
+func NewFloatAdder(cc *Compiler, x, y, r []*Wire) error {
+	return newFloatAdder(cc, x, y, r)
+}
+
+// NewFloatSubtractor creates a floating point subtractor circuit
+// implementing r=x-y.
+func NewFloatSubtractor(cc *Compiler, x, y, r []*Wire) error {
+	if len(y) == 0 {
+		return fmt.Errorf("invalid float subtractor arguments: y=%d", len(y))
+	}
+	neg := make([]*Wire, len(y))
+	copy(neg, y)
+	neg[len(y)-1] = notWire(cc, y[len(y)-1])
+	return newFloatAdder(cc, x, neg, r)
+}
+
+func newFloatAdder(cc *Compiler, x, y, r []*Wire) error {
+	f, err := floatArgs(x, y, r)
+	if err != nil {
+		return err
+	}
+
+	// Order the operands so that |a| >= |b|.
+	swap := NewWire()
+	err = NewLtComparator(cc, x[:f.bits-1], y[:f.bits-1], []*Wire{swap})
+	if err != nil {
+		return err
+	}
+	a := MakeWires(f.bits)
+	err = NewMUX(cc, []*Wire{swap}, y, x, a)
+	if err != nil {
+		return err
+	}
+	b := MakeWires(f.bits)
+	err = NewMUX(cc, []*Wire{swap}, x, y, b)
+	if err != nil {
+		return err
+	}
+	va, err := unpackFloat(cc, f, a, false)
+	if err != nil {
+		return err
+	}
+	vb, err := unpackFloat(cc, f, b, false)
+	if err != nil {
+		return err
+	}
+
+	// Align the significands with 3 extra bits for rounding.
+	d, err := subWires(cc, va.exp, vb.exp, len(va.exp))
+	if err != nil {
+		return err
+	}
+	am := append(constWires(cc, 0, 3), va.sig...)
+	bm := append(constWires(cc, 0, 3), vb.sig...)
+	bm, err = shiftRightSticky(cc, bm, d)
+	if err != nil {
+		return err
+	}
+
+	n := len(am) + 1
+	sum := MakeWires(n)
+	err = NewAdder(cc, am, bm, sum)
+	if err != nil {
+		return err
+	}
+	diff := MakeWires(len(am))
+	err = NewSubtractor(cc, am, bm, diff)
+	if err != nil {
+		return err
+	}
+	subtract := xorWire(cc, va.sign, vb.sign)
+	s := MakeWires(n)
+	err = NewMUX(cc, []*Wire{subtract}, diff, sum, s)
+	if err != nil {
+		return err
+	}
+
+	// Normalize the result.
+	lz, err := leadingZeros(cc, s)
+	if err != nil {
+		return err
+	}
+	norm, err := shiftWiresLeft(cc, s, lz)
+	if err != nil {
+		return err
+	}
+	exp, err := addWires(cc, va.exp, constWires(cc, 1, len(va.exp)),
+		len(va.exp))
+	if err != nil {
+		return err
+	}
+	exp, err = subWires(cc, exp, lz, len(exp))
+	if err != nil {
+		return err
+	}
+	value := MakeWires(f.bits)
+	err = roundFloat(cc, f, va.sign, exp, norm, value)
+	if err != nil {
+		return err
+	}
+
+	nan := orWire(cc, orWire(cc, va.nan, vb.nan),
+		andWire(cc, andWire(cc, va.inf, vb.inf), subtract))
+	zero := notWire(cc, orReduce(cc, s))
+
+	return selectFloat(cc, f, nan, va.inf, va.sign,
+		zero, andWire(cc, va.sign, vb.sign), value, r)
+}
+
+// NewFloatMultiplier creates a floating point multiplier circuit
+// implementing r=x*y.
+func NewFloatMultiplier(cc *Compiler, x, y, r []*Wire) error {
+	f, err := floatArgs(x, y, r)
+	if err != nil {
+		return err
+	}
+	va, err := unpackFloat(cc, f, x, true)
+	if err != nil {
+		return err
+	}
+	vb, err := unpackFloat(cc, f, y, true)
+	if err != nil {
+		return err
+	}
+
+	p := MakeWires(len(va.sig) + len(vb.sig))
+	err = NewMultiplier(cc, cc.Params.CircMultArrayTreshold, va.sig, vb.sig,
+		p)
+	if err != nil {
+		return err
+	}
+	top := p[len(p)-1]
+	norm := MakeWires(len(p))
+	err = NewMUX(cc, []*Wire{top}, p, cc.ShiftLeft(p, len(p), 1), norm)
+	if err != nil {
+		return err
+	}
+
+	exp, err := addWires(cc, va.exp, vb.exp, len(va.exp))
+	if err != nil {
+		return err
+	}
+	exp, err = subWires(cc, exp, constWires(cc, f.bias(), len(exp)),
+		len(exp))
+	if err != nil {
+		return err
+	}
+	exp, err = addWires(cc, exp, []*Wire{top}, len(exp))
+	if err != nil {
+		return err
+	}
+
+	sign := xorWire(cc, va.sign, vb.sign)
+	value := MakeWires(f.bits)
+	err = roundFloat(cc, f, sign, exp, norm, value)
+	if err != nil {
+		return err
+	}
+
+	nan := orWire(cc, orWire(cc, va.nan, vb.nan),
+		orWire(cc, andWire(cc, va.inf, vb.zero),
+			andWire(cc, va.zero, vb.inf)))
+
+	return selectFloat(cc, f, nan, orWire(cc, va.inf, vb.inf), sign,
+		orWire(cc, va.zero, vb.zero), sign, value, r)
+}
+
+// NewFloatDivider creates a floating point division circuit
+// implementing r=x/y.
+func NewFloatDivider(cc *Compiler, x, y, r []*Wire) error {
+	f, err := floatArgs(x, y, r)
+	if err != nil {
+		return err
+	}
+	va, err := unpackFloat(cc, f, x, true)
+	if err != nil {
+		return err
+	}
+	vb, err := unpackFloat(cc, f, y, true)
+	if err != nil {
+		return err
+	}
+
+	// The quotient of the significands has f.frac+3 bits after the
+	// binary point and its most significant bit is f.frac+3 or
+	// f.frac+2. The remainder sets the sticky bit.
+	dividend := append(constWires(cc, 0, f.frac+3), va.sig...)
+	q := MakeWires(f.frac + 4)
+	rem := MakeWires(len(vb.sig))
+	err = NewDivider(cc, dividend, vb.sig, q, rem)
+	if err != nil {
+		return err
+	}
+	qs := append([]*Wire{orReduce(cc, rem)}, q...)
+	top := q[len(q)-1]
+	norm := MakeWires(len(qs))
+	err = NewMUX(cc, []*Wire{top}, qs, cc.ShiftLeft(qs, len(qs), 1), norm)
+	if err != nil {
+		return err
+	}
+
+	exp, err := subWires(cc, va.exp, vb.exp, len(va.exp))
+	if err != nil {
+		return err
+	}
+	exp, err = addWires(cc, exp, constWires(cc, f.bias()-1, len(exp)),
+		len(exp))
+	if err != nil {
+		return err
+	}
+	exp, err = addWires(cc, exp, []*Wire{top}, len(exp))
+	if err != nil {
+		return err
+	}
+
+	sign := xorWire(cc, va.sign, vb.sign)
+	value := MakeWires(f.bits)
+	err = roundFloat(cc, f, sign, exp, norm, value)
+	if err != nil {
+		return err
+	}
+
+	nan := orWire(cc, orWire(cc, va.nan, vb.nan),
+		orWire(cc, andWire(cc, va.zero, vb.zero),
+			andWire(cc, va.inf, vb.inf)))
+
+	return selectFloat(cc, f, nan, orWire(cc, va.inf, vb.zero), sign,
+		orWire(cc, va.zero, vb.inf), sign, value, r)
+}
+
+// NewFloatNegator creates a floating point negation circuit
+// implementing r=-x.
+func NewFloatNegator(cc *Compiler, x, r []*Wire) error {
+	if len(x) != len(r) || len(r) == 0 {
+		return fmt.Errorf("invalid float negator arguments: x=%d, r=%d",
+			len(x), len(r))
+	}
+	for i := 0; i < len(r)-1; i++ {
+		cc.ID(x[i], r[i])
+	}
+	cc.INV(x[len(x)-1], r[len(r)-1])
+	return nil
+}
+
+// floatComparator returns wires testing if x<y and x==y. NaN values
+// are unordered and the zero values are equal.
+func floatComparator(cc *Compiler, x, y []*Wire) (lt, eq *Wire, err error) {
+	f, err := floatArgs(x, y, x)
+	if err != nil {
+		return nil, nil, err
+	}
+	magX := x[:f.bits-1]
+	magY := y[:f.bits-1]
+	sx := x[f.bits-1]
+	sy := y[f.bits-1]
+
+	ordered := notWire(cc, orWire(cc, isNaN(cc, f, x), isNaN(cc, f, y)))
+	bothZero := notWire(cc, orWire(cc, orReduce(cc, magX),
+		orReduce(cc, magY)))
+
+	magLt := NewWire()
+	err = NewLtComparator(cc, magX, magY, []*Wire{magLt})
+	if err != nil {
+		return nil, nil, err
+	}
+	magGt := NewWire()
+	err = NewGtComparator(cc, magX, magY, []*Wire{magGt})
+	if err != nil {
+		return nil, nil, err
+	}
+	same := NewWire()
+	err = NewEqComparator(cc, x, y, []*Wire{same})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// x<y if x is negative and y positive, both are positive and
+	// |x|<|y|, or both are negative and |x|>|y|.
+	notSx := notWire(cc, sx)
+	notSy := notWire(cc, sy)
+	less := orWire(cc, andWire(cc, sx, notSy),
+		orWire(cc, andWire(cc, andWire(cc, notSx, notSy), magLt),
+			andWire(cc, andWire(cc, sx, sy), magGt)))
+
+	lt = andWire(cc, andWire(cc, ordered, notWire(cc, bothZero)), less)
+	eq = andWire(cc, ordered, orWire(cc, same, bothZero))
+	return lt, eq, nil
+}
+
+func floatResult(r []*Wire) error {
+	if len(r) != 1 {
+		return fmt.Errorf("invalid float comparator arguments: r=%d", len(r))
+	}
+	return nil
+}
+
+// NewFloatLtComparator tests if x<y.
+func NewFloatLtComparator(cc *Compiler, x, y, r []*Wire) error {
+	if err := floatResult(r); err != nil {
+		return err
+	}
+	lt, _, err := floatComparator(cc, x, y)
+	if err != nil {
+		return err
+	}
+	cc.ID(lt, r[0])
+	return nil
+}
+
+// NewFloatLeComparator tests if x<=y.
+func NewFloatLeComparator(cc *Compiler, x, y, r []*Wire) error {
+	if err := floatResult(r); err != nil {
+		return err
+	}
+	lt, eq, err := floatComparator(cc, x, y)
+	if err != nil {
+		return err
+	}
+	cc.AddGate(NewBinary(circuit.OR, lt, eq, r[0]))
+	return nil
+}
+
+// NewFloatGtComparator tests if x>y.
+func NewFloatGtComparator(cc *Compiler, x, y, r []*Wire) error {
+	return NewFloatLtComparator(cc, y, x, r)
+}
+
+// NewFloatGeComparator tests if x>=y.
+func NewFloatGeComparator(cc *Compiler, x, y, r []*Wire) error {
+	return NewFloatLeComparator(cc, y, x, r)
+}
+
+// NewFloatEqComparator tests if x==y.
+func NewFloatEqComparator(cc *Compiler, x, y, r []*Wire) error {
+	if err := floatResult(r); err != nil {
+		return err
+	}
+	_, eq, err := floatComparator(cc, x, y)
+	if err != nil {
+		return err
+	}
+	cc.ID(eq, r[0])
+	return nil
+}
+
+// NewFloatNeqComparator tests if x!=y.
+func NewFloatNeqComparator(cc *Compiler, x, y, r []*Wire) error {
+	if err := floatResult(r); err != nil {
+		return err
+	}
+	_, eq, err := floatComparator(cc, x, y)
+	if err != nil {
+		return err
+	}
+	cc.INV(eq, r[0])
+	return nil
+}
+
+// NewIntToFloat creates a circuit converting the signed integer x to
+// the floating point value r.
+func NewIntToFloat(cc *Compiler, x, r []*Wire) error {
+	if len(x) == 0 {
+		return fmt.Errorf("invalid int to float arguments: x=%d", len(x))
+	}
+	sign := x[len(x)-1]
+	neg := MakeWires(len(x))
+	err := NewNegator(cc, x, neg)
+	if err != nil {
+		return err
+	}
+	mag := MakeWires(len(x))
+	err = NewMUX(cc, []*Wire{sign}, neg, x, mag)
+	if err != nil {
+		return err
+	}
+	return intToFloat(cc, sign, mag, r)
+}
+
+// NewUintToFloat creates a circuit converting the unsigned integer x
+// to the floating point value r.
+func NewUintToFloat(cc *Compiler, x, r []*Wire) error {
+	if len(x) == 0 {
+		return fmt.Errorf("invalid uint to float arguments: x=%d", len(x))
+	}
+	return intToFloat(cc, cc.ZeroWire(), x, r)
+}
+
+func intToFloat(cc *Compiler, sign *Wire, mag, r []*Wire) error {
+	f, err := newFloatFormat(len(r))
+	if err != nil {
+		return err
+	}
+	lz, err := leadingZeros(cc, mag)
+	if err != nil {
+		return err
+	}
+	norm, err := shiftWiresLeft(cc, mag, lz)
+	if err != nil {
+		return err
+	}
+	w := f.ebits() + bits.Len(uint(len(mag)))
+	exp, err := subWires(cc, constWires(cc, f.bias()+int64(len(mag)-1), w),
+		lz, w)
+	if err != nil {
+		return err
+	}
+	value := MakeWires(f.bits)
+	err = roundFloat(cc, f, sign, exp, norm, value)
+	if err != nil {
+		return err
+	}
+	zero := notWire(cc, orReduce(cc, mag))
+	return selectFloat(cc, f, nil, nil, nil, zero, cc.ZeroWire(), value, r)
+}
+
+// NewFloatToInt creates a circuit converting the floating point
+// value x to the integer r. The fraction is truncated towards
+// zero. The result is undefined if the value does not fit into r.
+func NewFloatToInt(cc *Compiler, x, r []*Wire) error {
+	f, err := newFloatFormat(len(x))
+	if err != nil {
+		return err
+	}
+	if len(r) == 0 {
+		return fmt.Errorf("invalid float to int arguments: r=%d", len(r))
+	}
+	v, err := unpackFloat(cc, f, x, false)
+	if err != nil {
+		return err
+	}
+
+	// The value is sig*2^(exp-bias-frac).
+	w := len(v.exp)
+	base := constWires(cc, f.bias()+int64(f.frac), w)
+	sl, err := subWires(cc, v.exp, base, w)
+	if err != nil {
+		return err
+	}
+	sr, err := subWires(cc, base, v.exp, w)
+	if err != nil {
+		return err
+	}
+	left := MakeWires(len(r))
+	err = NewShiftLeft(cc, v.sig, sl, left)
+	if err != nil {
+		return err
+	}
+	right := MakeWires(len(v.sig))
+	err = NewShiftRight(cc, v.sig, sr, right)
+	if err != nil {
+		return err
+	}
+	if len(right) > len(r) {
+		right = right[:len(r)]
+	}
+	mag := MakeWires(len(r))
+	err = NewMUX(cc, []*Wire{sl[w-1]}, right, left, mag)
+	if err != nil {
+		return err
+	}
+	neg := MakeWires(len(r))
+	err = NewNegator(cc, mag, neg)
+	if err != nil {
+		return err
+	}
+	return NewMUX(cc, []*Wire{v.sign}, neg, mag, r)
+}
+
+// NewFloatToFloat creates a circuit converting the floating point
+// value x to the floating point value r of a different size.
+func NewFloatToFloat(cc *Compiler, x, r []*Wire) error {
+	fx, err := newFloatFormat(len(x))
+	if err != nil {
+		return err
+	}
+	fr, err := newFloatFormat(len(r))
+	if err != nil {
+		return err
+	}
+	if fx == fr {
+		for i := 0; i < len(r); i++ {
+			cc.ID(x[i], r[i])
+		}
+		return nil
+	}
+	v, err := unpackFloat(cc, fx, x, true)
+	if err != nil {
+		return err
+	}
+	w := fx.ebits()
+	if fr.ebits() > w {
+		w = fr.ebits()
+	}
+	exp := signExtend(v.exp, w)
+	exp, err = addWires(cc, exp, constWires(cc, fr.bias()-fx.bias(), w), w)
+	if err != nil {
+		return err
+	}
+	value := MakeWires(fr.bits)
+	err = roundFloat(cc, fr, v.sign, exp, v.sig, value)
+	if err != nil {
+		return err
+	}
+	return selectFloat(cc, fr, v.nan, v.inf, v.sign, v.zero, v.sign,
+		value, r)
+}
+
+// isNaN tests if the floating point value x is NaN.
+func isNaN(cc *Compiler, f floatFormat, x []*Wire) *Wire {
+	return andWire(cc, andReduce(cc, x[f.frac:f.frac+f.exp]),
+		orReduce(cc, x[:f.frac]))
+}
+
+// leadingZeros returns the number of leading zero bits in x.
+func leadingZeros(cc *Compiler, x []*Wire) ([]*Wire, error) {
+	n := bits.Len(uint(len(x)))
+	r := constWires(cc, int64(len(x)), n)
+	for i := 0; i < len(x); i++ {
+		o := MakeWires(n)
+		err := NewMUX(cc, []*Wire{x[i]}, constWires(cc, int64(len(x)-1-i), n),
+			r, o)
+		if err != nil {
+			return nil, err
+		}
+		r = o
+	}
+	return r, nil
+}
+
+func shiftWiresLeft(cc *Compiler, x, s []*Wire) ([]*Wire, error) {
+	r := MakeWires(len(x))
+	err := NewShiftLeft(cc, x, s, r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// shiftRightSticky shifts x right by s bits. The least significant
+// bit of the result is set if any bits were shifted out.
+func shiftRightSticky(cc *Compiler, x, s []*Wire) ([]*Wire, error) {
+	shifted := MakeWires(len(x))
+	err := NewShiftRight(cc, x, s, shifted)
+	if err != nil {
+		return nil, err
+	}
+	back := MakeWires(len(x))
+	err = NewShiftLeft(cc, shifted, s, back)
+	if err != nil {
+		return nil, err
+	}
+	lost := NewWire()
+	err = NewNeqComparator(cc, x, back, []*Wire{lost})
+	if err != nil {
+		return nil, err
+	}
+	r := make([]*Wire, len(x))
+	copy(r, shifted)
+	r[0] = orWire(cc, shifted[0], lost)
+	return r, nil
+}
+
+// addWires returns x+y truncated to n bits.
+func addWires(cc *Compiler, x, y []*Wire, n int) ([]*Wire, error) {
+	r := MakeWires(n)
+	err := NewAdder(cc, cc.ZeroExtend(x, n)[:n], cc.ZeroExtend(y, n)[:n], r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// subWires returns x-y truncated to n bits.
+func subWires(cc *Compiler, x, y []*Wire, n int) ([]*Wire, error) {
+	r := MakeWires(n)
+	err := NewSubtractor(cc, cc.ZeroExtend(x, n)[:n],
+		cc.ZeroExtend(y, n)[:n], r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// signExtend extends the signed value x to n bits.
+func signExtend(x []*Wire, n int) []*Wire {
+	r := make([]*Wire, n)
+	for i := 0; i < n; i++ {
+		if i < len(x) {
+			r[i] = x[i]
+		} else {
+			r[i] = x[len(x)-1]
+		}
+	}
+	return r
+}
+
+// constWires returns the n least significant bits of the two's
+// complement value v.
+func constWires(cc *Compiler, v int64, n int) []*Wire {
+	r := make([]*Wire, n)
+	for i := 0; i < n; i++ {
+		shift := i
+		if shift > 63 {
+			shift = 63
+		}
+		if (v>>shift)&1 != 0 {
+			r[i] = cc.OneWire()
+		} else {
+			r[i] = cc.ZeroWire()
+		}
+	}
+	return r
+}
+
+func andWire(cc *Compiler, a, b *Wire) *Wire {
+	o := NewWire()
+	cc.AddGate(NewBinary(circuit.AND, a, b, o))
+	return o
+}
+
+func orWire(cc *Compiler, a, b *Wire) *Wire {
+	o := NewWire()
+	cc.AddGate(NewBinary(circuit.OR, a, b, o))
+	return o
+}
+
+func xorWire(cc *Compiler, a, b *Wire) *Wire {
+	o := NewWire()
+	cc.AddGate(NewBinary(circuit.XOR, a, b, o))
+	return o
+}
+
+func notWire(cc *Compiler, a *Wire) *Wire {
+	o := NewWire()
+	cc.INV(a, o)
+	return o
+}
+
+// orReduce returns the logical OR of the wires x.
+func orReduce(cc *Compiler, x []*Wire) *Wire {
+	if len(x) == 0 {
+		return cc.ZeroWire()
+	}
+	r := x[0]
+	for i := 1; i < len(x); i++ {
+		r = orWire(cc, r, x[i])
+	}
+	return r
+}
+
+// andReduce returns the logical AND of the wires x.
+func andReduce(cc *Compiler, x []*Wire) *Wire {
+	if len(x) == 0 {
+		return cc.OneWire()
+	}
+	r := x[0]
+	for i := 1; i < len(x); i++ {
+		r = andWire(cc, r, x[i])
+	}
+	return r
+}
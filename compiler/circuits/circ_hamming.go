@@ -15,29 +15,9 @@ import (
 func Hamming(compiler *Compiler, a, b, r []*Wire) error {
 	a, b = compiler.ZeroPad(a, b)
 
-	var arr [][]*Wire
+	diff := MakeWires(len(a))
 	for i := 0; i < len(a); i++ {
-		w := NewWire()
-		compiler.AddGate(NewBinary(circuit.XOR, a[i], b[i], w))
-		arr = append(arr, []*Wire{w})
+		compiler.AddGate(NewBinary(circuit.XOR, a[i], b[i], diff[i]))
 	}
-
-	for len(arr) > 2 {
-		var n [][]*Wire
-		for i := 0; i < len(arr); i += 2 {
-			if i+1 < len(arr) {
-				result := MakeWires(len(arr[i]) + 1)
-				err := NewAdder(compiler, arr[i], arr[i+1], result)
-				if err != nil {
-					return err
-				}
-				n = append(n, result)
-			} else {
-				n = append(n, arr[i])
-			}
-		}
-		arr = n
-	}
-
-	return NewAdder(compiler, arr[0], arr[1], r)
+	return NewPopcount(compiler, diff, r)
 }
@@ -0,0 +1,133 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package circuits
+
+import (
+	"fmt"
+)
+
+// NewIndex creates a new array element read circuit implementing
+// r=array[index]. The array has count elements of len(r) bits each
+// and the circuit selects the element with a linear scan of MUX
+// circuits. The signed argument specifies if the index is a two's
+// complement signed value. The result is zero if the index is out of
+// range.
+func NewIndex(compiler *Compiler, count int, signed bool,
+	array, index, r []*Wire) error {
+
+	size := len(r)
+	if size == 0 || len(array) > count*size {
+		return fmt.Errorf("invalid index arguments: array=%d, count=%d, r=%d",
+			len(array), count, len(r))
+	}
+	array = compiler.ZeroExtend(array, count*size)
+
+	result := make([]*Wire, size)
+	for i := 0; i < size; i++ {
+		result[i] = compiler.ZeroWire()
+	}
+	for i := 0; i < count; i++ {
+		sel, err := newIndexSelector(compiler, signed, index, i)
+		if err != nil {
+			return err
+		}
+		if sel == nil {
+			break
+		}
+		var o []*Wire
+		if i+1 < count {
+			o = MakeWires(size)
+		} else {
+			o = r
+		}
+		err = NewMUX(compiler, sel, array[i*size:(i+1)*size], result, o)
+		if err != nil {
+			return err
+		}
+		result = o
+	}
+	if len(result) > 0 && result[0] != r[0] {
+		// Index can't address all elements; copy the result to output.
+		for i := 0; i < size; i++ {
+			compiler.ID(result[i], r[i])
+		}
+	}
+	return nil
+}
+
+// NewIndexSet creates a new array element write circuit implementing
+// r=array; r[index]=value. The array has count elements and the
+// circuit updates the elements with a linear scan of MUX circuits. The
+// signed argument specifies if the index is a two's complement signed
+// value. The array is unmodified if the index is out of range.
+func NewIndexSet(compiler *Compiler, count int, signed bool,
+	array, index, value, r []*Wire) error {
+
+	if count <= 0 || len(r)%count != 0 || len(array) > len(r) {
+		return fmt.Errorf(
+			"invalid index set arguments: array=%d, count=%d, r=%d",
+			len(array), count, len(r))
+	}
+	size := len(r) / count
+	if len(value) > size {
+		return fmt.Errorf("invalid index set value: value=%d, element=%d",
+			len(value), size)
+	}
+	array = compiler.ZeroExtend(array, len(r))
+	value = compiler.ZeroExtend(value, size)
+
+	for i := 0; i < count; i++ {
+		sel, err := newIndexSelector(compiler, signed, index, i)
+		if err != nil {
+			return err
+		}
+		if sel == nil {
+			// Index can't address this element.
+			for j := i * size; j < len(r); j++ {
+				compiler.ID(array[j], r[j])
+			}
+			break
+		}
+		err = NewMUX(compiler, sel, value, array[i*size:(i+1)*size],
+			r[i*size:(i+1)*size])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newIndexSelector creates a selector circuit that tests if index is
+// equal to the element number i. The function returns nil if index
+// is too small to hold the value i. The sign bit of a signed index
+// is zero for all element numbers so negative indices select no
+// elements.
+func newIndexSelector(compiler *Compiler, signed bool, index []*Wire,
+	i int) ([]*Wire, error) {
+
+	bits := len(index)
+	if signed {
+		bits--
+	}
+	if bits < 63 && i >= 1<<bits {
+		return nil, nil
+	}
+	k := make([]*Wire, len(index))
+	for bit := 0; bit < len(index); bit++ {
+		if bit < 63 && i&(1<<bit) != 0 {
+			k[bit] = compiler.OneWire()
+		} else {
+			k[bit] = compiler.ZeroWire()
+		}
+	}
+	sel := []*Wire{NewWire()}
+	err := NewEqComparator(compiler, index, k, sel)
+	if err != nil {
+		return nil, err
+	}
+	return sel, nil
+}
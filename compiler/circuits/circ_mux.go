@@ -13,9 +13,11 @@ import (
 )
 
 // NewMUX creates a multiplexer circuit that selects the input t or f
-// to output, based on the value of the condition cond.
+// to output, based on the value of the condition cond. The inputs
+// are zero-extended to the output size.
 func NewMUX(compiler *Compiler, cond, t, f, out []*Wire) error {
-	t, f = compiler.ZeroPad(t, f)
+	t = compiler.ZeroExtend(t, len(out))
+	f = compiler.ZeroExtend(f, len(out))
 	if len(cond) != 1 || len(t) != len(f) || len(t) != len(out) {
 		return fmt.Errorf("invalid mux arguments: cond=%d, l=%d, r=%d, out=%d",
 			len(cond), len(t), len(f), len(out))
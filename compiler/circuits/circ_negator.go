@@ -0,0 +1,43 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package circuits
+
+import (
+	"fmt"
+
+	"github.com/markkurossi/mpc/circuit"
+)
+
+// NewNegator creates a new two's complement negation circuit
+// implementing r=-x. The circuit computes r=^x+1 with a half adder
+// chain.
+func NewNegator(compiler *Compiler, x, r []*Wire) error {
+	if len(x) > len(r) {
+		return fmt.Errorf("invalid negator arguments: x=%d, r=%d",
+			len(x), len(r))
+	}
+	x = compiler.ZeroExtend(x, len(r))
+	var carry *Wire
+	for i := 0; i < len(x); i++ {
+		nx := NewWire()
+		compiler.INV(x[i], nx)
+
+		if i == 0 {
+			// ^x[0]+1: the sum is x[0] and carry ^x[0].
+			compiler.ID(x[0], r[0])
+			carry = nx
+			continue
+		}
+		compiler.AddGate(NewBinary(circuit.XOR, nx, carry, r[i]))
+		if i+1 < len(x) {
+			cout := NewWire()
+			compiler.AddGate(NewBinary(circuit.AND, nx, carry, cout))
+			carry = cout
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package circuits
+
+import (
+	"fmt"
+
+	"github.com/markkurossi/mpc/circuit"
+)
+
+type shiftOp int
+
+const (
+	shiftLeft shiftOp = iota
+	shiftRight
+	shiftRightArithmetic
+	rotateLeft
+	rotateRight
+)
+
+// NewShiftLeft creates a barrel shifter circuit implementing r=x<<s.
+func NewShiftLeft(cc *Compiler, x, s, r []*Wire) error {
+	return newShifter(cc, shiftLeft, x, s, r)
+}
+
+// NewShiftRight creates a barrel shifter circuit implementing the
+// logical right shift r=x>>s.
+func NewShiftRight(cc *Compiler, x, s, r []*Wire) error {
+	return newShifter(cc, shiftRight, x, s, r)
+}
+
+// NewShiftRightArithmetic creates a barrel shifter circuit
+// implementing the arithmetic right shift r=x>>s. The most
+// significant bit of x is shifted in from the left.
+func NewShiftRightArithmetic(cc *Compiler, x, s, r []*Wire) error {
+	return newShifter(cc, shiftRightArithmetic, x, s, r)
+}
+
+// NewRotateLeft creates a barrel shifter circuit rotating x left by
+// s bits. The rotation is done over len(r) bits.
+func NewRotateLeft(cc *Compiler, x, s, r []*Wire) error {
+	return newShifter(cc, rotateLeft, x, s, r)
+}
+
+// NewRotateRight creates a barrel shifter circuit rotating x right
+// by s bits. The rotation is done over len(r) bits.
+func NewRotateRight(cc *Compiler, x, s, r []*Wire) error {
+	return newShifter(cc, rotateRight, x, s, r)
+}
+
+// newShifter creates a logarithmic barrel shifter. Each count bit i
+// selects a stage that shifts the value by 2^i bits. For shifts, the
+// count bits whose stages would shift all bits out are combined into
+// one stage that replaces the value with the fill bits.
+func newShifter(cc *Compiler, op shiftOp, x, s, r []*Wire) error {
+	n := len(r)
+	if n == 0 || len(x) == 0 || len(s) == 0 {
+		return fmt.Errorf("invalid shifter arguments: x=%d, s=%d, r=%d",
+			len(x), len(s), len(r))
+	}
+	fill := cc.ZeroWire()
+	if op == shiftRightArithmetic {
+		fill = x[len(x)-1]
+	}
+	x = cc.ZeroExtend(x, n)[:n]
+
+	var sels []*Wire
+	var amounts []int
+
+	switch op {
+	case rotateLeft, rotateRight:
+		amount := 1 % n
+		for i := 0; i < len(s); i++ {
+			if amount != 0 {
+				sels = append(sels, s[i])
+				amounts = append(amounts, amount)
+			}
+			amount = (amount * 2) % n
+		}
+
+	default:
+		var i int
+		for i = 0; i < len(s) && 1<<i < n; i++ {
+			sels = append(sels, s[i])
+			amounts = append(amounts, 1<<i)
+		}
+		if i < len(s) {
+			sel := s[i]
+			for i++; i < len(s); i++ {
+				o := NewWire()
+				cc.AddGate(NewBinary(circuit.OR, sel, s[i], o))
+				sel = o
+			}
+			sels = append(sels, sel)
+			amounts = append(amounts, n)
+		}
+	}
+
+	if len(sels) == 0 {
+		for i := 0; i < n; i++ {
+			cc.ID(x[i], r[i])
+		}
+		return nil
+	}
+
+	for stage, amount := range amounts {
+		shifted := make([]*Wire, n)
+		for bit := 0; bit < n; bit++ {
+			var src int
+			switch op {
+			case shiftLeft:
+				src = bit - amount
+			case shiftRight, shiftRightArithmetic:
+				src = bit + amount
+				if src >= n {
+					src = -1
+				}
+			case rotateLeft:
+				src = (bit - amount + n) % n
+			case rotateRight:
+				src = (bit + amount) % n
+			}
+			if src < 0 {
+				shifted[bit] = fill
+			} else {
+				shifted[bit] = x[src]
+			}
+		}
+		var o []*Wire
+		if stage+1 < len(amounts) {
+			o = MakeWires(n)
+		} else {
+			o = r
+		}
+		err := NewMUX(cc, []*Wire{sels[stage]}, shifted, x, o)
+		if err != nil {
+			return err
+		}
+		x = o
+	}
+	return nil
+}
@@ -10,6 +10,10 @@ package circuits
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"math/rand"
 	"os"
 	"testing"
 
@@ -136,3 +140,848 @@ func TestMultiply(t *testing.T) {
 		result.Marshal(os.Stdout)
 	}
 }
+
+func TestIndex(t *testing.T) {
+	count := 4
+	bits := 4
+
+	inputs := makeWires(count*bits+2, false)
+	outputs := makeWires(bits, true)
+	c, err := NewCompiler(params, circuit.IO{
+		circuit.IOArg{Name: "a", Size: count * bits},
+		circuit.IOArg{Name: "i", Size: 2},
+	}, NewIO(bits, "out"), inputs, outputs)
+	if err != nil {
+		t.Fatalf("NewCompiler: %s", err)
+	}
+	err = NewIndex(c, count, false, inputs[:count*bits], inputs[count*bits:],
+		outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := c.Compile()
+
+	array := big.NewInt(0xdcba)
+	for i := 0; i < count; i++ {
+		out, err := result.Compute([]*big.Int{array, big.NewInt(int64(i))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := int64(0xa + i)
+		if out[0].Int64() != expected {
+			t.Errorf("index %d: got %v, expected %v", i, out[0], expected)
+		}
+	}
+}
+
+func testShifter(t *testing.T, name string, bits, countBits int,
+	shifter func(cc *Compiler, x, s, r []*Wire) error,
+	reference func(x, s uint64) uint64) {
+
+	inputs := makeWires(bits+countBits, false)
+	outputs := makeWires(bits, true)
+	c, err := NewCompiler(params, circuit.IO{
+		circuit.IOArg{Name: "x", Size: bits},
+		circuit.IOArg{Name: "s", Size: countBits},
+	}, NewIO(bits, "out"), inputs, outputs)
+	if err != nil {
+		t.Fatalf("NewCompiler: %s", err)
+	}
+	err = shifter(c, inputs[:bits], inputs[bits:], outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := c.Compile()
+
+	mask := uint64(1)<<bits - 1
+	for x := uint64(0); x <= mask; x++ {
+		for s := uint64(0); s < 1<<countBits; s++ {
+			out, err := result.Compute([]*big.Int{
+				new(big.Int).SetUint64(x),
+				new(big.Int).SetUint64(s),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := reference(x, s) & mask
+			if out[0].Uint64() != expected {
+				t.Fatalf("%s%d(%x, %d): got %x, expected %x",
+					name, bits, x, s, out[0], expected)
+			}
+		}
+	}
+}
+
+func TestShift(t *testing.T) {
+	for _, bits := range []int{6, 8} {
+		bits := bits
+		rotl := func(x, s uint64) uint64 {
+			k := s % uint64(bits)
+			return x<<k | x>>(uint64(bits)-k)
+		}
+		testShifter(t, "lshift", bits, 4, NewShiftLeft,
+			func(x, s uint64) uint64 {
+				return x << s
+			})
+		testShifter(t, "rshift", bits, 4, NewShiftRight,
+			func(x, s uint64) uint64 {
+				return x >> s
+			})
+		testShifter(t, "srshift", bits, 4, NewShiftRightArithmetic,
+			func(x, s uint64) uint64 {
+				return uint64(int64(x<<(64-bits)) >> (64 - bits) >> s)
+			})
+		testShifter(t, "rotl", bits, 4, NewRotateLeft, rotl)
+		testShifter(t, "rotr", bits, 4, NewRotateRight,
+			func(x, s uint64) uint64 {
+				return rotl(x, uint64(bits)-s%uint64(bits))
+			})
+	}
+}
+
+func newTestCircuit(t *testing.T, in []int, out int,
+	gen func(cc *Compiler, in [][]*Wire, r []*Wire) error) *circuit.Circuit {
+
+	var size int
+	var io circuit.IO
+	for idx, bits := range in {
+		io = append(io, circuit.IOArg{
+			Name: fmt.Sprintf("in%d", idx),
+			Size: bits,
+		})
+		size += bits
+	}
+	inputs := makeWires(size, false)
+	outputs := makeWires(out, true)
+	c, err := NewCompiler(params, io, NewIO(out, "out"), inputs, outputs)
+	if err != nil {
+		t.Fatalf("NewCompiler: %s", err)
+	}
+	var args [][]*Wire
+	for _, bits := range in {
+		args = append(args, inputs[:bits])
+		inputs = inputs[bits:]
+	}
+	err = gen(c, args, outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.Compile()
+}
+
+func compute(t *testing.T, c *circuit.Circuit, in ...uint64) uint64 {
+	var args []*big.Int
+	for _, v := range in {
+		args = append(args, new(big.Int).SetUint64(v))
+	}
+	out, err := c.Compute(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out[0].Uint64()
+}
+
+var floatTestValues = []float64{
+	0, math.Copysign(0, -1), 1, -1, 1.5, -2.75, 0.1, 3, 7, 1e10, -1e-10,
+	123456.789, 1 << 24, 1<<24 + 1, 1<<53 + 1, math.MaxFloat32, 1e-38,
+	1e-40, -1e-45, math.SmallestNonzeroFloat64, 2.2250738585072014e-308,
+	1e-310, math.MaxFloat64, 1e300, math.Inf(1), math.Inf(-1), math.NaN(),
+}
+
+func floatValues(bits int) []uint64 {
+	rnd := rand.New(rand.NewSource(1))
+	var result []uint64
+	for _, v := range floatTestValues {
+		if bits == 32 {
+			result = append(result, uint64(math.Float32bits(float32(v))))
+		} else {
+			result = append(result, math.Float64bits(v))
+		}
+	}
+	for i := 0; i < 12; i++ {
+		if bits == 32 {
+			result = append(result, uint64(rnd.Uint32()))
+		} else {
+			result = append(result, rnd.Uint64())
+		}
+	}
+	return result
+}
+
+// floatEqual tests if the float bit patterns a and b are equal. All
+// NaN values are equal.
+func floatEqual(bits int, a, b uint64) bool {
+	if bits == 32 {
+		return a == b || math.IsNaN(float64(math.Float32frombits(uint32(a)))) &&
+			math.IsNaN(float64(math.Float32frombits(uint32(b))))
+	}
+	return a == b || math.IsNaN(math.Float64frombits(a)) &&
+		math.IsNaN(math.Float64frombits(b))
+}
+
+func TestFloat(t *testing.T) {
+	type binary func(cc *Compiler, x, y, r []*Wire) error
+
+	for _, bits := range []int{32, 64} {
+		ops := []struct {
+			name  string
+			gen   binary
+			out   int
+			ref32 func(x, y float32) uint64
+			ref64 func(x, y float64) uint64
+		}{
+			{"add", NewFloatAdder, bits,
+				func(x, y float32) uint64 {
+					return uint64(math.Float32bits(x + y))
+				},
+				func(x, y float64) uint64 { return math.Float64bits(x + y) }},
+			{"sub", NewFloatSubtractor, bits,
+				func(x, y float32) uint64 {
+					return uint64(math.Float32bits(x - y))
+				},
+				func(x, y float64) uint64 { return math.Float64bits(x - y) }},
+			{"mult", NewFloatMultiplier, bits,
+				func(x, y float32) uint64 {
+					return uint64(math.Float32bits(x * y))
+				},
+				func(x, y float64) uint64 { return math.Float64bits(x * y) }},
+			{"div", NewFloatDivider, bits,
+				func(x, y float32) uint64 {
+					return uint64(math.Float32bits(x / y))
+				},
+				func(x, y float64) uint64 { return math.Float64bits(x / y) }},
+			{"lt", NewFloatLtComparator, 1,
+				func(x, y float32) uint64 { return boolBit(x < y) },
+				func(x, y float64) uint64 { return boolBit(x < y) }},
+			{"le", NewFloatLeComparator, 1,
+				func(x, y float32) uint64 { return boolBit(x <= y) },
+				func(x, y float64) uint64 { return boolBit(x <= y) }},
+			{"gt", NewFloatGtComparator, 1,
+				func(x, y float32) uint64 { return boolBit(x > y) },
+				func(x, y float64) uint64 { return boolBit(x > y) }},
+			{"ge", NewFloatGeComparator, 1,
+				func(x, y float32) uint64 { return boolBit(x >= y) },
+				func(x, y float64) uint64 { return boolBit(x >= y) }},
+			{"eq", NewFloatEqComparator, 1,
+				func(x, y float32) uint64 { return boolBit(x == y) },
+				func(x, y float64) uint64 { return boolBit(x == y) }},
+			{"neq", NewFloatNeqComparator, 1,
+				func(x, y float32) uint64 { return boolBit(x != y) },
+				func(x, y float64) uint64 { return boolBit(x != y) }},
+		}
+		values := floatValues(bits)
+
+		for _, op := range ops {
+			op := op
+			c := newTestCircuit(t, []int{bits, bits}, op.out,
+				func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+					return op.gen(cc, in[0], in[1], r)
+				})
+			for _, x := range values {
+				for _, y := range values {
+					var expected uint64
+					if bits == 32 {
+						expected = op.ref32(math.Float32frombits(uint32(x)),
+							math.Float32frombits(uint32(y)))
+					} else {
+						expected = op.ref64(math.Float64frombits(x),
+							math.Float64frombits(y))
+					}
+					result := compute(t, c, x, y)
+					if !floatEqual(op.out, result, expected) {
+						t.Errorf("float%d %s(%x, %x): got %x, expected %x",
+							bits, op.name, x, y, result, expected)
+					}
+				}
+			}
+		}
+	}
+}
+
+func boolBit(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func TestFloatConversions(t *testing.T) {
+	ints := []int64{0, 1, -1, 7, -100, 1<<24 + 1, 1<<53 + 1, math.MaxInt64,
+		math.MinInt64, 123456789}
+
+	for _, bits := range []int{32, 64} {
+		itof := newTestCircuit(t, []int{64}, bits,
+			func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+				return NewIntToFloat(cc, in[0], r)
+			})
+		utof := newTestCircuit(t, []int{64}, bits,
+			func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+				return NewUintToFloat(cc, in[0], r)
+			})
+		for _, i := range ints {
+			var si, ui uint64
+			if bits == 32 {
+				si = uint64(math.Float32bits(float32(i)))
+				ui = uint64(math.Float32bits(float32(uint64(i))))
+			} else {
+				si = math.Float64bits(float64(i))
+				ui = math.Float64bits(float64(uint64(i)))
+			}
+			if r := compute(t, itof, uint64(i)); r != si {
+				t.Errorf("int64 %d to float%d: got %x, expected %x",
+					i, bits, r, si)
+			}
+			if r := compute(t, utof, uint64(i)); r != ui {
+				t.Errorf("uint64 %d to float%d: got %x, expected %x",
+					uint64(i), bits, r, ui)
+			}
+		}
+
+		ftoi := newTestCircuit(t, []int{bits}, 32,
+			func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+				return NewFloatToInt(cc, in[0], r)
+			})
+		for _, f := range []float64{0, 1, -1, 1.5, -2.75, 0.1, 1e-40, 255.9,
+			-1e9, 2147483647} {
+			var x uint64
+			if bits == 32 {
+				x = uint64(math.Float32bits(float32(f)))
+			} else {
+				x = math.Float64bits(f)
+			}
+			expected := uint64(uint32(int32(f)))
+			if bits == 32 {
+				expected = uint64(uint32(int32(float32(f))))
+			}
+			if f == 2147483647 && bits == 32 {
+				// Rounds to 2^31 which does not fit into int32.
+				continue
+			}
+			if r := compute(t, ftoi, x); r != expected {
+				t.Errorf("float%d %v to int32: got %x, expected %x",
+					bits, f, r, expected)
+			}
+		}
+	}
+
+	ftof64 := newTestCircuit(t, []int{32}, 64,
+		func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+			return NewFloatToFloat(cc, in[0], r)
+		})
+	for _, x := range floatValues(32) {
+		expected := math.Float64bits(float64(math.Float32frombits(uint32(x))))
+		if r := compute(t, ftof64, x); !floatEqual(64, r, expected) {
+			t.Errorf("float32 %x to float64: got %x, expected %x",
+				x, r, expected)
+		}
+	}
+	ftof32 := newTestCircuit(t, []int{64}, 32,
+		func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+			return NewFloatToFloat(cc, in[0], r)
+		})
+	for _, x := range floatValues(64) {
+		expected := uint64(math.Float32bits(
+			float32(math.Float64frombits(x))))
+		if r := compute(t, ftof32, x); !floatEqual(32, r, expected) {
+			t.Errorf("float64 %x to float32: got %x, expected %x",
+				x, r, expected)
+		}
+	}
+}
+
+func fixedValues(bits int) []int64 {
+	max := int64(1)<<(bits-1) - 1
+	min := -max - 1
+	result := []int64{0, 1, -1, 2, -2, 255, -256, 1 << (bits / 2),
+		-1 << (bits / 2), max, min, max / 3, min / 5}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 12; i++ {
+		result = append(result, rnd.Int63n(max)-rnd.Int63n(max))
+	}
+	return result
+}
+
+func TestFixed(t *testing.T) {
+	for _, format := range []struct {
+		bits int
+		frac int
+	}{{16, 8}, {32, 16}, {24, 0}} {
+		n := format.bits
+		f := uint(format.frac)
+		mask := uint64(1)<<n - 1
+		mult := newTestCircuit(t, []int{n, n}, n,
+			func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+				return NewFixedMultiplier(cc, 0, format.frac, in[0], in[1], r)
+			})
+		div := newTestCircuit(t, []int{n, n}, n,
+			func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+				return NewFixedDivider(cc, format.frac, in[0], in[1], r)
+			})
+		values := fixedValues(n)
+		for _, x := range values {
+			for _, y := range values {
+				var expected int64
+				if f > 0 {
+					// Round ties away from zero.
+					p := x * y
+					if p < 0 {
+						p = -p
+					}
+					expected = (p + 1<<(f-1)) >> f
+					if x*y < 0 {
+						expected = -expected
+					}
+				} else {
+					expected = x * y
+				}
+				r := compute(t, mult, uint64(x)&mask, uint64(y)&mask)
+				if r != uint64(expected)&mask {
+					t.Errorf("fixed%d.%d %d*%d: got %x, expected %x",
+						n, f, x, y, r, uint64(expected)&mask)
+				}
+				if y == 0 {
+					continue
+				}
+				ax, ay := x, y
+				if ax < 0 {
+					ax = -ax
+				}
+				if ay < 0 {
+					ay = -ay
+				}
+				expected = ((ax<<(f+1))/ay + 1) >> 1
+				if (x < 0) != (y < 0) {
+					expected = -expected
+				}
+				r = compute(t, div, uint64(x)&mask, uint64(y)&mask)
+				if r != uint64(expected)&mask {
+					t.Errorf("fixed%d.%d %d/%d: got %x, expected %x",
+						n, f, x, y, r, uint64(expected)&mask)
+				}
+			}
+		}
+	}
+}
+
+func TestFixedRounding(t *testing.T) {
+	const n = 16
+	const mask = uint64(1)<<n - 1
+
+	mult := newTestCircuit(t, []int{n, n}, n,
+		func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+			return NewFixedMultiplier(cc, 0, 8, in[0], in[1], r)
+		})
+	div := newTestCircuit(t, []int{n, n}, n,
+		func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+			return NewFixedDivider(cc, 8, in[0], in[1], r)
+		})
+
+	// The raw fixed16.8 operands have exact halfway results which
+	// round away from zero like constants.
+	tests := []struct {
+		circ     *circuit.Circuit
+		op       string
+		x, y     int64
+		expected int64
+	}{
+		{mult, "*", 1, 128, 1},
+		{mult, "*", -1, 128, -1},
+		{mult, "*", 3, 128, 2},
+		{mult, "*", -3, 128, -2},
+		{mult, "*", -5, -128, 3},
+		{mult, "*", 5, -128, -3},
+		{div, "/", 1, 512, 1},
+		{div, "/", -1, 512, -1},
+		{div, "/", 3, 512, 2},
+		{div, "/", -3, 512, -2},
+		{div, "/", -5, -512, 3},
+		{div, "/", 5, -512, -3},
+	}
+	for _, test := range tests {
+		x := uint64(test.x) & mask
+		y := uint64(test.y) & mask
+		r := compute(t, test.circ, x, y)
+		if r != uint64(test.expected)&mask {
+			t.Errorf("%d%s%d: got %x, expected %x",
+				test.x, test.op, test.y, r, uint64(test.expected)&mask)
+		}
+		rx := circuit.FixedToRat(new(big.Int).SetUint64(x), n, 8)
+		ry := circuit.FixedToRat(new(big.Int).SetUint64(y), n, 8)
+		if test.op == "*" {
+			rx.Mul(rx, ry)
+		} else {
+			rx.Quo(rx, ry)
+		}
+		raw, err := circuit.FixedFromRat(rx, n, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if raw.Uint64() != r {
+			t.Errorf("%d%s%d: circuit %x, constant %x",
+				test.x, test.op, test.y, r, raw)
+		}
+	}
+}
+
+func TestFixedConverter(t *testing.T) {
+	tests := []struct {
+		signed bool
+		from   int
+		to     int
+		in     int
+		out    int
+		x      uint64
+		r      uint64
+	}{
+		{true, 0, 16, 16, 32, 0xfffe, 0xfffe0000},
+		{false, 0, 16, 16, 32, 0xfffe, 0xfffe0000},
+		{true, 0, 8, 16, 32, 0xfffe, 0xfffffe00},
+		{false, 0, 8, 16, 32, 0xfffe, 0x00fffe00},
+		{true, 16, 0, 32, 16, 0xfffe8000, 0xfffe},
+		{true, 16, 0, 32, 16, 0x00018000, 0x0001},
+		{true, 16, 8, 32, 16, 0x00018040, 0x0180},
+		{true, 8, 16, 16, 32, 0x8001, 0xff800100},
+	}
+	for _, test := range tests {
+		test := test
+		c := newTestCircuit(t, []int{test.in}, test.out,
+			func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+				return NewFixedConverter(cc, test.signed, test.from, test.to,
+					in[0], r)
+			})
+		if r := compute(t, c, test.x); r != test.r {
+			t.Errorf("convert %x (%d.%d) to %d.%d: got %x, expected %x",
+				test.x, test.in, test.from, test.out, test.to, r, test.r)
+		}
+	}
+}
+
+func TestBits(t *testing.T) {
+	unary := []struct {
+		name string
+		out  int
+		gen  func(cc *Compiler, x, r []*Wire) error
+		eval func(x uint8) uint64
+	}{
+		{"popcount", 32, NewPopcount, func(x uint8) uint64 {
+			return uint64(bits.OnesCount8(x))
+		}},
+		{"clz", 32, NewLeadingZeros, func(x uint8) uint64 {
+			return uint64(bits.LeadingZeros8(x))
+		}},
+		{"ctz", 32, NewTrailingZeros, func(x uint8) uint64 {
+			return uint64(bits.TrailingZeros8(x))
+		}},
+		{"bitreverse", 8, NewBitReverse, func(x uint8) uint64 {
+			return uint64(bits.Reverse8(x))
+		}},
+		{"abs", 8, NewAbs, func(x uint8) uint64 {
+			if int8(x) < 0 {
+				return uint64(uint8(-int8(x)))
+			}
+			return uint64(x)
+		}},
+	}
+	for _, test := range unary {
+		test := test
+		c := newTestCircuit(t, []int{8}, test.out,
+			func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+				return test.gen(cc, in[0], r)
+			})
+		for x := 0; x < 256; x++ {
+			r := compute(t, c, uint64(x))
+			if r != test.eval(uint8(x)) {
+				t.Errorf("%s(%x): got %x, expected %x", test.name, x, r,
+					test.eval(uint8(x)))
+			}
+		}
+	}
+
+	binary := []struct {
+		name string
+		gen  func(cc *Compiler, x, y, r []*Wire) error
+		eval func(x, y uint8) uint8
+	}{
+		{"min", NewMin, func(x, y uint8) uint8 {
+			if x < y {
+				return x
+			}
+			return y
+		}},
+		{"max", NewMax, func(x, y uint8) uint8 {
+			if x > y {
+				return x
+			}
+			return y
+		}},
+		{"smin", NewSignedMin, func(x, y uint8) uint8 {
+			if int8(x) < int8(y) {
+				return x
+			}
+			return y
+		}},
+		{"smax", NewSignedMax, func(x, y uint8) uint8 {
+			if int8(x) > int8(y) {
+				return x
+			}
+			return y
+		}},
+	}
+	for _, test := range binary {
+		test := test
+		c := newTestCircuit(t, []int{8, 8}, 8,
+			func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+				return test.gen(cc, in[0], in[1], r)
+			})
+		for x := 0; x < 256; x += 7 {
+			for y := 0; y < 256; y += 5 {
+				r := compute(t, c, uint64(x), uint64(y))
+				if r != uint64(test.eval(uint8(x), uint8(y))) {
+					t.Errorf("%s(%x, %x): got %x, expected %x", test.name,
+						x, y, r, test.eval(uint8(x), uint8(y)))
+				}
+			}
+		}
+	}
+}
+
+func TestChecked(t *testing.T) {
+	const bits = 6
+	const mask = 1<<bits - 1
+
+	// wrap returns the wrapped result and tells if the exact value v
+	// is outside the range of the signed or unsigned result type.
+	wrap := func(signed bool, v int64) (uint64, bool) {
+		if signed {
+			return uint64(v) & mask, v < -(1<<(bits-1)) || v >= 1<<(bits-1)
+		}
+		return uint64(v) & mask, v < 0 || v > mask
+	}
+	value := func(signed bool, x uint64) int64 {
+		if signed && x&(1<<(bits-1)) != 0 {
+			return int64(x) - 1<<bits
+		}
+		return int64(x)
+	}
+	clamp := func(signed bool, v int64) uint64 {
+		min, max := int64(0), int64(mask)
+		if signed {
+			min, max = -(1 << (bits - 1)), 1<<(bits-1)-1
+		}
+		if v < min {
+			v = min
+		} else if v > max {
+			v = max
+		}
+		return uint64(v) & mask
+	}
+
+	tests := []struct {
+		name      string
+		checked   func(cc *Compiler, signed bool, x, y, r []*Wire, ov *Wire) error
+		saturated func(cc *Compiler, signed bool, x, y, r []*Wire) error
+		eval      func(x, y int64) int64
+	}{
+		{"add", NewCheckedAdder, NewSaturatingAdder,
+			func(x, y int64) int64 {
+				return x + y
+			}},
+		{"sub", NewCheckedSubtractor, NewSaturatingSubtractor,
+			func(x, y int64) int64 {
+				return x - y
+			}},
+		{"mul", NewCheckedMultiplier, NewSaturatingMultiplier,
+			func(x, y int64) int64 {
+				return x * y
+			}},
+	}
+	for _, test := range tests {
+		for _, signed := range []bool{false, true} {
+			test := test
+			signed := signed
+			checked := newTestCircuit(t, []int{bits, bits}, bits+1,
+				func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+					return test.checked(cc, signed, in[0], in[1], r[:bits],
+						r[bits])
+				})
+			saturated := newTestCircuit(t, []int{bits, bits}, bits,
+				func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+					return test.saturated(cc, signed, in[0], in[1], r)
+				})
+			for x := uint64(0); x <= mask; x++ {
+				for y := uint64(0); y <= mask; y++ {
+					v := test.eval(value(signed, x), value(signed, y))
+					result, overflow := wrap(signed, v)
+					if overflow {
+						result |= 1 << bits
+					}
+					r := compute(t, checked, x, y)
+					if r != result {
+						t.Errorf("checked %s(%x, %x) signed=%v: got %x, "+
+							"expected %x", test.name, x, y, signed, r, result)
+					}
+					r = compute(t, saturated, x, y)
+					if r != clamp(signed, v) {
+						t.Errorf("saturated %s(%x, %x) signed=%v: got %x, "+
+							"expected %x", test.name, x, y, signed, r,
+							clamp(signed, v))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestConstMultiplier(t *testing.T) {
+	const bits = 8
+	const mask = 1<<bits - 1
+
+	for _, c := range []int64{0, 1, 2, 3, 5, 7, 10, 15, 100, 127, 255, 256,
+		1000, -1, -3, -128} {
+		circ := newTestCircuit(t, []int{bits}, bits,
+			func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+				return NewConstMultiplier(cc, in[0], big.NewInt(c), r)
+			})
+		for x := uint64(0); x <= mask; x++ {
+			expected := uint64(int64(x)*c) & mask
+			r := compute(t, circ, x)
+			if r != expected {
+				t.Errorf("%d*%d: got %d, expected %d", x, c, r, expected)
+			}
+		}
+	}
+}
+
+func TestConstDivider(t *testing.T) {
+	const bits = 8
+	const mask = 1<<bits - 1
+
+	for d := int64(1); d <= 300; d++ {
+		circ := newTestCircuit(t, []int{bits}, 2*bits,
+			func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+				return NewConstDivider(cc, in[0], big.NewInt(d),
+					r[:bits], r[bits:])
+			})
+		for x := uint64(0); x <= mask; x++ {
+			expected := x/uint64(d) | x%uint64(d)<<bits
+			r := compute(t, circ, x)
+			if r != expected {
+				t.Errorf("%d/%d: got %x, expected %x", x, d, r, expected)
+			}
+		}
+	}
+	for d := int64(-128); d <= 127; d++ {
+		if d == 0 {
+			continue
+		}
+		circ := newTestCircuit(t, []int{bits}, 2*bits,
+			func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+				return NewSignedConstDivider(cc, in[0], big.NewInt(d),
+					r[:bits], r[bits:])
+			})
+		for x := uint64(0); x <= mask; x++ {
+			v := int64(int8(x))
+			expected := uint64(int8(v/d))&mask | (uint64(int8(v%d))&mask)<<bits
+			r := compute(t, circ, x)
+			if r != expected {
+				t.Errorf("%d/%d: got %x, expected %x", v, d, r, expected)
+			}
+		}
+	}
+}
+
+// constWiresOf returns the wires of the n-bit two's complement
+// constant c.
+func constWiresOf(cc *Compiler, c *big.Int, n int) []*Wire {
+	v := new(big.Int).Mod(c, new(big.Int).Lsh(big.NewInt(1), uint(n)))
+	r := make([]*Wire, n)
+	for i := 0; i < n; i++ {
+		if v.Bit(i) != 0 {
+			r[i] = cc.OneWire()
+		} else {
+			r[i] = cc.ZeroWire()
+		}
+	}
+	return r
+}
+
+func TestConstGates(t *testing.T) {
+	const bits = 32
+
+	ands := func(gen func(cc *Compiler, x, r []*Wire) error) int {
+		circ := newTestCircuit(t, []int{bits}, bits,
+			func(cc *Compiler, in [][]*Wire, r []*Wire) error {
+				return gen(cc, in[0], r)
+			})
+		return circ.Stats[circuit.AND]
+	}
+
+	for _, v := range []int64{3, 10, 1000, 0x5555, 0x12345, 1<<31 - 1, -7} {
+		c := big.NewInt(v)
+		general := ands(func(cc *Compiler, x, r []*Wire) error {
+			return NewMultiplier(cc, params.CircMultArrayTreshold, x,
+				constWiresOf(cc, c, bits), r)
+		})
+		constant := ands(func(cc *Compiler, x, r []*Wire) error {
+			return NewConstMultiplier(cc, x, c, r)
+		})
+		if verbose {
+			fmt.Printf("x*%d: %d vs. %d AND gates\n", v, constant, general)
+		}
+		if constant >= general {
+			t.Errorf("x*%d: constant multiplier has %d AND gates, "+
+				"general multiplier %d", v, constant, general)
+		}
+
+		general = ands(func(cc *Compiler, x, r []*Wire) error {
+			return NewSignedDivider(cc, x, constWiresOf(cc, c, bits), r, nil)
+		})
+		constant = ands(func(cc *Compiler, x, r []*Wire) error {
+			return NewSignedConstDivider(cc, x, c, r, nil)
+		})
+		if verbose {
+			fmt.Printf("x/%d: %d vs. %d AND gates\n", v, constant, general)
+		}
+		if constant >= general {
+			t.Errorf("x/%d: constant divider has %d AND gates, "+
+				"general divider %d", v, constant, general)
+		}
+
+		if v < 0 {
+			continue
+		}
+		general = ands(func(cc *Compiler, x, r []*Wire) error {
+			return NewDivider(cc, x, constWiresOf(cc, c, bits), nil, r)
+		})
+		constant = ands(func(cc *Compiler, x, r []*Wire) error {
+			return NewConstDivider(cc, x, c, nil, r)
+		})
+		if verbose {
+			fmt.Printf("x%%%d: %d vs. %d AND gates\n", v, constant, general)
+		}
+		if constant >= general {
+			t.Errorf("x%%%d: constant modulo has %d AND gates, "+
+				"general modulo %d", v, constant, general)
+		}
+	}
+}
+
+func TestMUXSizes(t *testing.T) {
+	cc, err := NewCompiler(params, NewIO(1, "in"), NewIO(8, "out"),
+		makeWires(1, false), makeWires(8, true))
+	if err != nil {
+		t.Fatalf("NewCompiler: %s", err)
+	}
+	cond := makeWires(1, false)
+
+	err = NewMUX(cc, cond, makeWires(4, false), makeWires(8, false),
+		makeWires(8, false))
+	if err != nil {
+		t.Errorf("NewMUX failed for narrow input: %s", err)
+	}
+	err = NewMUX(cc, cond, makeWires(16, false), makeWires(8, false),
+		makeWires(8, false))
+	if err == nil {
+		t.Errorf("NewMUX accepted oversize input")
+	}
+}
@@ -14,9 +14,9 @@ import (
 	"github.com/markkurossi/mpc/compiler/utils"
 )
 
-// Builtin implements a buitin circuit that uses input wires a and b
+// Builtin implements a buitin circuit that uses the input wires args
 // and returns the circuit result in r.
-type Builtin func(cc *Compiler, a, b, r []*Wire) error
+type Builtin func(cc *Compiler, args [][]*Wire, r []*Wire) error
 
 // Compiler implements binary circuit compiler.
 type Compiler struct {
@@ -107,6 +107,23 @@ func (c *Compiler) ZeroPad(x, y []*Wire) ([]*Wire, []*Wire) {
 	return rx, ry
 }
 
+// ZeroExtend pads the argument wires x with zero values so that the
+// result has size bits.
+func (c *Compiler) ZeroExtend(x []*Wire, size int) []*Wire {
+	if len(x) >= size {
+		return x
+	}
+	result := make([]*Wire, size)
+	for i := 0; i < size; i++ {
+		if i < len(x) {
+			result[i] = x[i]
+		} else {
+			result[i] = c.ZeroWire()
+		}
+	}
+	return result
+}
+
 // ShiftLeft shifts the size number of bits of the input wires w,
 // count bits left.
 func (c *Compiler) ShiftLeft(w []*Wire, size, count int) []*Wire {
@@ -79,37 +79,45 @@ func (c *Compiler) compile(source string, in io.Reader) (
 // to garble and stream the circuit to the evaluator node.
 func (c *Compiler) StreamFile(conn *p2p.Conn, file string,
 	input []string) (circuit.IO, []*big.Int, error) {
+	outputs, result, _, err := c.StreamFileWithTiming(conn, file, input)
+	return outputs, result, err
+}
+
+// StreamFileWithTiming is like StreamFile but it also returns the
+// timing report of the computation.
+func (c *Compiler) StreamFileWithTiming(conn *p2p.Conn, file string,
+	input []string) (circuit.IO, []*big.Int, *circuit.Timing, error) {
 
 	f, err := os.Open(file)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	defer f.Close()
 	return c.stream(conn, file, f, input)
 }
 
 func (c *Compiler) stream(conn *p2p.Conn, source string, in io.Reader,
-	inputFlag []string) (circuit.IO, []*big.Int, error) {
+	inputFlag []string) (circuit.IO, []*big.Int, *circuit.Timing, error) {
 
 	logger := utils.NewLogger(os.Stdout)
 	pkg, err := c.parse(source, in, logger, ast.NewPackage("main"))
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	program, _, err := pkg.Compile(c.packages, logger, c.params)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	if len(program.Inputs) != 2 {
-		return nil, nil,
+		return nil, nil, nil,
 			fmt.Errorf("invalid program for 2-party computation: %d parties",
 				len(program.Inputs))
 	}
 	input, err := program.Inputs[0].Parse(inputFlag)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	fmt.Printf(" + In1: %s\n", program.Inputs[0])
@@ -117,7 +125,7 @@ func (c *Compiler) stream(conn *p2p.Conn, source string, in io.Reader,
 	fmt.Printf(" - Out: %s\n", program.Outputs)
 	fmt.Printf(" -  In: %s\n", inputFlag)
 
-	return program.StreamCircuit(conn, c.params, input)
+	return program.StreamCircuitWithTiming(conn, c.params, input)
 }
 
 func (c *Compiler) parse(source string, in io.Reader, logger *utils.Logger,
@@ -10,6 +10,7 @@ import (
 	"math"
 	"math/big"
 	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/markkurossi/mpc/compiler/utils"
@@ -300,3 +301,298 @@ func main(a, b uint64) uint64 {
 		}
 	}
 }
+
+func TestInlineDepth(t *testing.T) {
+	code := `package main
+func main(a, b uint8) uint8 {
+    return count(a)
+}
+func count(n uint8) uint8 {
+    if n == 0 {
+        return 0
+    }
+    return 1 + count(n-1)
+}
+`
+	_, _, err := NewCompiler(&utils.Params{
+		MaxInlineDepth: 8,
+	}).Compile(code)
+	if err == nil {
+		t.Fatalf("unbounded recursion not detected")
+	}
+	expected := "maximum inline depth 8 exceeded: main -> count (9 times)"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("got error '%s', expected '%s'", err, expected)
+	}
+}
+
+func TestInlineDepthMutual(t *testing.T) {
+	code := `package main
+func main(a, b uint8) bool {
+    return even(a)
+}
+func even(n uint8) bool {
+    if n == 0 {
+        return true
+    }
+    return odd(n-1)
+}
+func odd(n uint8) bool {
+    if n == 0 {
+        return false
+    }
+    return even(n-1)
+}
+`
+	_, _, err := NewCompiler(&utils.Params{
+		MaxInlineDepth: 8,
+	}).Compile(code)
+	if err == nil {
+		t.Fatalf("unbounded recursion not detected")
+	}
+	expected := "maximum inline depth 8 exceeded: " +
+		"main -> (even -> odd) (4 times) -> even"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("got error '%s', expected '%s'", err, expected)
+	}
+}
+
+func TestReadOnlyVariable(t *testing.T) {
+	code := `package main
+var table = [4]uint8{1, 2, 3, 4}
+func main(a, b uint8) uint8 {
+    table[0] = a
+    return table[b]
+}
+`
+	_, _, err := NewCompiler(&utils.Params{}).Compile(code)
+	if err == nil {
+		t.Fatalf("assignment to package variable not detected")
+	}
+	expected := "cannot assign to table (read-only)"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("got error '%s', expected '%s'", err, expected)
+	}
+}
+
+var genericErrorTests = []struct {
+	code     string
+	expected string
+}{
+	{
+		code: `package main
+func Max[T Ordered](a, b T) T {
+    if a > b {
+        return a
+    }
+    return b
+}
+func main(a, b bool) bool {
+    return Max(a, b)
+}
+`,
+		expected: "bool1 does not satisfy T Ordered",
+	},
+	{
+		code: `package main
+func Zero[T any]() T {
+    var t T
+    return t
+}
+func main(a, b uint8) uint8 {
+    return Zero()
+}
+`,
+		expected: "in call to Zero, cannot infer T",
+	},
+	{
+		code: `package main
+func Add(a, b uint8) uint8 {
+    return a + b
+}
+func main(a, b uint8) uint8 {
+    return Add[uint8](a, b)
+}
+`,
+		expected: "Add is not a generic function",
+	},
+}
+
+func TestGenericErrors(t *testing.T) {
+	for idx, test := range genericErrorTests {
+		_, _, err := NewCompiler(&utils.Params{}).Compile(test.code)
+		if err == nil {
+			t.Errorf("test %d: error not detected", idx)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("test %d: got error '%s', expected '%s'",
+				idx, err, test.expected)
+		}
+	}
+}
+
+func TestConstEvalTypes(t *testing.T) {
+	// Each function is called with a constant argument, evaluated at
+	// compile time, and with a variable argument, compiled into the
+	// circuit. The results must be equal.
+	code := `package main
+func main(a, b uint8) (uint8, uint8, bool, bool, int8, int8, uint8, uint8,
+    int8, int8, uint8, uint8) {
+    return add(10), add(a), less(10), less(a), sign(10), sign(int8(a)),
+        neg(10), neg(a), twice(10), twice(int8(a)), pair(10), pair(a)
+}
+func add(x uint8) uint8 {
+    y := x + 250
+    return y >> 2
+}
+func less(x uint8) bool {
+    if x+250 < 100 {
+        return true
+    }
+    return false
+}
+func sign(x int8) int8 {
+    if x*20 < 0 {
+        return -1
+    }
+    return 1
+}
+func neg(x uint8) uint8 {
+    return -x >> 4
+}
+func twice(x int8) int8 {
+    var y = x * 2
+    y = y * 7
+    return y / 3
+}
+func pair(x uint8) uint8 {
+    s, d := split(x)
+    return (s + d) / 2
+}
+func split(x uint8) (uint8, uint8) {
+    return x * 30, x - 20
+}
+`
+	circ, _, err := NewCompiler(&utils.Params{}).Compile(code)
+	if err != nil {
+		t.Fatalf("compile failed: %s", err)
+	}
+	results, err := circ.Compute([]*big.Int{big.NewInt(10), big.NewInt(0)})
+	if err != nil {
+		t.Fatalf("compute failed: %s", err)
+	}
+	for i := 0; i < len(results); i += 2 {
+		if results[i].Cmp(results[i+1]) != 0 {
+			t.Errorf("result %d: constant %v, variable %v",
+				i/2, results[i], results[i+1])
+		}
+	}
+}
+
+var conversionErrorTests = []struct {
+	code     string
+	expected string
+}{
+	{
+		code: `package main
+func main(a, b uint8) int8 {
+    return int8(510)
+}
+`,
+		expected: "constant 510 overflows int8",
+	},
+	{
+		code: `package main
+func main(a, b uint8) uint8 {
+    return uint8(200) + uint8(100)
+}
+`,
+		expected: "constant 300 overflows uint8",
+	},
+	{
+		code: `package main
+func main(a, b uint8) uint8 {
+    return -uint8(1)
+}
+`,
+		expected: "constant -1 overflows uint8",
+	},
+	{
+		code: `package main
+var offset uint8 = 0x100 + 3
+func main(a, b uint8) uint8 {
+    return a + offset
+}
+`,
+		expected: "constant 259 overflows uint8",
+	},
+}
+
+func TestConversionErrors(t *testing.T) {
+	for idx, test := range conversionErrorTests {
+		_, _, err := NewCompiler(&utils.Params{}).Compile(test.code)
+		if err == nil {
+			t.Errorf("test %d: error not detected", idx)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("test %d: got error '%s', expected '%s'",
+				idx, err, test.expected)
+		}
+	}
+}
+
+var assignErrorTests = []struct {
+	code     string
+	expected string
+}{
+	{
+		code: `package main
+func main(a, b uint8) [4]uint8 {
+    var arr [4]uint8
+    arr[1] = 300
+    return arr
+}
+`,
+		expected: "constant 300 overflows uint8",
+	},
+	{
+		code: `package main
+func main(a, b uint8) [4]uint8 {
+    var arr [4]uint8
+    arr[a] = -1
+    return arr
+}
+`,
+		expected: "constant -1 overflows uint8",
+	},
+	{
+		code: `package main
+type Pair struct {
+    A uint8
+    B int8
+}
+func main(a, b uint8) Pair {
+    var p Pair
+    p.B = 128
+    return p
+}
+`,
+		expected: "constant 128 overflows int8",
+	},
+}
+
+func TestAssignErrors(t *testing.T) {
+	for idx, test := range assignErrorTests {
+		_, _, err := NewCompiler(&utils.Params{}).Compile(test.code)
+		if err == nil {
+			t.Errorf("test %d: error not detected", idx)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("test %d: got error '%s', expected '%s'",
+				idx, err, test.expected)
+		}
+	}
+}
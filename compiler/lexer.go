@@ -12,6 +12,7 @@ import (
 	"io"
 	"math"
 	"math/big"
+	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
@@ -40,6 +41,11 @@ const (
 	TSymConst
 	TSymType
 	TSymFor
+	TSymSwitch
+	TSymCase
+	TSymDefault
+	TSymBreak
+	TSymContinue
 	TAssign
 	TDefAssign
 	TMult
@@ -81,58 +87,63 @@ const (
 )
 
 var tokenTypes = map[TokenType]string{
-	TIdentifier: "identifier",
-	TConstant:   "constant",
-	TSymbol:     "symbol",
-	TSymPackage: "package",
-	TSymImport:  "import",
-	TSymFunc:    "func",
-	TSymIf:      "if",
-	TSymElse:    "else",
-	TSymReturn:  "return",
-	TSymStruct:  "struct",
-	TSymVar:     "var",
-	TSymConst:   "const",
-	TSymType:    "type",
-	TSymFor:     "for",
-	TAssign:     "=",
-	TDefAssign:  ":=",
-	TMult:       "*",
-	TMultEq:     "*=",
-	TDiv:        "/",
-	TDivEq:      "/=",
-	TMod:        "%",
-	TLshift:     "<<",
-	TRshift:     ">>",
-	TPlus:       "+",
-	TPlusPlus:   "++",
-	TPlusEq:     "+=",
-	TMinus:      "-",
-	TMinusMinus: "--",
-	TMinusEq:    "-=",
-	TLParen:     "(",
-	TRParen:     ")",
-	TLBrace:     "{",
-	TRBrace:     "}",
-	TLBracket:   "[",
-	TRBracket:   "]",
-	TComma:      ",",
-	TSemicolon:  ";",
-	TColon:      ":",
-	TDot:        ".",
-	TLt:         "<",
-	TLe:         "<=",
-	TGt:         ">",
-	TGe:         ">=",
-	TEq:         "==",
-	TNeq:        "!=",
-	TAnd:        "&&",
-	TOr:         "||",
-	TNot:        "!",
-	TBitAnd:     "&",
-	TBitOr:      "|",
-	TBitXor:     "^",
-	TBitClear:   "&^",
+	TIdentifier:  "identifier",
+	TConstant:    "constant",
+	TSymbol:      "symbol",
+	TSymPackage:  "package",
+	TSymImport:   "import",
+	TSymFunc:     "func",
+	TSymIf:       "if",
+	TSymElse:     "else",
+	TSymReturn:   "return",
+	TSymStruct:   "struct",
+	TSymVar:      "var",
+	TSymConst:    "const",
+	TSymType:     "type",
+	TSymFor:      "for",
+	TSymSwitch:   "switch",
+	TSymCase:     "case",
+	TSymDefault:  "default",
+	TSymBreak:    "break",
+	TSymContinue: "continue",
+	TAssign:      "=",
+	TDefAssign:   ":=",
+	TMult:        "*",
+	TMultEq:      "*=",
+	TDiv:         "/",
+	TDivEq:       "/=",
+	TMod:         "%",
+	TLshift:      "<<",
+	TRshift:      ">>",
+	TPlus:        "+",
+	TPlusPlus:    "++",
+	TPlusEq:      "+=",
+	TMinus:       "-",
+	TMinusMinus:  "--",
+	TMinusEq:     "-=",
+	TLParen:      "(",
+	TRParen:      ")",
+	TLBrace:      "{",
+	TRBrace:      "}",
+	TLBracket:    "[",
+	TRBracket:    "]",
+	TComma:       ",",
+	TSemicolon:   ";",
+	TColon:       ":",
+	TDot:         ".",
+	TLt:          "<",
+	TLe:          "<=",
+	TGt:          ">",
+	TGe:          ">=",
+	TEq:          "==",
+	TNeq:         "!=",
+	TAnd:         "&&",
+	TOr:          "||",
+	TNot:         "!",
+	TBitAnd:      "&",
+	TBitOr:       "|",
+	TBitXor:      "^",
+	TBitClear:    "&^",
 }
 
 func (t TokenType) String() string {
@@ -175,19 +186,26 @@ func (t TokenType) BinaryType() ast.BinaryType {
 }
 
 var symbols = map[string]TokenType{
-	"import":  TSymImport,
-	"const":   TSymConst,
-	"type":    TSymType,
-	"for":     TSymFor,
-	"else":    TSymElse,
-	"func":    TSymFunc,
-	"if":      TSymIf,
-	"package": TSymPackage,
-	"return":  TSymReturn,
-	"struct":  TSymStruct,
-	"var":     TSymVar,
+	"import":   TSymImport,
+	"const":    TSymConst,
+	"type":     TSymType,
+	"for":      TSymFor,
+	"else":     TSymElse,
+	"func":     TSymFunc,
+	"if":       TSymIf,
+	"package":  TSymPackage,
+	"return":   TSymReturn,
+	"struct":   TSymStruct,
+	"switch":   TSymSwitch,
+	"case":     TSymCase,
+	"default":  TSymDefault,
+	"var":      TSymVar,
+	"break":    TSymBreak,
+	"continue": TSymContinue,
 }
 
+var reFixedPrefix = regexp.MustCompile(`^fixed[0-9]+$`)
+
 // Token specifies an input token.
 type Token struct {
 	Type     TokenType
@@ -210,6 +228,8 @@ func (t *Token) String() string {
 			str = fmt.Sprintf("%v", val)
 		case *big.Int:
 			str = val.String()
+		case float64:
+			str = strconv.FormatFloat(val, 'g', -1, 64)
 		default:
 			str = t.Type.String()
 		}
@@ -230,6 +250,12 @@ type Lexer struct {
 	unreadPoint utils.Point
 	history     map[int][]rune
 	lastComment Comment
+	last        TokenType
+	// Semicolons enables automatic semicolon insertion. When
+	// enabled, a newline after an identifier, a constant, or a
+	// closing parenthesis, bracket, or brace is returned as a
+	// TSemicolon token.
+	Semicolons bool
 }
 
 // NewLexer creates a new lexer for the input.
@@ -309,6 +335,9 @@ func (l *Lexer) Get() (*Token, error) {
 		if err != nil {
 			return nil, err
 		}
+		if r == '\n' && l.insertSemicolon() {
+			return l.Token(TSemicolon), nil
+		}
 		if unicode.IsSpace(r) {
 			continue
 		}
@@ -389,6 +418,29 @@ func (l *Lexer) Get() (*Token, error) {
 					comment = append(comment, r)
 				}
 				l.commentLine(string(comment), start)
+				if l.insertSemicolon() {
+					return l.Token(TSemicolon), nil
+				}
+				continue
+
+			case '*':
+				var comment []rune
+				for {
+					r, _, err := l.ReadRune()
+					if err != nil {
+						if err == io.EOF {
+							return nil, fmt.Errorf("%s: unterminated comment",
+								l.tokenStart)
+						}
+						return nil, err
+					}
+					if r == '/' && len(comment) > 0 &&
+						comment[len(comment)-1] == '*' {
+						break
+					}
+					comment = append(comment, r)
+				}
+				l.commentBlock(string(comment[:len(comment)-1]))
 				continue
 
 			case '=':
@@ -573,6 +625,14 @@ func (l *Lexer) Get() (*Token, error) {
 					}
 					symbol += string(r)
 				}
+				if reFixedPrefix.MatchString(symbol) {
+					// Fixed-point type name fixedN.F.
+					var err error
+					symbol, err = l.readFixedType(symbol)
+					if err != nil {
+						return nil, err
+					}
+				}
 				tt, ok := symbols[symbol]
 				if ok {
 					return l.Token(tt), nil
@@ -635,19 +695,19 @@ func (l *Lexer) Get() (*Token, error) {
 					input += string(r)
 				}
 
-				for {
-					r, _, err := l.ReadRune()
-					if err != nil {
-						if err != io.EOF {
-							return nil, err
-						}
-						break
-					}
-					if !unicode.IsDigit(r) {
-						l.UnreadRune()
-						break
-					}
-					input += string(r)
+				input, err := l.readDigits(input)
+				if err != nil {
+					return nil, err
+				}
+				r, _, err := l.ReadRune()
+				if err != nil && err != io.EOF {
+					return nil, err
+				}
+				if err == nil && (r == '.' || r == 'e' || r == 'E') {
+					return l.readFloat(input, r)
+				}
+				if err == nil {
+					l.UnreadRune()
 				}
 				u, err := strconv.ParseUint(input, 10, 64)
 				if err != nil {
@@ -672,14 +732,115 @@ func (l *Lexer) Get() (*Token, error) {
 	}
 }
 
+// readDigits reads decimal digits from the input and appends them to
+// the argument string.
+func (l *Lexer) readDigits(input string) (string, error) {
+	for {
+		r, _, err := l.ReadRune()
+		if err != nil {
+			if err != io.EOF {
+				return "", err
+			}
+			return input, nil
+		}
+		if !unicode.IsDigit(r) {
+			l.UnreadRune()
+			return input, nil
+		}
+		input += string(r)
+	}
+}
+
+// readFixedType reads the fractional bits of the fixed-point type
+// name fixedN.F. The input holds the fixedN prefix.
+func (l *Lexer) readFixedType(input string) (string, error) {
+	r, _, err := l.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			return "", err
+		}
+		return input, nil
+	}
+	if r != '.' {
+		l.UnreadRune()
+		return input, nil
+	}
+	frac, err := l.readDigits("")
+	if err != nil {
+		return "", err
+	}
+	if len(frac) == 0 {
+		return "", fmt.Errorf("%s: malformed fixed-point type '%s.'",
+			l.point, input)
+	}
+	return input + "." + frac, nil
+}
+
+// readFloat reads the fraction and exponent of a floating point
+// constant. The input holds the integer part and r is the first
+// character after it.
+func (l *Lexer) readFloat(input string, r rune) (*Token, error) {
+	var err error
+	if r == '.' {
+		input += "."
+		input, err = l.readDigits(input)
+		if err != nil {
+			return nil, err
+		}
+		r, _, err = l.ReadRune()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if err == nil && r != 'e' && r != 'E' {
+			l.UnreadRune()
+		}
+	}
+	if err == nil && (r == 'e' || r == 'E') {
+		input += "e"
+		r, _, err = l.ReadRune()
+		if err != nil {
+			return nil, err
+		}
+		if r == '+' || r == '-' {
+			input += string(r)
+		} else {
+			l.UnreadRune()
+		}
+		input, err = l.readDigits(input)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f, err := strconv.ParseFloat(input, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s: malformed constant '%s'", l.point, input)
+	}
+	token := l.Token(TConstant)
+	token.ConstVal = f
+	return token, nil
+}
+
 // Unget pushes the token back to the lexer input stream. The next
 // call to Get will return it.
 func (l *Lexer) Unget(t *Token) {
 	l.ungot = t
 }
 
+func (l *Lexer) insertSemicolon() bool {
+	if !l.Semicolons {
+		return false
+	}
+	switch l.last {
+	case TIdentifier, TConstant, TRParen, TRBracket, TRBrace:
+		return true
+	default:
+		return false
+	}
+}
+
 // Token returns a new token for the argument token type.
 func (l *Lexer) Token(t TokenType) *Token {
+	l.last = t
 	return &Token{
 		Type: t,
 		From: l.tokenStart,
@@ -713,6 +874,35 @@ func (l *Lexer) commentLine(line string, loc utils.Point) {
 	}
 }
 
+func (l *Lexer) commentBlock(block string) {
+	var lines []string
+	for _, line := range strings.Split(block, "\n") {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	l.lastComment = Comment{
+		Start: l.tokenStart,
+		End:   l.point,
+		Lines: lines,
+	}
+}
+
+// Comment returns the last comment if it starts between the argument
+// locations.
+func (l *Lexer) Comment(from, to utils.Point) (Comment, bool) {
+	c := l.lastComment
+	if c.Empty() || before(c.Start, from) || !before(c.Start, to) {
+		return Comment{}, false
+	}
+	return c, true
+}
+
+func before(a, b utils.Point) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Col < b.Col
+}
+
 // Annotations returns the annotations immediately preceding the
 // current lexer location.
 func (l *Lexer) Annotations(loc utils.Point) ast.Annotations {
@@ -38,3 +38,60 @@ func TestLexer(t *testing.T) {
 		}
 	}
 }
+
+func TestLexerFloat(t *testing.T) {
+	lexer := NewLexer("{data}",
+		bytes.NewReader([]byte("1.5 2. 3e2 4.5e-1 0.25 6E+1 7")))
+	for _, expected := range []interface{}{
+		1.5, 2.0, 300.0, 0.45, 0.25, 60.0, int32(7),
+	} {
+		token, err := lexer.Get()
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if token.Type != TConstant || token.ConstVal != expected {
+			t.Errorf("got %v (%T), expected %v (%T)", token.ConstVal,
+				token.ConstVal, expected, expected)
+		}
+	}
+}
+
+func TestLexerFixed(t *testing.T) {
+	lexer := NewLexer("{data}",
+		bytes.NewReader([]byte("fixed32.16 fixed8 fixed16.8(x) fixedx.y")))
+	for _, expected := range []string{
+		"fixed32.16", "fixed8", "fixed16.8", "(", "x", ")", "fixedx", ".",
+		"y",
+	} {
+		token, err := lexer.Get()
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if token.String() != expected {
+			t.Errorf("got %v, expected %v", token, expected)
+		}
+	}
+}
+
+func TestLexerComment(t *testing.T) {
+	lexer := NewLexer("{data}",
+		bytes.NewReader([]byte("a /* max 8 */ b /* c\n * d */ e /**/ f")))
+	for _, expected := range []string{"a", "b", "e", "f"} {
+		token, err := lexer.Get()
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if token.String() != expected {
+			t.Errorf("got %v, expected %v", token, expected)
+		}
+	}
+	lexer = NewLexer("{data}", bytes.NewReader([]byte("a /* b")))
+	_, err := lexer.Get()
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	_, err = lexer.Get()
+	if err == nil {
+		t.Errorf("unterminated comment not detected")
+	}
+}
@@ -0,0 +1,92 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package compiler
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/markkurossi/mpc/circuit"
+	"github.com/markkurossi/mpc/p2p"
+)
+
+// Stream compiles the input program and uses the streaming mode to
+// garble and stream the circuit to the evaluator node.
+func (c *Compiler) Stream(conn *p2p.Conn, data string, input []string) (
+	circuit.IO, []*big.Int, error) {
+	outputs, result, _, err := c.stream(conn, "{data}",
+		strings.NewReader(data), input)
+	return outputs, result, err
+}
+
+// RunLocal compiles the input program and runs the garbler and the
+// evaluator in the current process. The inputs are parsed from the
+// argument strings as with the garbled command. The function returns
+// the program outputs and the results of both parties.
+func (c *Compiler) RunLocal(data string, garblerInput, evaluatorInput []string) (
+	circuit.IO, []*big.Int, []*big.Int, error) {
+
+	circ, _, err := c.Compile(data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(circ.Inputs) != 2 {
+		return nil, nil, nil,
+			fmt.Errorf("invalid program for 2-party computation: %d parties",
+				len(circ.Inputs))
+	}
+	gInput, err := circ.Inputs[0].Parse(garblerInput)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	eInput, err := circ.Inputs[1].Parse(evaluatorInput)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	gResult, eResult, err := circuit.RunLocal(circ, gInput, eInput,
+		c.params.Verbose)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return circ.Outputs, gResult, eResult, nil
+}
+
+// StreamLocal compiles the input program and runs the streaming
+// garbler and evaluator in the current process. The function returns
+// the program outputs and the results of both parties.
+func (c *Compiler) StreamLocal(data string, garblerInput,
+	evaluatorInput []string) (circuit.IO, []*big.Int, []*big.Int, error) {
+
+	gConn, eConn, gRaw, eRaw := p2p.Pipe()
+
+	var gResult []*big.Int
+	done := make(chan error)
+
+	go func() {
+		var err error
+		_, gResult, err = c.Stream(gConn, data, garblerInput)
+		if err != nil {
+			// Unblock the evaluator.
+			gRaw.Close()
+		}
+		done <- err
+	}()
+
+	outputs, eResult, eErr := circuit.StreamEvaluator(eConn, evaluatorInput,
+		c.params.Verbose)
+	eRaw.Close()
+
+	gErr := <-done
+	gRaw.Close()
+
+	err := circuit.LocalError(gErr, eErr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return outputs, gResult, eResult, nil
+}
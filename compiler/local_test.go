@@ -0,0 +1,263 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package compiler
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/markkurossi/mpc/compiler/utils"
+)
+
+var localTests = []struct {
+	Name string
+	Code string
+}{
+	{
+		Name: "add",
+		Code: `
+package main
+func main(a, b uint8) uint8 {
+    return a + b
+}
+`,
+	},
+	{
+		Name: "mux",
+		Code: `
+package main
+func main(a, b uint8) (uint8, bool) {
+    if a < b {
+        return b - a, true
+    }
+    return a - b, false
+}
+`,
+	},
+	{
+		Name: "unary",
+		Code: `
+package main
+func main(a, b uint8) (uint8, uint8, bool) {
+    return -a, ^b, !(a < b)
+}
+`,
+	},
+	{
+		Name: "switch",
+		Code: `
+package main
+func main(a, b uint8) uint8 {
+    switch a & 3 {
+    case 0:
+        return b
+    case 1, 2:
+        return b + 1
+    default:
+        return 42
+    }
+}
+`,
+	},
+	{
+		Name: "array",
+		Code: `
+package main
+func main(a, b uint8) uint8 {
+    var t [4]uint8
+    t[a & 3] = b
+    t[1] = t[1] + 1
+    return t[b & 3]
+}
+`,
+	},
+	{
+		Name: "lvalue",
+		Code: `
+package main
+func main(a, b uint8) uint16 {
+    var r uint16
+    if a > b {
+        r[8:16] = a
+    } else {
+        r[0:8] = b
+    }
+    return r
+}
+`,
+	},
+	{
+		Name: "composite",
+		Code: `
+package main
+type Pair struct {
+    A uint8
+    B uint8
+}
+func main(a, b uint8) [2]Pair {
+    p := Pair{B: a}
+    arr := [2]Pair{p, {b, 7}}
+    return arr
+}
+`,
+	},
+	{
+		Name: "methods",
+		Code: `
+package main
+type Pair struct {
+    A uint8
+    B uint8
+}
+func (p Pair) Max() uint8 {
+    if p.A > p.B {
+        return p.A
+    }
+    return p.B
+}
+func main(a, b uint8) uint8 {
+    p := Pair{a, b}
+    return p.Max()
+}
+`,
+	},
+	{
+		Name: "float",
+		Code: `
+package main
+func main(a, b uint8) uint8 {
+    x := float32(a) / 4
+    y := float64(b) * -0.5
+    if x > float32(y) {
+        return uint8(x + 0.5)
+    }
+    return uint8(int32(y - 1))
+}
+`,
+	},
+	{
+		Name: "fixed",
+		Code: `
+package main
+func main(a, b uint8) uint8 {
+    x := fixed24.8(a) / 3
+    y := fixed24.8(b) * 0.75
+    return uint8(x - y + 100)
+}
+`,
+	},
+	{
+		Name: "signed",
+		Code: `
+package main
+func main(a, b uint8) uint8 {
+    x := int8(a)
+    y := int8(b)
+    if x < y {
+        return uint8(x / (y | 1))
+    }
+    return uint8(x % -7)
+}
+`,
+	},
+	{
+		Name: "shift",
+		Code: `
+package main
+func main(a, b uint8) uint8 {
+    return a<<b ^ uint8(int8(a)>>(b&7)) ^ rotl(a, b) ^ rotr(a, 3)
+}
+`,
+	},
+	{
+		Name: "convert",
+		Code: `
+package main
+func main(a, b uint8) (int16, uint16, uint4, bool) {
+    return int16(int8(a)), uint16(int8(b)), uint4(a), bool(uint1(b))
+}
+`,
+	},
+	{
+		Name: "break",
+		Code: `
+package main
+func main(a, b uint8) uint8 {
+    var r uint8
+    for i := 0; i < 8; i++ {
+        if (a>>i)&1 == 0 {
+            continue
+        }
+        if r >= b {
+            break
+        }
+        r++
+    }
+    return r
+}
+`,
+	},
+	{
+		Name: "constants",
+		Code: `
+package main
+func main(a, b uint8) (uint8, uint8, uint8, uint8, uint8, uint8, int8, int8) {
+    x := int8(b)
+    return a * 5, a / 5, a % 5, a * 7, a / 7, a % 7, x / -3, x / 5
+}
+`,
+	},
+}
+
+func TestRunLocal(t *testing.T) {
+	for _, test := range localTests {
+		circ, _, err := NewCompiler(&utils.Params{}).Compile(test.Code)
+		if err != nil {
+			t.Fatalf("%s: compile failed: %s", test.Name, err)
+		}
+		for _, in := range [][2]int{{0, 0}, {3, 200}, {200, 3}, {255, 255}} {
+			gIn := []string{fmt.Sprintf("%d", in[0])}
+			eIn := []string{fmt.Sprintf("%d", in[1])}
+
+			g, err := circ.Inputs[0].Parse(gIn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, err := circ.Inputs[1].Parse(eIn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := circ.Compute([]*big.Int{g, e})
+			if err != nil {
+				t.Fatalf("%s: compute failed: %s", test.Name, err)
+			}
+
+			for _, stream := range []bool{false, true} {
+				c := NewCompiler(&utils.Params{})
+				var gResult, eResult []*big.Int
+				if stream {
+					_, gResult, eResult, err = c.StreamLocal(test.Code, gIn,
+						eIn)
+				} else {
+					_, gResult, eResult, err = c.RunLocal(test.Code, gIn, eIn)
+				}
+				if err != nil {
+					t.Fatalf("%s: stream=%v: %s", test.Name, stream, err)
+				}
+				for i := range expected {
+					if expected[i].Cmp(gResult[i]) != 0 ||
+						expected[i].Cmp(eResult[i]) != 0 {
+						t.Errorf("%s(%v): stream=%v: result %d: "+
+							"got %s/%s, expected %s", test.Name, in, stream,
+							i, gResult[i], eResult[i], expected[i])
+					}
+				}
+			}
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/markkurossi/mpc/compiler/ast"
@@ -22,6 +23,10 @@ type Parser struct {
 	logger   *utils.Logger
 	lexer    *Lexer
 	pkg      *ast.Package
+	// noCompositeLit disables composite literals in the headers of
+	// the if, for, and switch statements where the '{' token starts
+	// the statement block.
+	noCompositeLit bool
 }
 
 // NewParser creates a new parser.
@@ -192,6 +197,9 @@ func (p *Parser) parseToplevel() error {
 	case TSymConst:
 		return p.parseConst()
 
+	case TSymVar:
+		return p.parseVar()
+
 	case TSymType:
 		return p.parseTypeDecl()
 
@@ -200,6 +208,14 @@ func (p *Parser) parseToplevel() error {
 		if err != nil {
 			return err
 		}
+		if f.Receiver != nil {
+			_, ok := p.pkg.Methods[f.Name]
+			if ok {
+				return p.errf(f.Loc, "method %s already declared", f.Name)
+			}
+			p.pkg.Methods[f.Name] = f
+			return nil
+		}
 		_, ok := p.pkg.Functions[f.Name]
 		if ok {
 			return p.errf(f.Loc, "function %s already defined", f.Name)
@@ -220,10 +236,19 @@ func (p *Parser) parseConst() error {
 	}
 	switch token.Type {
 	case TIdentifier:
-		return p.parseConstDef(token)
+		_, err = p.parseConstDef(token, 0, nil)
+		return err
 
 	case TLParen:
-		for {
+		// The constant specifications are separated by semicolons
+		// or newlines.
+		p.lexer.Semicolons = true
+		defer func() {
+			p.lexer.Semicolons = false
+		}()
+
+		var prev *ast.ConstantDef
+		for iota := 0; ; iota++ {
 			t, err := p.lexer.Get()
 			if err != nil {
 				return err
@@ -231,10 +256,21 @@ func (p *Parser) parseConst() error {
 			if t.Type == TRParen {
 				return nil
 			}
-			err = p.parseConstDef(t)
+			prev, err = p.parseConstDef(t, iota, prev)
 			if err != nil {
 				return err
 			}
+			t, err = p.lexer.Get()
+			if err != nil {
+				return err
+			}
+			switch t.Type {
+			case TSemicolon:
+			case TRParen:
+				return nil
+			default:
+				return p.errUnexpected(t, TSemicolon)
+			}
 		}
 
 	default:
@@ -242,40 +278,147 @@ func (p *Parser) parseConst() error {
 	}
 }
 
-func (p *Parser) parseConstDef(token *Token) error {
+// parseConstDef parses the constant definition with the iota value
+// iota. A constant without type and init value in a const
+// declaration block repeats the type and init value of the previous
+// constant prev. Such a constant specification ends with ';', newline,
+// or ')'.
+func (p *Parser) parseConstDef(token *Token, iota int,
+	prev *ast.ConstantDef) (*ast.ConstantDef, error) {
+
 	if token.Type != TIdentifier {
-		return p.errf(token.From, "unexpected token '%s'", token.Type)
+		return nil, p.errf(token.From, "unexpected token '%s'", token.Type)
 	}
 
 	t, err := p.lexer.Get()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var constType *ast.TypeInfo
-	if t.Type != TAssign {
+	var value ast.AST
+
+	if t.Type == TSemicolon || t.Type == TRParen {
 		p.lexer.Unget(t)
-		constType, err = p.parseType()
-		if err != nil {
-			return err
+		if prev == nil {
+			return nil, p.errf(token.From,
+				"missing init expr for const declaration")
+		}
+		constType = prev.Type
+		value = prev.Init
+	} else {
+		if t.Type != TAssign {
+			p.lexer.Unget(t)
+			constType, err = p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			_, err = p.needToken(TAssign)
+			if err != nil {
+				return nil, err
+			}
 		}
-		_, err = p.needToken(TAssign)
+		value, err = p.parseExpr()
 		if err != nil {
-			return nil
+			return nil, err
 		}
 	}
-	value, err := p.parseExprPrimary()
-	if err != nil {
-		return err
-	}
 
-	p.pkg.Constants = append(p.pkg.Constants, &ast.ConstantDef{
+	def := &ast.ConstantDef{
 		Loc:  token.From,
 		Name: token.StrVal,
 		Type: constType,
 		Init: value,
-	})
+		Iota: iota,
+	}
+	p.pkg.Constants = append(p.pkg.Constants, def)
 
-	return nil
+	return def, nil
+}
+
+func (p *Parser) parseVar() error {
+	token, err := p.lexer.Get()
+	if err != nil {
+		return err
+	}
+	if token.Type != TLParen {
+		p.lexer.Unget(token)
+		def, err := p.parseVarDef(token.From)
+		if err != nil {
+			return err
+		}
+		p.pkg.Variables = append(p.pkg.Variables, def)
+		return nil
+	}
+	for {
+		t, err := p.lexer.Get()
+		if err != nil {
+			return err
+		}
+		if t.Type == TRParen {
+			return nil
+		}
+		p.lexer.Unget(t)
+		def, err := p.parseVarDef(t.From)
+		if err != nil {
+			return err
+		}
+		p.pkg.Variables = append(p.pkg.Variables, def)
+	}
+}
+
+// parseVarDef parses the variable definition. The variable type can
+// be omitted if the definition has an initializer.
+func (p *Parser) parseVarDef(loc utils.Point) (*ast.VariableDef, error) {
+	var names []string
+	for {
+		tName, err := p.needToken(TIdentifier)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, tName.StrVal)
+		t, err := p.lexer.Get()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type != TComma {
+			p.lexer.Unget(t)
+			break
+		}
+	}
+
+	t, err := p.lexer.Get()
+	if err != nil {
+		return nil, err
+	}
+	var typeInfo *ast.TypeInfo
+	if t.Type != TAssign {
+		p.lexer.Unget(t)
+		typeInfo, err = p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		t, err = p.lexer.Get()
+		if err != nil {
+			return nil, err
+		}
+	}
+	var expr ast.AST
+	if t.Type == TAssign {
+		// Initializer.
+		expr, err = p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		p.lexer.Unget(t)
+	}
+
+	return &ast.VariableDef{
+		Loc:   loc,
+		Names: names,
+		Type:  typeInfo,
+		Init:  expr,
+	}, nil
 }
 
 func (p *Parser) parseTypeDecl() error {
@@ -355,16 +498,76 @@ func (p *Parser) parseTypeDecl() error {
 		return nil
 
 	default:
-		return p.errf(t.From, "unexpected token '%s'", t.Type)
+		p.lexer.Unget(t)
+		ti, err := p.parseType()
+		if err != nil {
+			return err
+		}
+		typeInfo := &ast.TypeInfo{
+			Type:      ast.TypeDefined,
+			TypeName:  name.StrVal,
+			AliasType: ti,
+		}
+		p.pkg.Types = append(p.pkg.Types, typeInfo)
+		return nil
 	}
 }
 
 func (p *Parser) parseFunc(annotations ast.Annotations) (*ast.Func, error) {
+	t, err := p.lexer.Get()
+	if err != nil {
+		return nil, err
+	}
+	// Method receiver.
+	var receiver *ast.Variable
+	if t.Type == TLParen {
+		t, err = p.needToken(TIdentifier)
+		if err != nil {
+			return nil, err
+		}
+		typeInfo, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if typeInfo.Type != ast.TypeName || len(typeInfo.Name.Package) > 0 {
+			return nil, p.errf(t.From, "invalid receiver type %s", typeInfo)
+		}
+		receiver = &ast.Variable{
+			Loc:  t.From,
+			Name: t.StrVal,
+			Type: typeInfo,
+		}
+		_, err = p.needToken(TRParen)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		p.lexer.Unget(t)
+	}
+
 	name, err := p.needToken(TIdentifier)
 	if err != nil {
 		return nil, err
 	}
 
+	// Type parameters.
+	var typeParams []*ast.TypeParam
+	t, err = p.lexer.Get()
+	if err != nil {
+		return nil, err
+	}
+	if t.Type == TLBracket {
+		if receiver != nil {
+			return nil, p.errf(t.From, "methods cannot have type parameters")
+		}
+		typeParams, err = p.parseTypeParams()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		p.lexer.Unget(t)
+	}
+
 	_, err = p.needToken(TLParen)
 	if err != nil {
 		return nil, err
@@ -374,7 +577,7 @@ func (p *Parser) parseFunc(annotations ast.Annotations) (*ast.Func, error) {
 
 	var arguments []*ast.Variable
 
-	t, err := p.lexer.Get()
+	t, err = p.lexer.Get()
 	if err != nil {
 		return nil, err
 	}
@@ -488,83 +691,187 @@ func (p *Parser) parseFunc(annotations ast.Annotations) (*ast.Func, error) {
 		return nil, err
 	}
 
-	return ast.NewFunc(name.From, name.StrVal, arguments, returnValues, body,
-		annotations), nil
+	if receiver == nil {
+		f := ast.NewFunc(name.From, name.StrVal, arguments, returnValues,
+			body, annotations)
+		f.TypeParams = typeParams
+		return f, nil
+	}
+	f := ast.NewFunc(name.From,
+		fmt.Sprintf("%s.%s", receiver.Type.Name.Name, name.StrVal),
+		arguments, returnValues, body, annotations)
+	f.Receiver = receiver
+	return f, nil
 }
 
-func (p *Parser) parseBlock() (ast.List, error) {
-	var result ast.List
+// TypeParameters = "[" TypeParamList [ "," ] "]" .
+// TypeParamList  = TypeParamDecl { "," TypeParamDecl } .
+// TypeParamDecl  = IdentifierList TypeConstraint .
+// TypeConstraint = Type { "|" Type } .
+//
+// The opening '[' token is already consumed.
+func (p *Parser) parseTypeParams() ([]*ast.TypeParam, error) {
+	var result []*ast.TypeParam
 	for {
 		t, err := p.lexer.Get()
 		if err != nil {
 			return nil, err
 		}
-		if t.Type == TRBrace {
+		if t.Type == TRBracket && len(result) > 0 {
 			break
 		}
-		p.lexer.Unget(t)
+		if t.Type != TIdentifier {
+			return nil, p.errUnexpected(t, TIdentifier)
+		}
+		param := &ast.TypeParam{
+			Loc:  t.From,
+			Name: t.StrVal,
+		}
+		for _, tp := range result {
+			if tp.Name == param.Name {
+				return nil, p.errf(t.From, "%s redeclared", param.Name)
+			}
+		}
 
-		ast, err := p.parseStatement()
+		t, err = p.lexer.Get()
 		if err != nil {
 			return nil, err
 		}
-		result = append(result, ast)
-	}
-	return result, nil
-}
+		if t.Type == TComma {
+			result = append(result, param)
+			continue
+		}
+		p.lexer.Unget(t)
 
-func (p *Parser) parseStatement() (ast.AST, error) {
-	tStmt, err := p.lexer.Get()
-	if err != nil {
-		return nil, err
-	}
-	switch tStmt.Type {
-	case TSymVar:
-		var names []string
+		// Constraint.
+		var constraint []*ast.TypeInfo
 		for {
-			tName, err := p.needToken(TIdentifier)
+			typeInfo, err := p.parseType()
 			if err != nil {
 				return nil, err
 			}
-			names = append(names, tName.StrVal)
-			t, err := p.lexer.Get()
+			constraint = append(constraint, typeInfo)
+
+			t, err = p.lexer.Get()
 			if err != nil {
 				return nil, err
 			}
-			if t.Type != TComma {
+			if t.Type != TBitOr {
 				p.lexer.Unget(t)
 				break
 			}
 		}
+		param.Constraint = constraint
+
+		// All unconstrained parameters get this constraint.
+		for i := len(result) - 1; i >= 0; i-- {
+			if result[i].Constraint != nil {
+				break
+			}
+			result[i].Constraint = constraint
+		}
+		result = append(result, param)
+
+		t, err = p.lexer.Get()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type == TRBracket {
+			break
+		}
+		if t.Type != TComma {
+			return nil, p.errUnexpected(t, TComma)
+		}
+	}
+	if result[len(result)-1].Constraint == nil {
+		return nil, p.errf(result[len(result)-1].Loc,
+			"missing type constraint")
+	}
+	return result, nil
+}
+
+// TypeArgs = "[" TypeList [ "," ] "]" .
+// TypeList = Type { "," Type } .
+//
+// The opening '[' token and the optional first type argument are
+// already consumed.
+func (p *Parser) parseTypeArgs(first *ast.TypeInfo) ([]*ast.TypeInfo, error) {
+	var result []*ast.TypeInfo
+	if first != nil {
+		result = append(result, first)
+	}
+	for {
+		t, err := p.lexer.Get()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type == TRBracket && len(result) > 0 {
+			break
+		}
+		p.lexer.Unget(t)
+
 		typeInfo, err := p.parseType()
 		if err != nil {
 			return nil, err
 		}
+		result = append(result, typeInfo)
 
+		t, err = p.lexer.Get()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type == TRBracket {
+			break
+		}
+		if t.Type != TComma {
+			return nil, p.errUnexpected(t, TComma)
+		}
+	}
+
+	// Generic functions must be called.
+	t, err := p.needToken(TLParen)
+	if err != nil {
+		return nil, err
+	}
+	p.lexer.Unget(t)
+
+	return result, nil
+}
+
+func (p *Parser) parseBlock() (ast.List, error) {
+	var result ast.List
+	for {
 		t, err := p.lexer.Get()
 		if err != nil {
 			return nil, err
 		}
-		var expr ast.AST
-		if t.Type == TAssign {
-			// Initializer.
-			expr, err = p.parseExpr()
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			p.lexer.Unget(t)
+		if t.Type == TRBrace {
+			break
 		}
+		p.lexer.Unget(t)
 
-		return &ast.VariableDef{
-			Loc:   tStmt.From,
-			Names: names,
-			Type:  typeInfo,
-			Init:  expr,
-		}, nil
+		ast, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ast)
+	}
+	return result, nil
+}
+
+func (p *Parser) parseStatement() (ast.AST, error) {
+	tStmt, err := p.lexer.Get()
+	if err != nil {
+		return nil, err
+	}
+	switch tStmt.Type {
+	case TSymVar:
+		return p.parseVarDef(tStmt.From)
 
 	case TSymIf:
+		p.noCompositeLit = true
 		expr, err := p.parseExpr()
+		p.noCompositeLit = false
 		if err != nil {
 			return nil, err
 		}
@@ -600,6 +907,9 @@ func (p *Parser) parseStatement() (ast.AST, error) {
 			False: b2,
 		}, nil
 
+	case TSymSwitch:
+		return p.parseSwitch(tStmt)
+
 	case TSymReturn:
 		var exprs []ast.AST
 		if p.sameLine(tStmt.To) {
@@ -629,28 +939,26 @@ func (p *Parser) parseStatement() (ast.AST, error) {
 			Exprs: exprs,
 		}, nil
 
+	case TSymBreak:
+		return &ast.Break{
+			Loc: tStmt.From,
+		}, nil
+
+	case TSymContinue:
+		return &ast.Continue{
+			Loc: tStmt.From,
+		}, nil
+
 	case TSymFor:
-		init, err := p.parseStatement()
-		if err != nil {
-			return nil, err
-		}
-		_, err = p.needToken(TSemicolon)
+		init, cond, inc, err := p.parseForClause()
 		if err != nil {
 			return nil, err
 		}
-		cond, err := p.parseExpr()
+		t, err := p.needToken(TLBrace)
 		if err != nil {
 			return nil, err
 		}
-		_, err = p.needToken(TSemicolon)
-		if err != nil {
-			return nil, err
-		}
-		inc, err := p.parseStatement()
-		if err != nil {
-			return nil, err
-		}
-		_, err = p.needToken(TLBrace)
+		max, err := p.parseLoopBound(tStmt.From, t.From)
 		if err != nil {
 			return nil, err
 		}
@@ -663,6 +971,7 @@ func (p *Parser) parseStatement() (ast.AST, error) {
 			Init: init,
 			Cond: cond,
 			Inc:  inc,
+			Max:  max,
 			Body: body,
 		}, nil
 
@@ -751,6 +1060,133 @@ func (p *Parser) parseStatement() (ast.AST, error) {
 	}
 }
 
+func (p *Parser) parseForClause() (init, cond, inc ast.AST, err error) {
+	p.noCompositeLit = true
+	defer func() {
+		p.noCompositeLit = false
+	}()
+
+	init, err = p.parseStatement()
+	if err != nil {
+		return
+	}
+	_, err = p.needToken(TSemicolon)
+	if err != nil {
+		return
+	}
+	cond, err = p.parseExpr()
+	if err != nil {
+		return
+	}
+	_, err = p.needToken(TSemicolon)
+	if err != nil {
+		return
+	}
+	inc, err = p.parseStatement()
+	return
+}
+
+// parseLoopBound parses the optional maximum iteration count
+// annotation /* max N */ of the for loop between the argument
+// locations. The function returns 0 if the loop is not annotated.
+func (p *Parser) parseLoopBound(from, to utils.Point) (int, error) {
+	c, ok := p.lexer.Comment(from, to)
+	if !ok || len(c.Lines) != 1 {
+		return 0, nil
+	}
+	fields := strings.Fields(c.Lines[0])
+	if len(fields) == 0 || fields[0] != "max" {
+		return 0, nil
+	}
+	if len(fields) != 2 {
+		return 0, p.errf(c.Start, "malformed loop bound '%s'", c.Lines[0])
+	}
+	max, err := strconv.Atoi(fields[1])
+	if err != nil || max <= 0 {
+		return 0, p.errf(c.Start, "invalid loop bound '%s'", fields[1])
+	}
+	return max, nil
+}
+
+func (p *Parser) parseSwitch(tSwitch *Token) (ast.AST, error) {
+	result := &ast.Switch{
+		Loc: tSwitch.From,
+	}
+	t, err := p.lexer.Get()
+	if err != nil {
+		return nil, err
+	}
+	if t.Type != TLBrace {
+		p.lexer.Unget(t)
+		p.noCompositeLit = true
+		result.Expr, err = p.parseExpr()
+		p.noCompositeLit = false
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.needToken(TLBrace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var hasDefault bool
+	for {
+		t, err := p.lexer.Get()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type == TRBrace {
+			break
+		}
+		c := &ast.Case{
+			Loc: t.From,
+		}
+		switch t.Type {
+		case TSymCase:
+			c.Exprs, err = p.parseExprList()
+			if err != nil {
+				return nil, err
+			}
+
+		case TSymDefault:
+			if hasDefault {
+				return nil, p.errf(t.From, "multiple defaults in switch")
+			}
+			hasDefault = true
+
+		default:
+			return nil, p.errf(t.From,
+				"unexpected token '%s': expected 'case', 'default' or '}'", t)
+		}
+		_, err = p.needToken(TColon)
+		if err != nil {
+			return nil, err
+		}
+
+		// Case body.
+		for {
+			t, err := p.lexer.Get()
+			if err != nil {
+				return nil, err
+			}
+			p.lexer.Unget(t)
+			if t.Type == TSymCase || t.Type == TSymDefault ||
+				t.Type == TRBrace {
+				break
+			}
+			stmt, err := p.parseStatement()
+			if err != nil {
+				return nil, err
+			}
+			c.Body = append(c.Body, stmt)
+		}
+		result.Cases = append(result.Cases, c)
+	}
+
+	return result, nil
+}
+
 func (p *Parser) parseExprList() ([]ast.AST, error) {
 	var list []ast.AST
 
@@ -899,7 +1335,7 @@ func (p *Parser) parseExprAdditive() (ast.AST, error) {
 }
 
 func (p *Parser) parseExprMultiplicative() (ast.AST, error) {
-	left, err := p.parseExprPrimary()
+	left, err := p.parseExprUnary()
 	if err != nil {
 		return nil, err
 	}
@@ -910,7 +1346,7 @@ func (p *Parser) parseExprMultiplicative() (ast.AST, error) {
 		}
 		switch t.Type {
 		case TMult, TDiv, TMod, TLshift, TRshift, TBitAnd, TBitClear:
-			right, err := p.parseExprPrimary()
+			right, err := p.parseExprUnary()
 			if err != nil {
 				return nil, err
 			}
@@ -928,6 +1364,39 @@ func (p *Parser) parseExprMultiplicative() (ast.AST, error) {
 	}
 }
 
+// UnaryExpr = PrimaryExpr | unary_op UnaryExpr .
+// unary_op  = "+" | "-" | "!" | "^" .
+
+func (p *Parser) parseExprUnary() (ast.AST, error) {
+	t, err := p.lexer.Get()
+	if err != nil {
+		return nil, err
+	}
+	var op ast.UnaryType
+	switch t.Type {
+	case TPlus:
+		op = ast.UnaryPlus
+	case TMinus:
+		op = ast.UnaryMinus
+	case TNot:
+		op = ast.UnaryNot
+	case TBitXor:
+		op = ast.UnaryBnot
+	default:
+		p.lexer.Unget(t)
+		return p.parseExprPrimary()
+	}
+	expr, err := p.parseExprUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Unary{
+		Loc:  t.From,
+		Op:   op,
+		Expr: expr,
+	}, nil
+}
+
 // PrimaryExpr =
 //     Operand |
 //     Conversion |
@@ -950,6 +1419,7 @@ func (p *Parser) parseExprPrimary() (ast.AST, error) {
 	if err != nil {
 		return nil, err
 	}
+	var typeArgs []*ast.TypeInfo
 
 	for {
 		t, err := p.lexer.Get()
@@ -962,7 +1432,15 @@ func (p *Parser) parseExprPrimary() (ast.AST, error) {
 		switch t.Type {
 		case TDot:
 			// Selector.
-			return nil, fmt.Errorf("Selector not implemented yet")
+			id, err := p.needToken(TIdentifier)
+			if err != nil {
+				return nil, err
+			}
+			primary = &ast.Selector{
+				Loc:  primary.Location(),
+				Expr: primary,
+				Name: id.StrVal,
+			}
 
 		case TLBracket:
 			var expr1, expr2 ast.AST
@@ -971,16 +1449,49 @@ func (p *Parser) parseExprPrimary() (ast.AST, error) {
 			if err != nil {
 				return nil, err
 			}
+			_, isRef := primary.(*ast.VariableRef)
+			if isRef && n.Type == TLBracket {
+				// Type arguments starting with an array type.
+				p.lexer.Unget(n)
+				typeArgs, err = p.parseTypeArgs(nil)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
 			if n.Type != TColon {
 				p.lexer.Unget(n)
 				expr1, err = p.parseExpr()
 				if err != nil {
 					return nil, err
 				}
-				_, err = p.needToken(TColon)
+				n, err = p.lexer.Get()
 				if err != nil {
 					return nil, err
 				}
+				ref, ok := expr1.(*ast.VariableRef)
+				if isRef && ok && n.Type == TComma {
+					// Type argument list.
+					typeArgs, err = p.parseTypeArgs(&ast.TypeInfo{
+						Type: ast.TypeName,
+						Name: ref.Name,
+					})
+					if err != nil {
+						return nil, err
+					}
+					continue
+				}
+				if n.Type == TRBracket {
+					primary = &ast.Index{
+						Loc:   primary.Location(),
+						Expr:  primary,
+						Index: expr1,
+					}
+					continue
+				}
+				if n.Type != TColon {
+					return nil, p.errUnexpected(n, TColon)
+				}
 			}
 			n, err = p.lexer.Get()
 			if err != nil {
@@ -1007,33 +1518,75 @@ func (p *Parser) parseExprPrimary() (ast.AST, error) {
 		case TLParen:
 			// Arguments.
 			var arguments []ast.AST
-			for {
-				expr, err := p.parseExpr()
-				if err != nil {
-					return nil, err
+			n, err := p.lexer.Get()
+			if err != nil {
+				return nil, err
+			}
+			if n.Type != TRParen {
+				p.lexer.Unget(n)
+				for {
+					expr, err := p.parseExpr()
+					if err != nil {
+						return nil, err
+					}
+					arguments = append(arguments, expr)
+
+					n, err := p.lexer.Get()
+					if err != nil {
+						return nil, err
+					}
+					if n.Type == TRParen {
+						break
+					} else if n.Type != TComma {
+						return nil, p.errf(n.From, "unexpected token %s", n)
+					}
 				}
-				arguments = append(arguments, expr)
-
-				n, err := p.lexer.Get()
-				if err != nil {
-					return nil, err
+			}
+			switch pr := primary.(type) {
+			case *ast.VariableRef:
+				primary = &ast.Call{
+					Loc:      primary.Location(),
+					Name:     pr.Name,
+					TypeArgs: typeArgs,
+					Exprs:    arguments,
 				}
-				if n.Type == TRParen {
-					break
-				} else if n.Type != TComma {
-					return nil, p.errf(n.From, "unexpected token %s", n)
+				typeArgs = nil
+
+			case *ast.Index:
+				// Generic function call with one type argument.
+				fn, ok := pr.Expr.(*ast.VariableRef)
+				ref, ok2 := pr.Index.(*ast.VariableRef)
+				if !ok || !ok2 {
+					return nil, p.errf(primary.Location(),
+						"non-function %s used as function", primary)
 				}
-			}
-			vr, ok := primary.(*ast.VariableRef)
-			if !ok {
+				primary = &ast.Call{
+					Loc:  primary.Location(),
+					Name: fn.Name,
+					TypeArgs: []*ast.TypeInfo{
+						{
+							Type: ast.TypeName,
+							Name: ref.Name,
+						},
+					},
+					Exprs: arguments,
+				}
+
+			case *ast.Selector:
+				// Method call.
+				primary = &ast.Call{
+					Loc:  primary.Location(),
+					Recv: pr.Expr,
+					Name: ast.Identifier{
+						Name: pr.Name,
+					},
+					Exprs: arguments,
+				}
+
+			default:
 				return nil, p.errf(primary.Location(),
 					"non-function %s used as function", primary)
 			}
-			return &ast.Call{
-				Loc:   primary.Location(),
-				Name:  vr.Name,
-				Exprs: arguments,
-			}, nil
 
 		default:
 			p.lexer.Unget(t)
@@ -1066,31 +1619,79 @@ func (p *Parser) parseOperand() (ast.AST, error) {
 		if err != nil {
 			return nil, err
 		}
+		var name ast.Identifier
 		if n.Type == TDot {
 			id, err := p.needToken(TIdentifier)
 			if err != nil {
 				return nil, err
 			}
 			// QualifiedIdent.
-			return &ast.VariableRef{
-				Loc: t.From,
-				Name: ast.Identifier{
-					Package: t.StrVal,
-					Name:    id.StrVal,
-				},
-			}, nil
+			name = ast.Identifier{
+				Package: t.StrVal,
+				Name:    id.StrVal,
+			}
+		} else {
+			// Identifier in current package.
+			p.lexer.Unget(n)
+			name = ast.Identifier{
+				Name: t.StrVal,
+			}
+		}
+		if !p.noCompositeLit {
+			n, err = p.lexer.Get()
+			if err != nil {
+				return nil, err
+			}
+			if n.Type == TLBrace {
+				return p.parseCompositeLit(t.From, &ast.TypeInfo{
+					Type: ast.TypeName,
+					Name: name,
+				})
+			}
+			p.lexer.Unget(n)
 		}
-		// Identifier in current package.
-		p.lexer.Unget(n)
 		return &ast.VariableRef{
-			Loc: t.From,
-			Name: ast.Identifier{
-				Name: t.StrVal,
-			},
+			Loc:  t.From,
+			Name: name,
 		}, nil
 
+	case TLBracket: // ArrayType LiteralValue | ArrayType "(" Expression ")"
+		p.lexer.Unget(t)
+		typeInfo, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		n, err := p.lexer.Get()
+		if err != nil {
+			return nil, err
+		}
+		if n.Type == TLParen {
+			// Conversion.
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			_, err = p.needToken(TRParen)
+			if err != nil {
+				return nil, err
+			}
+			return &ast.Call{
+				Loc:   t.From,
+				Type:  typeInfo,
+				Exprs: []ast.AST{expr},
+			}, nil
+		}
+		if n.Type != TLBrace {
+			p.lexer.Unget(n)
+			return nil, p.errUnexpected(n, TLBrace)
+		}
+		return p.parseCompositeLit(t.From, typeInfo)
+
 	case TLParen: // '(' Expression ')'
+		noCompositeLit := p.noCompositeLit
+		p.noCompositeLit = false
 		expr, err := p.parseExpr()
+		p.noCompositeLit = noCompositeLit
 		if err != nil {
 			return nil, err
 		}
@@ -1107,6 +1708,87 @@ func (p *Parser) parseOperand() (ast.AST, error) {
 	}
 }
 
+// LiteralValue = "{" [ ElementList [ "," ] ] "}" .
+// ElementList  = KeyedElement { "," KeyedElement } .
+// KeyedElement = [ Key ":" ] Element .
+// Key          = FieldName | Expression | LiteralValue .
+// Element      = Expression | LiteralValue .
+//
+// The opening '{' token is already consumed. The typeInfo is nil for
+// literal values with elided types.
+func (p *Parser) parseCompositeLit(loc utils.Point, typeInfo *ast.TypeInfo) (
+	ast.AST, error) {
+
+	noCompositeLit := p.noCompositeLit
+	p.noCompositeLit = false
+	defer func() {
+		p.noCompositeLit = noCompositeLit
+	}()
+
+	result := &ast.CompositeLit{
+		Loc:  loc,
+		Type: typeInfo,
+	}
+	for {
+		t, err := p.lexer.Get()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type == TRBrace {
+			break
+		}
+		p.lexer.Unget(t)
+
+		element, err := p.parseElement()
+		if err != nil {
+			return nil, err
+		}
+		t, err = p.lexer.Get()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type == TColon {
+			value, err := p.parseElement()
+			if err != nil {
+				return nil, err
+			}
+			result.Value = append(result.Value, ast.KeyedElement{
+				Key:     element,
+				Element: value,
+			})
+			t, err = p.lexer.Get()
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			result.Value = append(result.Value, ast.KeyedElement{
+				Element: element,
+			})
+		}
+		if t.Type == TRBrace {
+			break
+		}
+		if t.Type != TComma {
+			p.lexer.Unget(t)
+			return nil, p.errf(t.From,
+				"unexpected token '%s': expected ',' or '}'", t)
+		}
+	}
+	return result, nil
+}
+
+func (p *Parser) parseElement() (ast.AST, error) {
+	t, err := p.lexer.Get()
+	if err != nil {
+		return nil, err
+	}
+	if t.Type == TLBrace {
+		return p.parseCompositeLit(t.From, nil)
+	}
+	p.lexer.Unget(t)
+	return p.parseExpr()
+}
+
 // Type      = TypeName | TypeLit | "(" Type ")" .
 // TypeName  = identifier | QualifiedIdent .
 // TypeLit   = ArrayType | StructType | SliceType .
@@ -109,6 +109,19 @@ func max(a, b int4) int4 {
     }
     return v
 }
+`,
+	`
+package main
+func main(a, b int4) int4 {
+    var r int4
+    for i := 0; i < b; i++ /* max 8 */ {
+        if i == a {
+            break
+        }
+        r++
+    }
+    return r
+}
 `,
 }
 
@@ -11,6 +11,7 @@ import (
 
 	"github.com/markkurossi/mpc/circuit"
 	"github.com/markkurossi/mpc/compiler/circuits"
+	"github.com/markkurossi/mpc/compiler/types"
 	"github.com/markkurossi/mpc/compiler/utils"
 )
 
@@ -83,6 +84,7 @@ func (prog *Program) Circuit(cc *circuits.Compiler) error {
 			}
 			wires = append(wires, w)
 		}
+		extendConstants(instr, wires)
 		switch instr.Op {
 		case Iadd, Uadd:
 			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
@@ -104,42 +106,28 @@ func (prog *Program) Circuit(cc *circuits.Compiler) error {
 				return err
 			}
 
-		case Imult, Umult:
+		case Ineg, Uneg:
 			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
 			if err != nil {
 				return err
 			}
-			err = circuits.NewMultiplier(cc, cc.Params.CircMultArrayTreshold,
-				wires[0], wires[1], o)
+			err = circuits.NewNegator(cc, wires[0], o)
 			if err != nil {
 				return err
 			}
 
-		case Idiv, Udiv:
-			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
-			if err != nil {
-				return err
-			}
-
-			err = circuits.NewDivider(cc, wires[0], wires[1], o, nil)
-			if err != nil {
-				return err
-			}
-
-		case Imod, Umod:
-			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
-			if err != nil {
-				return err
-			}
-
-			err = circuits.NewDivider(cc, wires[0], wires[1], nil, o)
-			if err != nil {
-				return err
-			}
-
-		case Lshift:
+		case Lshift, Rshift, Srshift, Rotl, Rotr:
 			if !instr.In[1].Const {
-				return fmt.Errorf("%s: only constant shift supported", instr.Op)
+				// Barrel shifter for variable shift count.
+				o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
+				if err != nil {
+					return err
+				}
+				_, err = circuitGenerators[instr.Op](cc, instr, wires, o)
+				if err != nil {
+					return err
+				}
+				break
 			}
 			var count int
 			switch val := instr.In[1].ConstValue.(type) {
@@ -155,56 +143,69 @@ func (prog *Program) Circuit(cc *circuits.Compiler) error {
 			}
 			o := make([]*circuits.Wire, instr.Out.Type.Bits)
 			for bit := 0; bit < len(o); bit++ {
-				var w *circuits.Wire
-				if bit-count >= 0 && bit-count < len(wires[0]) {
-					w = wires[0][bit-count]
+				src := shiftSource(instr.Op, bit, count, len(o), len(wires[0]))
+				if src < 0 {
+					o[bit] = cc.ZeroWire()
 				} else {
-					w = cc.ZeroWire()
+					o[bit] = wires[0][src]
 				}
-				o[bit] = w
 			}
 			err := prog.SetWires(instr.Out.String(), o)
 			if err != nil {
 				return err
 			}
 
-		case Rshift:
+		case Slice:
 			if !instr.In[1].Const {
-				return fmt.Errorf("%s: only constant shift supported", instr.Op)
+				return fmt.Errorf("%s: only constant index supported", instr.Op)
 			}
-			var count int
+			var from int
 			switch val := instr.In[1].ConstValue.(type) {
 			case int32:
-				count = int(val)
+				from = int(val)
 			default:
 				return fmt.Errorf("%s: unsupported index type %T",
 					instr.Op, val)
 			}
-			if count < 0 {
-				return fmt.Errorf("%s: negative shift count %d",
-					instr.Op, count)
+
+			if !instr.In[2].Const {
+				return fmt.Errorf("%s: only constant index supported", instr.Op)
+			}
+			var to int
+			switch val := instr.In[2].ConstValue.(type) {
+			case int32:
+				to = int(val)
+			default:
+				return fmt.Errorf("%s: unsupported index type %T",
+					instr.Op, val)
+			}
+			if from >= to {
+				return fmt.Errorf("%s: bounds out of range [%d:%d]",
+					instr.Op, from, to)
 			}
 			o := make([]*circuits.Wire, instr.Out.Type.Bits)
-			for bit := 0; bit < len(o); bit++ {
+
+			for bit := from; bit < to; bit++ {
 				var w *circuits.Wire
-				if bit+count < len(wires[0]) {
-					w = wires[0][bit+count]
+				if bit < len(wires[0]) {
+					w = wires[0][bit]
 				} else {
 					w = cc.ZeroWire()
 				}
-				o[bit] = w
+				o[bit-from] = w
 			}
 			err := prog.SetWires(instr.Out.String(), o)
 			if err != nil {
 				return err
 			}
 
-		case Slice:
-			if !instr.In[1].Const {
+		case Amov:
+			// v arr from to o
+			if !instr.In[2].Const {
 				return fmt.Errorf("%s: only constant index supported", instr.Op)
 			}
 			var from int
-			switch val := instr.In[1].ConstValue.(type) {
+			switch val := instr.In[2].ConstValue.(type) {
 			case int32:
 				from = int(val)
 			default:
@@ -212,93 +213,116 @@ func (prog *Program) Circuit(cc *circuits.Compiler) error {
 					instr.Op, val)
 			}
 
-			if !instr.In[2].Const {
+			if !instr.In[3].Const {
 				return fmt.Errorf("%s: only constant index supported", instr.Op)
 			}
 			var to int
-			switch val := instr.In[2].ConstValue.(type) {
+			switch val := instr.In[3].ConstValue.(type) {
 			case int32:
 				to = int(val)
 			default:
 				return fmt.Errorf("%s: unsupported index type %T",
 					instr.Op, val)
 			}
-			if from >= to {
+			if from < 0 || from >= to || to > instr.Out.Type.Bits {
 				return fmt.Errorf("%s: bounds out of range [%d:%d]",
 					instr.Op, from, to)
 			}
 			o := make([]*circuits.Wire, instr.Out.Type.Bits)
 
-			for bit := from; bit < to; bit++ {
+			for bit := 0; bit < instr.Out.Type.Bits; bit++ {
 				var w *circuits.Wire
-				if bit < len(wires[0]) {
-					w = wires[0][bit]
+				if bit >= from && bit < to {
+					if bit-from < len(wires[0]) {
+						w = wires[0][bit-from]
+					} else {
+						w = cc.ZeroWire()
+					}
 				} else {
-					w = cc.ZeroWire()
+					if bit < len(wires[1]) {
+						w = wires[1][bit]
+					} else {
+						w = cc.ZeroWire()
+					}
 				}
-				o[bit-from] = w
+				o[bit] = w
 			}
 			err := prog.SetWires(instr.Out.String(), o)
 			if err != nil {
 				return err
 			}
 
-		case Ilt, Ult:
+		case Aget:
 			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
 			if err != nil {
 				return err
 			}
-			err = circuits.NewLtComparator(cc, wires[0], wires[1], o)
+			err = circuits.NewIndex(cc, instr.In[0].Type.ArraySize,
+				instr.In[1].Type.Type == types.Int, wires[0], wires[1], o)
 			if err != nil {
 				return err
 			}
 
-		case Ile, Ule:
+		case Aset:
 			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
 			if err != nil {
 				return err
 			}
-			err = circuits.NewLeComparator(cc, wires[0], wires[1], o)
+			err = circuits.NewIndexSet(cc, instr.In[0].Type.ArraySize,
+				instr.In[1].Type.Type == types.Int, wires[0], wires[1],
+				wires[2], o)
 			if err != nil {
 				return err
 			}
 
-		case Igt, Ugt:
+		case Ult:
 			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
 			if err != nil {
 				return err
 			}
-			err = circuits.NewGtComparator(cc, wires[0], wires[1], o)
+			err = circuits.NewLtComparator(cc, wires[0], wires[1], o)
 			if err != nil {
 				return err
 			}
 
-		case Ige, Uge:
+		case Ule:
 			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
 			if err != nil {
 				return err
 			}
-			err = circuits.NewGeComparator(cc, wires[0], wires[1], o)
+			err = circuits.NewLeComparator(cc, wires[0], wires[1], o)
 			if err != nil {
 				return err
 			}
 
-		case Eq:
+		case Ugt:
 			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
 			if err != nil {
 				return err
 			}
-			err = circuits.NewEqComparator(cc, wires[0], wires[1], o)
+			err = circuits.NewGtComparator(cc, wires[0], wires[1], o)
 			if err != nil {
 				return err
 			}
 
-		case Neq:
+		case Uge:
 			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
 			if err != nil {
 				return err
 			}
-			err = circuits.NewNeqComparator(cc, wires[0], wires[1], o)
+			err = circuits.NewGeComparator(cc, wires[0], wires[1], o)
+			if err != nil {
+				return err
+			}
+
+		case Imult, Umult, Udiv, Umod, Idiv, Imod, Ilt, Ile, Igt, Ige,
+			Eq, Neq, Fadd, Fsub, Fneg, Fmult, Fdiv, Flt, Fle, Fgt, Fge,
+			Itof, Utof, Ftoi, Ftof, Xmult, Xdiv, Itox, Utox, Xtoi, Xtox:
+			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
+			if err != nil {
+				return err
+			}
+			_, err = circuitGenerators[instr.Op](cc, instr, wires, o)
 			if err != nil {
 				return err
 			}
@@ -363,6 +387,16 @@ func (prog *Program) Circuit(cc *circuits.Compiler) error {
 				return err
 			}
 
+		case Not:
+			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
+			if err != nil {
+				return err
+			}
+			err = circuits.NewLogicalNOT(cc, wires[0], o)
+			if err != nil {
+				return err
+			}
+
 		case Band:
 			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
 			if err != nil {
@@ -403,13 +437,25 @@ func (prog *Program) Circuit(cc *circuits.Compiler) error {
 				return err
 			}
 
-		case Mov:
+		case Bnot:
+			o, err := prog.Wires(instr.Out.String(), instr.Out.Type.Bits)
+			if err != nil {
+				return err
+			}
+			err = circuits.NewBinaryNOT(cc, wires[0], o)
+			if err != nil {
+				return err
+			}
+
+		case Mov, Smov:
 			o := make([]*circuits.Wire, instr.Out.Type.Bits)
 
 			for bit := 0; bit < instr.Out.Type.Bits; bit++ {
 				var w *circuits.Wire
 				if bit < len(wires[0]) {
 					w = wires[0][bit]
+				} else if instr.Op == Smov {
+					w = wires[0][len(wires[0])-1]
 				} else {
 					w = cc.ZeroWire()
 				}
@@ -511,7 +557,7 @@ func (prog *Program) Circuit(cc *circuits.Compiler) error {
 			if err != nil {
 				return err
 			}
-			err = instr.Builtin(cc, wires[0], wires[1], o)
+			err = instr.Builtin(cc, wires, o)
 			if err != nil {
 				return err
 			}
@@ -525,3 +571,30 @@ func (prog *Program) Circuit(cc *circuits.Compiler) error {
 
 	return nil
 }
+
+// shiftSource returns the input bit for the output bit of the shift
+// and rotate operations with constant count. The bits specifies the
+// number of output bits and in the number of input bits. The function
+// returns -1 if the output bit is zero.
+func shiftSource(op Operand, bit, count, bits, in int) int {
+	var src int
+	switch op {
+	case Lshift:
+		src = bit - count
+	case Rshift:
+		src = bit + count
+	case Srshift:
+		src = bit + count
+		if src >= in {
+			src = in - 1
+		}
+	case Rotl:
+		src = ((bit-count)%bits + bits) % bits
+	case Rotr:
+		src = (bit + count) % bits
+	}
+	if src < 0 || src >= in {
+		return -1
+	}
+	return src
+}
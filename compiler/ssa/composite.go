@@ -0,0 +1,116 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package ssa
+
+import (
+	"fmt"
+
+	"github.com/markkurossi/mpc/compiler/types"
+)
+
+// CompositeValue implements constant values of struct and array
+// types. The Elements hold the constant values of the struct fields
+// or array elements. A nil element value is the zero value of its
+// type.
+type CompositeValue struct {
+	Type     types.Info
+	Elements []interface{}
+}
+
+func (c *CompositeValue) String() string {
+	result := c.Type.String() + "{"
+	for idx, el := range c.Elements {
+		if idx > 0 {
+			result += ","
+		}
+		if el == nil {
+			result += "0"
+		} else {
+			result += fmt.Sprintf("%v", el)
+		}
+	}
+	return result + "}"
+}
+
+// Element returns the type and value of the element idx. The function
+// returns the zero value of the element type for nil elements.
+func (c *CompositeValue) Element(idx int) (types.Info, interface{}) {
+	var t types.Info
+	switch c.Type.Type {
+	case types.Array:
+		t = *c.Type.ElementType
+	case types.Struct:
+		t = c.Type.Struct[idx].Type
+		t.Offset = 0
+	}
+	if c.Elements[idx] != nil {
+		return t, c.Elements[idx]
+	}
+	switch t.Type {
+	case types.Bool:
+		return t, false
+	case types.Struct, types.Array:
+		return t, NewCompositeValue(t)
+	default:
+		return t, int32(0)
+	}
+}
+
+// Bit tests if the argument bit is set in the value.
+func (c *CompositeValue) Bit(bit int) bool {
+	switch c.Type.Type {
+	case types.Array:
+		bits := c.Type.ElementType.Bits
+		idx := bit / bits
+		if idx >= len(c.Elements) {
+			return false
+		}
+		return elementBit(*c.Type.ElementType, c.Elements[idx], bit%bits)
+
+	case types.Struct:
+		for idx, f := range c.Type.Struct {
+			if bit >= f.Type.Offset && bit < f.Type.Offset+f.Type.Bits {
+				return elementBit(f.Type, c.Elements[idx], bit-f.Type.Offset)
+			}
+		}
+	}
+	return false
+}
+
+// elementBit tests if the argument bit is set in the element value
+// of type t. Numeric constants are converted to floating point and
+// fixed-point values for float and fixed elements.
+func elementBit(t types.Info, value interface{}, bit int) bool {
+	if t.Type == types.Float && value != nil {
+		f, err := FloatValue(value, t.Bits)
+		if err == nil {
+			value = f
+		}
+	}
+	if t.Type == types.Fixed && value != nil {
+		f, err := FixedValue(value, t)
+		if err == nil {
+			value = f
+		}
+	}
+	return constBit(value, bit)
+}
+
+// NewCompositeValue creates a zero composite value for the type t.
+func NewCompositeValue(t types.Info) *CompositeValue {
+	return &CompositeValue{
+		Type:     t,
+		Elements: make([]interface{}, compositeSize(t)),
+	}
+}
+
+func compositeSize(t types.Info) int {
+	if t.Type == types.Array {
+		return t.ArraySize
+	}
+	return len(t.Struct)
+}
@@ -9,6 +9,7 @@ package ssa
 import (
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 
 	"github.com/markkurossi/mpc/circuit"
@@ -27,10 +28,14 @@ const (
 	Isub
 	Usub
 	Fsub
+	Ineg
+	Uneg
+	Fneg
 	Bor
 	Bxor
 	Band
 	Bclr
+	Bnot
 	Bts
 	Btc
 	Imult
@@ -44,7 +49,13 @@ const (
 	Fmod
 	Lshift
 	Rshift
+	Srshift
+	Rotl
+	Rotr
 	Slice
+	Amov
+	Aget
+	Aset
 	Ilt
 	Ult
 	Flt
@@ -57,11 +68,23 @@ const (
 	Ige
 	Uge
 	Fge
+	Itof
+	Utof
+	Ftoi
+	Ftof
+	Xmult
+	Xdiv
+	Itox
+	Utox
+	Xtoi
+	Xtox
 	Eq
 	Neq
 	And
 	Or
+	Not
 	Mov
+	Smov
 	Phi
 	Ret
 	Circ
@@ -76,10 +99,14 @@ var operands = map[Operand]string{
 	Isub:    "isub",
 	Usub:    "usub",
 	Fsub:    "fsub",
+	Ineg:    "ineg",
+	Uneg:    "uneg",
+	Fneg:    "fneg",
 	Band:    "band",
 	Bor:     "bor",
 	Bxor:    "bxor",
 	Bclr:    "bclr",
+	Bnot:    "bnot",
 	Bts:     "bts",
 	Btc:     "btc",
 	Imult:   "imult",
@@ -93,7 +120,13 @@ var operands = map[Operand]string{
 	Fmod:    "fmod",
 	Lshift:  "lshift",
 	Rshift:  "rshift",
+	Srshift: "srshift",
+	Rotl:    "rotl",
+	Rotr:    "rotr",
 	Slice:   "slice",
+	Amov:    "amov",
+	Aget:    "aget",
+	Aset:    "aset",
 	Ilt:     "ilt",
 	Ult:     "ult",
 	Flt:     "flt",
@@ -106,11 +139,23 @@ var operands = map[Operand]string{
 	Ige:     "ige",
 	Uge:     "uge",
 	Fge:     "fge",
+	Itof:    "itof",
+	Utof:    "utof",
+	Ftoi:    "ftoi",
+	Ftof:    "ftof",
+	Xmult:   "xmult",
+	Xdiv:    "xdiv",
+	Itox:    "itox",
+	Utox:    "utox",
+	Xtoi:    "xtoi",
+	Xtox:    "xtox",
 	Eq:      "eq",
 	Neq:     "neq",
 	And:     "and",
 	Or:      "or",
+	Not:     "not",
 	Mov:     "mov",
+	Smov:    "smov",
 	Phi:     "phi",
 	Ret:     "ret",
 	Circ:    "circ",
@@ -138,21 +183,22 @@ func (op Operand) String() string {
 
 // Instr implements SSA assembly instruction.
 type Instr struct {
-	Op      Operand
-	In      []Variable
-	Out     *Variable
-	Label   *Block
-	Circ    *circuit.Circuit
-	Builtin circuits.Builtin
-	GC      string
-	Ret     []Variable
+	Op          Operand
+	In          []Variable
+	Out         *Variable
+	Label       *Block
+	Circ        *circuit.Circuit
+	Builtin     circuits.Builtin
+	BuiltinName string
+	GC          string
+	Ret         []Variable
 }
 
 // NewAddInstr creates a new addition instruction based on the type t.
 func NewAddInstr(t types.Info, l, r, o Variable) (Instr, error) {
 	var op Operand
 	switch t.Type {
-	case types.Int:
+	case types.Int, types.Fixed:
 		op = Iadd
 	case types.Uint:
 		op = Uadd
@@ -174,7 +220,7 @@ func NewAddInstr(t types.Info, l, r, o Variable) (Instr, error) {
 func NewSubInstr(t types.Info, l, r, o Variable) (Instr, error) {
 	var op Operand
 	switch t.Type {
-	case types.Int:
+	case types.Int, types.Fixed:
 		op = Isub
 	case types.Uint:
 		op = Usub
@@ -190,6 +236,27 @@ func NewSubInstr(t types.Info, l, r, o Variable) (Instr, error) {
 	}, nil
 }
 
+// NewNegInstr creates a new negation instruction based on the type
+// t.
+func NewNegInstr(t types.Info, v, o Variable) (Instr, error) {
+	var op Operand
+	switch t.Type {
+	case types.Int, types.Fixed:
+		op = Ineg
+	case types.Uint:
+		op = Uneg
+	case types.Float:
+		op = Fneg
+	default:
+		return Instr{}, fmt.Errorf("Invalid type %s for negation", t)
+	}
+	return Instr{
+		Op:  op,
+		In:  []Variable{v},
+		Out: &o,
+	}, nil
+}
+
 // NewMultInstr creates a new multiplication instruction based on the
 // type t.
 func NewMultInstr(t types.Info, l, r, o Variable) (Instr, error) {
@@ -201,6 +268,8 @@ func NewMultInstr(t types.Info, l, r, o Variable) (Instr, error) {
 		op = Umult
 	case types.Float:
 		op = Fmult
+	case types.Fixed:
+		op = Xmult
 	default:
 		return Instr{}, fmt.Errorf("Invalid type %s for multiplication", t)
 	}
@@ -221,6 +290,8 @@ func NewDivInstr(t types.Info, l, r, o Variable) (Instr, error) {
 		op = Udiv
 	case types.Float:
 		op = Fdiv
+	case types.Fixed:
+		op = Xdiv
 	default:
 		return Instr{}, fmt.Errorf("Invalid type %s for division", t)
 	}
@@ -260,10 +331,33 @@ func NewLshiftInstr(l, r, o Variable) Instr {
 	}
 }
 
-// NewRshiftInstr creates a new Rshift instruction.
+// NewRshiftInstr creates a new Rshift instruction. The instruction
+// is an arithmetic Srshift for signed integer values.
 func NewRshiftInstr(l, r, o Variable) Instr {
+	op := Rshift
+	if l.Type.Type == types.Int {
+		op = Srshift
+	}
+	return Instr{
+		Op:  op,
+		In:  []Variable{l, r},
+		Out: &o,
+	}
+}
+
+// NewRotlInstr creates a new Rotl instruction.
+func NewRotlInstr(l, r, o Variable) Instr {
 	return Instr{
-		Op:  Rshift,
+		Op:  Rotl,
+		In:  []Variable{l, r},
+		Out: &o,
+	}
+}
+
+// NewRotrInstr creates a new Rotr instruction.
+func NewRotrInstr(l, r, o Variable) Instr {
+	return Instr{
+		Op:  Rotr,
 		In:  []Variable{l, r},
 		Out: &o,
 	}
@@ -278,11 +372,41 @@ func NewSliceInstr(v, from, to, o Variable) Instr {
 	}
 }
 
+// NewAmovInstr creates a new Amov instruction. The instruction sets
+// o to a copy of arr where the bits from-to are replaced with v.
+func NewAmovInstr(v, arr, from, to, o Variable) Instr {
+	return Instr{
+		Op:  Amov,
+		In:  []Variable{v, arr, from, to},
+		Out: &o,
+	}
+}
+
+// NewAgetInstr creates a new Aget instruction that reads the element
+// index of the array arr into o.
+func NewAgetInstr(arr, index, o Variable) Instr {
+	return Instr{
+		Op:  Aget,
+		In:  []Variable{arr, index},
+		Out: &o,
+	}
+}
+
+// NewAsetInstr creates a new Aset instruction. The instruction sets o
+// to a copy of arr where the element index is replaced with v.
+func NewAsetInstr(arr, index, v, o Variable) Instr {
+	return Instr{
+		Op:  Aset,
+		In:  []Variable{arr, index, v},
+		Out: &o,
+	}
+}
+
 // NewLtInstr creates a new less-than instruction based on the type t.
 func NewLtInstr(t types.Info, l, r, o Variable) (Instr, error) {
 	var op Operand
 	switch t.Type {
-	case types.Int:
+	case types.Int, types.Fixed:
 		op = Ilt
 	case types.Uint:
 		op = Ult
@@ -303,7 +427,7 @@ func NewLtInstr(t types.Info, l, r, o Variable) (Instr, error) {
 func NewLeInstr(t types.Info, l, r, o Variable) (Instr, error) {
 	var op Operand
 	switch t.Type {
-	case types.Int:
+	case types.Int, types.Fixed:
 		op = Ile
 	case types.Uint:
 		op = Ule
@@ -324,7 +448,7 @@ func NewLeInstr(t types.Info, l, r, o Variable) (Instr, error) {
 func NewGtInstr(t types.Info, l, r, o Variable) (Instr, error) {
 	var op Operand
 	switch t.Type {
-	case types.Int:
+	case types.Int, types.Fixed:
 		op = Igt
 	case types.Uint:
 		op = Ugt
@@ -345,7 +469,7 @@ func NewGtInstr(t types.Info, l, r, o Variable) (Instr, error) {
 func NewGeInstr(t types.Info, l, r, o Variable) (Instr, error) {
 	var op Operand
 	switch t.Type {
-	case types.Int:
+	case types.Int, types.Fixed:
 		op = Ige
 	case types.Uint:
 		op = Uge
@@ -361,6 +485,56 @@ func NewGeInstr(t types.Info, l, r, o Variable) (Instr, error) {
 	}, nil
 }
 
+// NewFloatConvInstr creates a new floating point conversion
+// instruction converting v to the type of o.
+func NewFloatConvInstr(v, o Variable) (Instr, error) {
+	var op Operand
+	switch {
+	case o.Type.Type == types.Float && v.Type.Type == types.Int:
+		op = Itof
+	case o.Type.Type == types.Float && v.Type.Type == types.Uint:
+		op = Utof
+	case o.Type.Type == types.Float && v.Type.Type == types.Float:
+		op = Ftof
+	case (o.Type.Type == types.Int || o.Type.Type == types.Uint) &&
+		v.Type.Type == types.Float:
+		op = Ftoi
+	default:
+		return Instr{}, fmt.Errorf("Invalid conversion from %s to %s",
+			v.Type, o.Type)
+	}
+	return Instr{
+		Op:  op,
+		In:  []Variable{v},
+		Out: &o,
+	}, nil
+}
+
+// NewFixedConvInstr creates a new fixed-point conversion instruction
+// converting v to the type of o.
+func NewFixedConvInstr(v, o Variable) (Instr, error) {
+	var op Operand
+	switch {
+	case o.Type.Type == types.Fixed && v.Type.Type == types.Int:
+		op = Itox
+	case o.Type.Type == types.Fixed && v.Type.Type == types.Uint:
+		op = Utox
+	case o.Type.Type == types.Fixed && v.Type.Type == types.Fixed:
+		op = Xtox
+	case (o.Type.Type == types.Int || o.Type.Type == types.Uint) &&
+		v.Type.Type == types.Fixed:
+		op = Xtoi
+	default:
+		return Instr{}, fmt.Errorf("Invalid conversion from %s to %s",
+			v.Type, o.Type)
+	}
+	return Instr{
+		Op:  op,
+		In:  []Variable{v},
+		Out: &o,
+	}, nil
+}
+
 // NewEqInstr creates a new Eq instruction.
 func NewEqInstr(l, r, o Variable) (Instr, error) {
 	return Instr{
@@ -397,6 +571,15 @@ func NewOrInstr(l, r, o Variable) (Instr, error) {
 	}, nil
 }
 
+// NewNotInstr creates a new Not instruction.
+func NewNotInstr(v, o Variable) (Instr, error) {
+	return Instr{
+		Op:  Not,
+		In:  []Variable{v},
+		Out: &o,
+	}, nil
+}
+
 // NewBandInstr creates a new Band instruction.
 func NewBandInstr(l, r, o Variable) (Instr, error) {
 	return Instr{
@@ -415,6 +598,15 @@ func NewBclrInstr(l, r, o Variable) (Instr, error) {
 	}, nil
 }
 
+// NewBnotInstr creates a new Bnot instruction.
+func NewBnotInstr(v, o Variable) (Instr, error) {
+	return Instr{
+		Op:  Bnot,
+		In:  []Variable{v},
+		Out: &o,
+	}, nil
+}
+
 // NewBorInstr creates a new Bor instruction.
 func NewBorInstr(l, r, o Variable) (Instr, error) {
 	return Instr{
@@ -442,6 +634,16 @@ func NewMovInstr(from, to Variable) Instr {
 	}
 }
 
+// NewSmovInstr creates a new Smov instruction. The instruction
+// sign-extends from to the size of to.
+func NewSmovInstr(from, to Variable) Instr {
+	return Instr{
+		Op:  Smov,
+		In:  []Variable{from},
+		Out: &to,
+	}
+}
+
 // NewPhiInstr creates a new Phi instruction.
 func NewPhiInstr(cond, l, r, v Variable) Instr {
 	return Instr{
@@ -470,13 +672,17 @@ func NewCircInstr(args []Variable, circ *circuit.Circuit,
 	}
 }
 
-// NewBuiltinInstr creates a new Builtin instruction.
-func NewBuiltinInstr(builtin circuits.Builtin, a, b, r Variable) Instr {
+// NewBuiltinInstr creates a new Builtin instruction. The name
+// identifies the builtin circuit in the instruction's string
+// representation.
+func NewBuiltinInstr(name string, builtin circuits.Builtin,
+	args []Variable, r Variable) Instr {
 	return Instr{
-		Op:      Builtin,
-		In:      []Variable{a, b},
-		Out:     &r,
-		Builtin: builtin,
+		Op:          Builtin,
+		In:          args,
+		Out:         &r,
+		Builtin:     builtin,
+		BuiltinName: name,
 	}
 }
 
@@ -499,6 +705,9 @@ func (i Instr) StringTyped() string {
 
 func (i Instr) string(maxLen int, typesOnly bool) string {
 	result := i.Op.String()
+	if len(i.BuiltinName) > 0 {
+		result += "." + i.BuiltinName
+	}
 
 	if len(i.In) == 0 && i.Out == nil && i.Label == nil && len(i.GC) == 0 {
 		return result
@@ -594,7 +803,14 @@ func (v *Variable) Value(block *Block, gen *Generator) Variable {
 
 // Bit tests if the argument bit is set in the variable.
 func (v *Variable) Bit(bit int) bool {
-	switch val := v.ConstValue.(type) {
+	return constBit(v.ConstValue, bit)
+}
+
+func constBit(value interface{}, bit int) bool {
+	switch val := value.(type) {
+	case nil:
+		return false
+
 	case bool:
 		if bit == 0 {
 			return val
@@ -607,12 +823,18 @@ func (v *Variable) Bit(bit int) bool {
 	case uint64:
 		return (val & (1 << bit)) != 0
 
+	case float32:
+		return (math.Float32bits(val) & (1 << bit)) != 0
+
+	case float64:
+		return (math.Float64bits(val) & (1 << bit)) != 0
+
 	case *big.Int:
-		if bit > val.BitLen() {
-			return false
-		}
 		return val.Bit(bit) != 0
 
+	case *FixedConst:
+		return val.Raw.Bit(bit) != 0
+
 	case string:
 		bytes := []byte(val)
 		idx := bit / 8
@@ -622,8 +844,12 @@ func (v *Variable) Bit(bit int) bool {
 		}
 		return bytes[idx]&(1<<mod) != 0
 
+	case *CompositeValue:
+		return val.Bit(bit)
+
 	default:
-		panic(fmt.Sprintf("Variable.Bit called for non const %v (%T)", v, val))
+		panic(fmt.Sprintf("Variable.Bit called for non const %v (%T)",
+			value, val))
 	}
 }
 
@@ -648,6 +874,84 @@ func (v Variable) TypeCompatible(o Variable) bool {
 	return v.Type.Equal(o.Type)
 }
 
+// FloatValue converts the numeric constant value to a floating point
+// value of the argument size.
+func FloatValue(value interface{}, bits int) (interface{}, error) {
+	var f float64
+	switch val := value.(type) {
+	case int32:
+		f = float64(val)
+	case int64:
+		f = float64(val)
+	case uint64:
+		f = float64(val)
+	case float32:
+		f = float64(val)
+	case float64:
+		f = val
+	case *big.Int:
+		f, _ = new(big.Float).SetInt(val).Float64()
+	case *FixedConst:
+		f, _ = circuit.FixedToRat(val.Raw, val.Type.Bits,
+			val.Type.Fraction).Float64()
+	default:
+		return nil, fmt.Errorf("invalid float constant %v (%T)", val, val)
+	}
+	switch bits {
+	case 32:
+		return float32(f), nil
+	case 64:
+		return f, nil
+	default:
+		return nil, fmt.Errorf("unsupported float size %d", bits)
+	}
+}
+
+// FixedConst implements fixed-point constant values. The Raw holds
+// the two's complement encoding of the value scaled by 2^Fraction.
+type FixedConst struct {
+	Type types.Info
+	Raw  *big.Int
+}
+
+func (f *FixedConst) String() string {
+	return circuit.FormatFixed(f.Raw, f.Type.Bits, f.Type.Fraction)
+}
+
+// FixedValue converts the numeric constant value to a fixed-point
+// value of the type t.
+func FixedValue(value interface{}, t types.Info) (*FixedConst, error) {
+	var r *big.Rat
+	switch val := value.(type) {
+	case int32:
+		r = new(big.Rat).SetInt64(int64(val))
+	case int64:
+		r = new(big.Rat).SetInt64(val)
+	case uint64:
+		r = new(big.Rat).SetInt(new(big.Int).SetUint64(val))
+	case *big.Int:
+		r = new(big.Rat).SetInt(val)
+	case float32:
+		r = new(big.Rat).SetFloat64(float64(val))
+	case float64:
+		r = new(big.Rat).SetFloat64(val)
+	case *FixedConst:
+		r = circuit.FixedToRat(val.Raw, val.Type.Bits, val.Type.Fraction)
+	}
+	if r == nil {
+		return nil, fmt.Errorf("invalid fixed-point constant %v (%T)",
+			value, value)
+	}
+	raw, err := circuit.FixedFromRat(r, t.Bits, t.Fraction)
+	if err != nil {
+		return nil, err
+	}
+	return &FixedConst{
+		Type: t,
+		Raw:  raw,
+	}, nil
+}
+
 // Constant creates a constant variable for the argument value.
 func Constant(gen *Generator, value interface{}) (Variable, error) {
 	v := Variable{
@@ -657,16 +961,28 @@ func Constant(gen *Generator, value interface{}) (Variable, error) {
 	switch val := value.(type) {
 	case int32:
 		var minBits int
-		// Count minimum bits needed to represent the value.
-		for minBits = 1; minBits < 32; minBits++ {
-			if (0xffffffff<<minBits)&uint64(val) == 0 {
-				break
+		t := types.Uint
+		if val < 0 {
+			// Count minimum bits needed to represent the two's
+			// complement value.
+			t = types.Int
+			for minBits = 1; minBits < 32; minBits++ {
+				if int64(val) >= -(int64(1) << (minBits - 1)) {
+					break
+				}
+			}
+		} else {
+			// Count minimum bits needed to represent the value.
+			for minBits = 1; minBits < 32; minBits++ {
+				if (0xffffffff<<minBits)&uint64(val) == 0 {
+					break
+				}
 			}
 		}
 
 		v.Name = fmt.Sprintf("$%d", val)
 		v.Type = types.Info{
-			Type:    types.Uint,
+			Type:    t,
 			Bits:    32,
 			MinBits: minBits,
 		}
@@ -696,16 +1012,18 @@ func Constant(gen *Generator, value interface{}) (Variable, error) {
 
 	case *big.Int:
 		v.Name = fmt.Sprintf("$%s", val.String())
+		minBits := val.BitLen()
 		if val.Sign() == -1 {
 			v.Type = types.Info{
 				Type: types.Int,
 			}
+			// The two's complement value needs the sign bit.
+			minBits = new(big.Int).Not(val).BitLen() + 1
 		} else {
 			v.Type = types.Info{
 				Type: types.Uint,
 			}
 		}
-		minBits := val.BitLen()
 		var bits int
 		if minBits > 64 {
 			bits = minBits
@@ -718,6 +1036,27 @@ func Constant(gen *Generator, value interface{}) (Variable, error) {
 		v.Type.Bits = bits
 		v.Type.MinBits = minBits
 
+	case float32:
+		v.Name = fmt.Sprintf("$%vf32", val)
+		v.Type = types.Info{
+			Type:    types.Float,
+			Bits:    32,
+			MinBits: 32,
+		}
+
+	case float64:
+		v.Name = fmt.Sprintf("$%vf64", val)
+		v.Type = types.Info{
+			Type:    types.Float,
+			Bits:    64,
+			MinBits: 64,
+		}
+
+	case *FixedConst:
+		v.Name = fmt.Sprintf("$%s%s", val, val.Type.ShortString())
+		v.Type = val.Type
+		v.Type.MinBits = val.Type.Bits
+
 	case bool:
 		v.Name = fmt.Sprintf("$%v", val)
 		v.Type = types.Info{
@@ -741,6 +1080,11 @@ func Constant(gen *Generator, value interface{}) (Variable, error) {
 		v.Type = val
 		v.TypeRef = true
 
+	case *CompositeValue:
+		v.Name = fmt.Sprintf("$%s", val)
+		v.Type = val.Type
+		v.Type.MinBits = val.Type.Bits
+
 	default:
 		return v, fmt.Errorf("Constant: %v (%T) not implemented yet", val, val)
 	}
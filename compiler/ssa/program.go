@@ -9,12 +9,14 @@ package ssa
 import (
 	"fmt"
 	"io"
+	"math/big"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/markkurossi/mpc/circuit"
 	"github.com/markkurossi/mpc/compiler/circuits"
+	"github.com/markkurossi/mpc/compiler/types"
 	"github.com/markkurossi/mpc/compiler/utils"
 )
 
@@ -191,7 +193,7 @@ func (prog *Program) liveness() {
 			live.Add(in)
 		}
 		switch step.Instr.Op {
-		case Slice, Mov:
+		case Slice, Mov, Smov:
 			if !step.Instr.In[0].Const {
 				// Now `out' is an alias for `in[0]' and we must make
 				// `in] live in all steps where `out' is live.
@@ -286,6 +288,74 @@ func (prog *Program) DefineConstants(zero, one *circuits.Wire) error {
 	return nil
 }
 
+// extendConstants sign-extends the wires of the negative constant
+// inputs of the instruction to the width of its other operands.
+// Constants have only their minimum number of wires which are
+// otherwise zero-extended.
+func extendConstants(instr Instr, wires [][]*circuits.Wire) {
+	switch instr.Op {
+	case Iadd, Isub, Ineg, Imult, Idiv, Imod, Ilt, Ile, Igt, Ige, Eq, Neq,
+		Band, Bclr, Bor, Bxor, Bnot, Lshift, Rshift, Srshift, Rotl, Rotr,
+		Amov, Aset, Mov, Smov, Phi, Builtin:
+	default:
+		return
+	}
+	var width int
+	if instr.Out != nil && instr.Out.Type.Type != types.Bool {
+		width = instr.Out.Type.Bits
+	}
+	for idx, in := range instr.In {
+		if !in.Const && len(wires[idx]) > width {
+			width = len(wires[idx])
+		}
+	}
+	for idx, in := range instr.In {
+		if !in.Const || !isNegative(in.ConstValue) ||
+			len(wires[idx]) == 0 || len(wires[idx]) >= width {
+			continue
+		}
+		w := make([]*circuits.Wire, width)
+		copy(w, wires[idx])
+		for i := len(wires[idx]); i < width; i++ {
+			w[i] = wires[idx][len(wires[idx])-1]
+		}
+		wires[idx] = w
+	}
+}
+
+func isNegative(value interface{}) bool {
+	switch val := value.(type) {
+	case int32:
+		return val < 0
+	case int64:
+		return val < 0
+	case *big.Int:
+		return val.Sign() < 0
+	default:
+		return false
+	}
+}
+
+// constInt returns the value of the integer constant v. The function
+// returns false if v is not an integer constant.
+func constInt(v Variable) (*big.Int, bool) {
+	if !v.Const {
+		return nil, false
+	}
+	switch val := v.ConstValue.(type) {
+	case int32:
+		return big.NewInt(int64(val)), true
+	case int64:
+		return big.NewInt(val), true
+	case uint64:
+		return new(big.Int).SetUint64(val), true
+	case *big.Int:
+		return val, true
+	default:
+		return nil, false
+	}
+}
+
 // PP pretty-prints the program to the argument io.Writer.
 func (prog *Program) PP(out io.Writer) {
 	for i, in := range prog.Inputs {
@@ -14,6 +14,7 @@ import (
 
 	"github.com/markkurossi/mpc/circuit"
 	"github.com/markkurossi/mpc/compiler/circuits"
+	"github.com/markkurossi/mpc/compiler/types"
 	"github.com/markkurossi/mpc/compiler/utils"
 	"github.com/markkurossi/mpc/ot"
 	"github.com/markkurossi/mpc/p2p"
@@ -22,41 +23,54 @@ import (
 // StreamCircuit streams the program circuit into the P2P connection.
 func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 	inputs *big.Int) (circuit.IO, []*big.Int, error) {
+	outputs, result, _, err := prog.StreamCircuitWithTiming(conn, params,
+		inputs)
+	return outputs, result, err
+}
+
+// StreamCircuitWithTiming streams the program circuit into the P2P
+// connection and returns the timing report of the computation.
+func (prog *Program) StreamCircuitWithTiming(conn *p2p.Conn,
+	params *utils.Params, inputs *big.Int) (
+	circuit.IO, []*big.Int, *circuit.Timing, error) {
 
 	timing := circuit.NewTiming()
+	timing.SetIOStats(func() p2p.IOStats {
+		return conn.Stats
+	})
 
 	var key [32]byte
 	_, err := rand.Read(key[:])
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	if params.Verbose {
 		fmt.Printf(" - Sending program info...\n")
 	}
 	if err := conn.SendData(key[:]); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	// Our input.
 	if err := sendArgument(conn, prog.Inputs[0]); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	// Peer input.
 	if err := sendArgument(conn, prog.Inputs[1]); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	// Program outputs.
 	if err := conn.SendUint32(len(prog.Outputs)); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	for _, o := range prog.Outputs {
 		if err := sendArgument(conn, o); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 	}
 	// Number of program steps.
 	if err := conn.SendUint32(len(prog.Steps)); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Collect input wire IDs.
@@ -71,7 +85,7 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 
 	streaming, err := circuit.NewStreaming(key[:], ids, conn)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Select our inputs.
@@ -89,12 +103,13 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 	}
 
 	// Send our inputs.
+	conn.SetMsgType(p2p.MsgInputs)
 	for idx, i := range n1 {
 		if params.Verbose && false {
 			fmt.Printf("N1[%d]:\t%s\n", idx, i)
 		}
 		if err := conn.SendLabel(i); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 	}
 
@@ -104,17 +119,18 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 	// Init oblivious transfer.
 	sender, err := ot.NewSender(2048)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Send our public key.
+	conn.SetMsgType(p2p.MsgOT)
 	pub := sender.PublicKey()
 	data := pub.N.Bytes()
 	if err := conn.SendData(data); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	if err := conn.SendUint32(pub.E); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	conn.Flush()
 
@@ -126,7 +142,7 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 	for i := 0; i < prog.Inputs[1].Size; i++ {
 		bit, err := conn.ReceiveUint32()
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		wire := streaming.GetInput(circuit.Wire(bit))
 
@@ -135,33 +151,33 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 
 		xfer, err := sender.NewTransfer(m0Data, m1Data)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 
 		x0, x1 := xfer.RandomMessages()
 		if err := conn.SendData(x0); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		if err := conn.SendData(x1); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		conn.Flush()
 
 		v, err := conn.ReceiveData()
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		xfer.ReceiveV(v)
 
 		m0p, m1p, err := xfer.Messages()
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		if err := conn.SendData(m0p); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		if err := conn.SendData(m1p); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		conn.Flush()
 	}
@@ -173,16 +189,16 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 
 	zero, err := prog.ZeroWire(conn, streaming)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	one, err := prog.OneWire(conn, streaming)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	err = prog.DefineConstants(zero, one)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Stream circuit.
@@ -217,10 +233,11 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 		for _, in := range instr.In {
 			w, err := prog.AssignedWires(in.String(), in.Type.Bits)
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 			wires = append(wires, w)
 		}
+		extendConstants(instr, wires)
 
 		var out []*circuits.Wire
 		var err error
@@ -228,7 +245,7 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 			out, err = prog.AssignedWires(instr.Out.String(),
 				instr.Out.Type.Bits)
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 		}
 
@@ -238,121 +255,110 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 
 		switch instr.Op {
 
-		case Lshift:
+		case Slice:
 			if !instr.In[1].Const {
-				return nil, nil,
-					fmt.Errorf("%s: only constant shift supported", instr.Op)
+				return nil, nil, nil,
+					fmt.Errorf("%s: only constant index supported", instr.Op)
 			}
-			var count int
+			var from int
 			switch val := instr.In[1].ConstValue.(type) {
 			case int32:
-				count = int(val)
+				from = int(val)
 			default:
-				return nil, nil,
+				return nil, nil, nil,
 					fmt.Errorf("%s: unsupported index type %T", instr.Op, val)
 			}
-			if count < 0 {
-				return nil, nil,
-					fmt.Errorf("%s: negative shift count %d", instr.Op, count)
-			}
-			for bit := 0; bit < len(out); bit++ {
-				var id uint32
-				if bit-count >= 0 && bit-count < len(wires[0]) {
-					id = wires[0][bit-count].ID
-				} else {
-					w, err := prog.ZeroWire(conn, streaming)
-					if err != nil {
-						return nil, nil, err
-					}
-					id = w.ID
-				}
-				out[bit].ID = id
-			}
 
-		case Rshift:
-			if !instr.In[1].Const {
-				return nil, nil,
-					fmt.Errorf("%s: only constant shift supported", instr.Op)
+			if !instr.In[2].Const {
+				return nil, nil, nil,
+					fmt.Errorf("%s: only constant index supported", instr.Op)
 			}
-			var count int
-			switch val := instr.In[1].ConstValue.(type) {
+			var to int
+			switch val := instr.In[2].ConstValue.(type) {
 			case int32:
-				count = int(val)
+				to = int(val)
 			default:
-				return nil, nil,
+				return nil, nil, nil,
 					fmt.Errorf("%s: unsupported index type %T", instr.Op, val)
 			}
-			if count < 0 {
-				return nil, nil,
-					fmt.Errorf("%s: negative shift count %d", instr.Op, count)
+			if from >= to {
+				return nil, nil, nil, fmt.Errorf("%s: bounds out of range [%d:%d]",
+					instr.Op, from, to)
 			}
-			for bit := 0; bit < len(out); bit++ {
+			for bit := from; bit < to; bit++ {
 				var id uint32
-				if bit+count < len(wires[0]) {
-					id = wires[0][bit+count].ID
+				if bit < len(wires[0]) {
+					id = wires[0][bit].ID
 				} else {
 					w, err := prog.ZeroWire(conn, streaming)
 					if err != nil {
-						return nil, nil, err
+						return nil, nil, nil, err
 					}
 					id = w.ID
 				}
-				out[bit].ID = id
+				out[bit-from].ID = id
 			}
 
-		case Slice:
-			if !instr.In[1].Const {
-				return nil, nil,
+		case Amov:
+			// v arr from to o
+			if !instr.In[2].Const {
+				return nil, nil, nil,
 					fmt.Errorf("%s: only constant index supported", instr.Op)
 			}
 			var from int
-			switch val := instr.In[1].ConstValue.(type) {
+			switch val := instr.In[2].ConstValue.(type) {
 			case int32:
 				from = int(val)
 			default:
-				return nil, nil,
+				return nil, nil, nil,
 					fmt.Errorf("%s: unsupported index type %T", instr.Op, val)
 			}
 
-			if !instr.In[2].Const {
-				return nil, nil,
+			if !instr.In[3].Const {
+				return nil, nil, nil,
 					fmt.Errorf("%s: only constant index supported", instr.Op)
 			}
 			var to int
-			switch val := instr.In[2].ConstValue.(type) {
+			switch val := instr.In[3].ConstValue.(type) {
 			case int32:
 				to = int(val)
 			default:
-				return nil, nil,
+				return nil, nil, nil,
 					fmt.Errorf("%s: unsupported index type %T", instr.Op, val)
 			}
-			if from >= to {
-				return nil, nil, fmt.Errorf("%s: bounds out of range [%d:%d]",
+			if from < 0 || from >= to || to > len(out) {
+				return nil, nil, nil, fmt.Errorf("%s: bounds out of range [%d:%d]",
 					instr.Op, from, to)
 			}
-			for bit := from; bit < to; bit++ {
-				var id uint32
-				if bit < len(wires[0]) {
-					id = wires[0][bit].ID
-				} else {
-					w, err := prog.ZeroWire(conn, streaming)
+			for bit := 0; bit < len(out); bit++ {
+				var w *circuits.Wire
+				if bit >= from && bit < to {
+					if bit-from < len(wires[0]) {
+						w = wires[0][bit-from]
+					}
+				} else if bit < len(wires[1]) {
+					w = wires[1][bit]
+				}
+				if w == nil {
+					w, err = prog.ZeroWire(conn, streaming)
 					if err != nil {
-						return nil, nil, err
+						return nil, nil, nil, err
 					}
-					id = w.ID
 				}
-				out[bit-from].ID = id
+				out[bit].ID = w.ID
 			}
 
-		case Mov:
+		case Mov, Smov:
 			for bit := 0; bit < instr.Out.Type.Bits; bit++ {
 				var id uint32
 				if bit < len(wires[0]) {
 					id = wires[0][bit].ID
+				} else if instr.Op == Smov {
+					id = wires[0][len(wires[0])-1].ID
 				} else {
 					w, err := prog.ZeroWire(conn, streaming)
 					if err != nil {
-						return nil, nil, err
+						return nil, nil, nil, err
 					}
 					id = w.ID
 				}
@@ -360,13 +366,15 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 			}
 
 		case Ret:
+			conn.SetMsgType(p2p.MsgOther)
 			if err := conn.SendUint32(circuit.OpReturn); err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
+			conn.SetMsgType(p2p.MsgResults)
 			for _, arg := range wires {
 				for _, w := range arg {
 					if err := conn.SendUint32(int(w.ID)); err != nil {
-						return nil, nil, err
+						return nil, nil, nil, err
 					}
 					returnIDs = append(returnIDs, w.ID)
 				}
@@ -392,7 +400,7 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 			for i, ret := range instr.Ret {
 				wires, err := prog.AssignedWires(ret.String(), ret.Type.Bits)
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, nil, err
 				}
 				for j := 0; j < instr.Circ.Outputs[i].Size; j++ {
 					if j < len(wires) {
@@ -403,7 +411,7 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 				}
 			}
 			if len(oIDs) != instr.Circ.Outputs.Size() {
-				return nil, nil, fmt.Errorf("%s: output mismatch: %d vs. %d",
+				return nil, nil, nil, fmt.Errorf("%s: output mismatch: %d vs. %d",
 					instr.Op, len(oIDs), instr.Circ.Outputs.Size())
 			}
 			if true {
@@ -412,7 +420,7 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 
 			err = prog.garble(conn, streaming, idx, instr.Circ, iIDs, oIDs)
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 
 		case GC:
@@ -424,17 +432,52 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 				fmt.Printf("GC: %s not known\n", instr.GC)
 			}
 
+		case Lshift, Rshift, Srshift, Rotl, Rotr:
+			if instr.In[1].Const {
+				var count int
+				switch val := instr.In[1].ConstValue.(type) {
+				case int32:
+					count = int(val)
+				default:
+					return nil, nil, nil, fmt.Errorf(
+						"%s: unsupported index type %T", instr.Op, val)
+				}
+				if count < 0 {
+					return nil, nil, nil, fmt.Errorf(
+						"%s: negative shift count %d", instr.Op, count)
+				}
+				for bit := 0; bit < len(out); bit++ {
+					var id uint32
+					src := shiftSource(instr.Op, bit, count, len(out),
+						len(wires[0]))
+					if src >= 0 {
+						id = wires[0][src].ID
+					} else {
+						w, err := prog.ZeroWire(conn, streaming)
+						if err != nil {
+							return nil, nil, nil, err
+						}
+						id = w.ID
+					}
+					out[bit].ID = id
+				}
+				break
+			}
+			// Barrel shifter for variable shift count.
+			fallthrough
+
 		default:
 			f, ok := circuitGenerators[instr.Op]
 			if !ok {
-				return nil, nil,
+				return nil, nil, nil,
 					fmt.Errorf("Program.Stream: %s not implemented yet",
 						instr.Op)
 			}
 			if params.Verbose && circuit.StreamDebug {
 				fmt.Printf(" - %s\n", instr.StringTyped())
 			}
-			circ, ok := cache[instr.StringTyped()]
+			key := circuitKey(instr, wires)
+			circ, ok := cache[key]
 			if !ok {
 				var cIn [][]*circuits.Wire
 				var flat []*circuits.Wire
@@ -452,11 +495,11 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 
 				cc, err := circuits.NewCompiler(params, nil, nil, flat, cOut)
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, nil, err
 				}
 				cacheable, err := f(cc, instr, cIn, cOut)
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, nil, err
 				}
 				pruned := cc.Prune()
 				if params.Verbose && circuit.StreamDebug {
@@ -465,7 +508,7 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 				}
 				circ = cc.Compile()
 				if cacheable {
-					cache[instr.StringTyped()] = circ
+					cache[key] = circ
 				}
 				if params.Verbose && circuit.StreamDebug {
 					fmt.Printf("%05d: - %s\n", idx, circ)
@@ -489,25 +532,27 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 
 			err = prog.garble(conn, streaming, idx, circ, iIDs, oIDs)
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 		}
 	}
 
+	conn.SetMsgType(p2p.MsgOther)
 	op, err := conn.ReceiveUint32()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	if op != circuit.OpResult {
-		return nil, nil, fmt.Errorf("unexpected operation: %d", op)
+		return nil, nil, nil, fmt.Errorf("unexpected operation: %d", op)
 	}
+	conn.SetMsgType(p2p.MsgResults)
 
 	result := new(big.Int)
 
 	for i := 0; i < prog.Outputs.Size(); i++ {
 		label, err := conn.ReceiveLabel()
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		wire := streaming.GetInput(circuit.Wire(returnIDs[i]))
 		var bit uint
@@ -516,14 +561,14 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 		} else if label.Equal(wire.L1) {
 			bit = 1
 		} else {
-			return nil, nil, fmt.Errorf("unknown label %s for result %d",
+			return nil, nil, nil, fmt.Errorf("unknown label %s for result %d",
 				label, i)
 		}
 		result.SetBit(result, i, bit)
 	}
 	data = result.Bytes()
 	if err := conn.SendData(data); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	conn.Flush()
 
@@ -531,6 +576,10 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 	ioStats = conn.Stats
 	timing.Sample("Eval", []string{circuit.FileSize(xfer.Sum()).String()})
 
+	timing.Circuit = &circuit.CircuitStats{
+		NumGates:  int(prog.numGates),
+		NumNonXOR: int(prog.numNonXOR),
+	}
 	if params.Verbose {
 		timing.Print(circuit.FileSize(conn.Stats.Sum()).String())
 	}
@@ -539,7 +588,28 @@ func (prog *Program) StreamCircuit(conn *p2p.Conn, params *utils.Params,
 		prog.nextWireID, len(cache))
 	fmt.Printf("#gates=%d, #non-XOR=%d\n", prog.numGates, prog.numNonXOR)
 
-	return prog.Outputs, prog.Outputs.Split(result), nil
+	return prog.Outputs, prog.Outputs.Split(result), timing, nil
+}
+
+// circuitKey returns the circuit cache key for the instruction. The
+// key includes the input wire sizes since constant inputs have only
+// their minimum number of wires.
+func circuitKey(instr Instr, wires [][]*circuits.Wire) string {
+	key := instr.StringTyped()
+	for _, w := range wires {
+		key += fmt.Sprintf(" %d", len(w))
+	}
+	switch instr.Op {
+	case Imult, Umult, Udiv, Umod, Idiv, Imod:
+		// The circuits for constant operands depend on the constant
+		// values.
+		for _, in := range instr.In {
+			if in.Const {
+				key += " " + in.String()
+			}
+		}
+	}
+	return key
 }
 
 func (prog *Program) garble(conn *p2p.Conn, streaming *circuit.Streaming,
@@ -557,9 +627,11 @@ func (prog *Program) garble(conn *p2p.Conn, streaming *circuit.Streaming,
 		}
 	}
 
+	conn.SetMsgType(p2p.MsgOther)
 	if err := conn.SendUint32(circuit.OpCircuit); err != nil {
 		return err
 	}
+	conn.SetMsgType(p2p.MsgTables)
 	if err := conn.SendUint32(step); err != nil {
 		return err
 	}
@@ -718,53 +790,169 @@ func newBinary(bin NewBinary) NewCircuit {
 	}
 }
 
+// NewUnary creates a new unary circuit.
+type NewUnary func(cc *circuits.Compiler, a []*circuits.Wire,
+	out []*circuits.Wire) error
+
+func newUnary(un NewUnary) NewCircuit {
+	return func(cc *circuits.Compiler, instr Instr, in [][]*circuits.Wire,
+		out []*circuits.Wire) (bool, error) {
+		return true, un(cc, in[0], out)
+	}
+}
+
 func newMultiplier(cc *circuits.Compiler, instr Instr, in [][]*circuits.Wire,
 	out []*circuits.Wire) (bool, error) {
+	if c, ok := constInt(instr.In[1]); ok {
+		return true, circuits.NewConstMultiplier(cc, in[0], c, out)
+	}
+	if c, ok := constInt(instr.In[0]); ok {
+		return true, circuits.NewConstMultiplier(cc, in[1], c, out)
+	}
 	return true, circuits.NewMultiplier(cc, cc.Params.CircMultArrayTreshold,
 		in[0], in[1], out)
 }
 
 func newDivider(cc *circuits.Compiler, instr Instr, in [][]*circuits.Wire,
 	out []*circuits.Wire) (bool, error) {
+	if d, ok := constInt(instr.In[1]); ok && d.Sign() > 0 {
+		return true, circuits.NewConstDivider(cc, in[0], d, out, nil)
+	}
 	return true, circuits.NewDivider(cc, in[0], in[1], out, nil)
 }
 
 func newModulo(cc *circuits.Compiler, instr Instr, in [][]*circuits.Wire,
 	out []*circuits.Wire) (bool, error) {
+	if d, ok := constInt(instr.In[1]); ok && d.Sign() > 0 {
+		return true, circuits.NewConstDivider(cc, in[0], d, nil, out)
+	}
 	return true, circuits.NewDivider(cc, in[0], in[1], nil, out)
 }
 
+func newSignedDivider(cc *circuits.Compiler, instr Instr,
+	in [][]*circuits.Wire, out []*circuits.Wire) (bool, error) {
+	if d, ok := constInt(instr.In[1]); ok && d.Sign() != 0 {
+		return true, circuits.NewSignedConstDivider(cc, in[0], d, out, nil)
+	}
+	return true, circuits.NewSignedDivider(cc, in[0], in[1], out, nil)
+}
+
+func newSignedModulo(cc *circuits.Compiler, instr Instr,
+	in [][]*circuits.Wire, out []*circuits.Wire) (bool, error) {
+	if d, ok := constInt(instr.In[1]); ok && d.Sign() != 0 {
+		return true, circuits.NewSignedConstDivider(cc, in[0], d, nil, out)
+	}
+	return true, circuits.NewSignedDivider(cc, in[0], in[1], nil, out)
+}
+
+func newFixedMultiplier(cc *circuits.Compiler, instr Instr,
+	in [][]*circuits.Wire, out []*circuits.Wire) (bool, error) {
+	return true, circuits.NewFixedMultiplier(cc,
+		cc.Params.CircMultArrayTreshold, instr.Out.Type.Fraction,
+		in[0], in[1], out)
+}
+
+func newFixedDivider(cc *circuits.Compiler, instr Instr,
+	in [][]*circuits.Wire, out []*circuits.Wire) (bool, error) {
+	return true, circuits.NewFixedDivider(cc, instr.Out.Type.Fraction,
+		in[0], in[1], out)
+}
+
+func newFixedConverter(cc *circuits.Compiler, instr Instr,
+	in [][]*circuits.Wire, out []*circuits.Wire) (bool, error) {
+	from := instr.In[0].Type
+	return true, circuits.NewFixedConverter(cc, from.Type != types.Uint,
+		from.Fraction, instr.Out.Type.Fraction, in[0], out)
+}
+
+func newEqComparator(cc *circuits.Compiler, instr Instr,
+	in [][]*circuits.Wire, out []*circuits.Wire) (bool, error) {
+	if instr.In[0].Type.Type == types.Float {
+		return true, circuits.NewFloatEqComparator(cc, in[0], in[1], out)
+	}
+	return true, circuits.NewEqComparator(cc, in[0], in[1], out)
+}
+
+func newNeqComparator(cc *circuits.Compiler, instr Instr,
+	in [][]*circuits.Wire, out []*circuits.Wire) (bool, error) {
+	if instr.In[0].Type.Type == types.Float {
+		return true, circuits.NewFloatNeqComparator(cc, in[0], in[1], out)
+	}
+	return true, circuits.NewNeqComparator(cc, in[0], in[1], out)
+}
+
 var circuitGenerators = map[Operand]NewCircuit{
 	Iadd:  newBinary(circuits.NewAdder),
 	Uadd:  newBinary(circuits.NewAdder),
 	Isub:  newBinary(circuits.NewSubtractor),
 	Usub:  newBinary(circuits.NewSubtractor),
+	Ineg:  newUnary(circuits.NewNegator),
+	Uneg:  newUnary(circuits.NewNegator),
 	Imult: newMultiplier,
 	Umult: newMultiplier,
-	Idiv:  newDivider,
+	Idiv:  newSignedDivider,
 	Udiv:  newDivider,
-	Imod:  newModulo,
+	Imod:  newSignedModulo,
 	Umod:  newModulo,
-	Ilt:   newBinary(circuits.NewLtComparator),
+	Ilt:   newBinary(circuits.NewSignedLtComparator),
 	Ult:   newBinary(circuits.NewLtComparator),
-	Ile:   newBinary(circuits.NewLeComparator),
+	Ile:   newBinary(circuits.NewSignedLeComparator),
 	Ule:   newBinary(circuits.NewLeComparator),
-	Igt:   newBinary(circuits.NewGtComparator),
+	Igt:   newBinary(circuits.NewSignedGtComparator),
 	Ugt:   newBinary(circuits.NewGtComparator),
-	Ige:   newBinary(circuits.NewGeComparator),
+	Ige:   newBinary(circuits.NewSignedGeComparator),
 	Uge:   newBinary(circuits.NewGeComparator),
-	Eq:    newBinary(circuits.NewEqComparator),
-	Neq:   newBinary(circuits.NewNeqComparator),
+	Eq:    newEqComparator,
+	Neq:   newNeqComparator,
 	And:   newBinary(circuits.NewLogicalAND),
 	Or:    newBinary(circuits.NewLogicalOR),
+	Not:   newUnary(circuits.NewLogicalNOT),
 	Band:  newBinary(circuits.NewBinaryAND),
 	Bclr:  newBinary(circuits.NewBinaryClear),
 	Bor:   newBinary(circuits.NewBinaryOR),
 	Bxor:  newBinary(circuits.NewBinaryXOR),
+	Bnot:  newUnary(circuits.NewBinaryNOT),
+
+	Fadd:  newBinary(circuits.NewFloatAdder),
+	Fsub:  newBinary(circuits.NewFloatSubtractor),
+	Fneg:  newUnary(circuits.NewFloatNegator),
+	Fmult: newBinary(circuits.NewFloatMultiplier),
+	Fdiv:  newBinary(circuits.NewFloatDivider),
+	Flt:   newBinary(circuits.NewFloatLtComparator),
+	Fle:   newBinary(circuits.NewFloatLeComparator),
+	Fgt:   newBinary(circuits.NewFloatGtComparator),
+	Fge:   newBinary(circuits.NewFloatGeComparator),
+	Itof:  newUnary(circuits.NewIntToFloat),
+	Utof:  newUnary(circuits.NewUintToFloat),
+	Ftoi:  newUnary(circuits.NewFloatToInt),
+	Ftof:  newUnary(circuits.NewFloatToFloat),
+
+	Xmult: newFixedMultiplier,
+	Xdiv:  newFixedDivider,
+	Itox:  newFixedConverter,
+	Utox:  newFixedConverter,
+	Xtoi:  newFixedConverter,
+	Xtox:  newFixedConverter,
+
+	Lshift:  newBinary(circuits.NewShiftLeft),
+	Rshift:  newBinary(circuits.NewShiftRight),
+	Srshift: newBinary(circuits.NewShiftRightArithmetic),
+	Rotl:    newBinary(circuits.NewRotateLeft),
+	Rotr:    newBinary(circuits.NewRotateRight),
 
 	Builtin: func(cc *circuits.Compiler, instr Instr, in [][]*circuits.Wire,
 		out []*circuits.Wire) (bool, error) {
-		return true, instr.Builtin(cc, in[0], in[1], out)
+		return true, instr.Builtin(cc, in, out)
+	},
+	Aget: func(cc *circuits.Compiler, instr Instr, in [][]*circuits.Wire,
+		out []*circuits.Wire) (bool, error) {
+		return true, circuits.NewIndex(cc, instr.In[0].Type.ArraySize,
+			instr.In[1].Type.Type == types.Int, in[0], in[1], out)
+	},
+	Aset: func(cc *circuits.Compiler, instr Instr, in [][]*circuits.Wire,
+		out []*circuits.Wire) (bool, error) {
+		return true, circuits.NewIndexSet(cc, instr.In[0].Type.ArraySize,
+			instr.In[1].Type.Type == types.Int, in[0], in[1], in[2], out)
 	},
 	Phi: func(cc *circuits.Compiler, instr Instr, in [][]*circuits.Wire,
 		out []*circuits.Wire) (bool, error) {
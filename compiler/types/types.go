@@ -40,8 +40,10 @@ const (
 	Int
 	Uint
 	Float
+	Fixed
 	String
 	Struct
+	Array
 )
 
 // Types define MPCL types and their names.
@@ -51,8 +53,10 @@ var Types = map[string]Type{
 	"int":         Int,
 	"uint":        Uint,
 	"float":       Float,
+	"fixed":       Fixed,
 	"string":      String,
 	"struct":      Struct,
+	"array":       Array,
 }
 
 var shortTypes = map[Type]string{
@@ -61,17 +65,27 @@ var shortTypes = map[Type]string{
 	Int:       "i",
 	Uint:      "u",
 	Float:     "f",
+	Fixed:     "x",
 	String:    "str",
 	Struct:    "struct",
+	Array:     "array",
 }
 
 // Info specifies information about a type.
 type Info struct {
-	Type    Type
-	Bits    int
-	MinBits int
-	Struct  []StructField
-	Offset  int
+	Type        Type
+	Bits        int
+	MinBits     int
+	Struct      []StructField
+	Offset      int
+	ElementType *Info
+	ArraySize   int
+	// Fraction specifies the number of fractional bits of fixed-point
+	// types.
+	Fraction int
+	// Name and Package identify named types.
+	Name    string
+	Package string
 }
 
 // StructField defines a structure field name and type.
@@ -86,17 +100,30 @@ func (f StructField) String() string {
 }
 
 func (i Info) String() string {
+	if i.Type == Array {
+		return fmt.Sprintf("[%d]%s", i.ArraySize, i.ElementType)
+	}
 	if i.Bits == 0 {
 		return i.Type.String()
 	}
+	if i.Type == Fixed {
+		return fmt.Sprintf("%s%d.%d", i.Type, i.Bits, i.Fraction)
+	}
 	return fmt.Sprintf("%s%d", i.Type, i.Bits)
 }
 
 // ShortString returns a short string name for the type info.
 func (i Info) ShortString() string {
+	if i.Type == Array {
+		return fmt.Sprintf("[%d]%s", i.ArraySize, i.ElementType.ShortString())
+	}
 	if i.Bits == 0 {
 		return i.Type.ShortString()
 	}
+	if i.Type == Fixed {
+		return fmt.Sprintf("%s%d.%d", i.Type.ShortString(), i.Bits,
+			i.Fraction)
+	}
 	return fmt.Sprintf("%s%d", i.Type.ShortString(), i.Bits)
 }
 
@@ -107,7 +134,13 @@ func (i Info) Undefined() bool {
 
 // Equal tests if the argument type is equal to this type info.
 func (i Info) Equal(o Info) bool {
-	return i.Type == o.Type && i.Bits == o.Bits
+	if i.Type != o.Type || i.Bits != o.Bits || i.Fraction != o.Fraction {
+		return false
+	}
+	if i.Type == Array {
+		return i.ArraySize == o.ArraySize && i.ElementType.Equal(*o.ElementType)
+	}
+	return true
 }
 
 // CanAssignConst tests if the argument const type can be assigned to
@@ -117,11 +150,32 @@ func (i Info) CanAssignConst(o Info) bool {
 	case Int:
 		return (o.Type == Int || o.Type == Uint) && i.Bits >= o.MinBits
 
+	case Float:
+		// Numeric constants are converted to the float size.
+		return o.Type == Float || o.Type == Int || o.Type == Uint
+
+	case Fixed:
+		// Numeric constants are converted to the fixed-point format.
+		return o.Type == Fixed || o.Type == Float || o.Type == Int ||
+			o.Type == Uint
+
 	default:
 		return i.Type == o.Type && i.Bits >= o.MinBits
 	}
 }
 
+// ArrayType returns type information for an array of size elements
+// of type element.
+func ArrayType(element Info, size int) Info {
+	return Info{
+		Type:        Array,
+		Bits:        element.Bits * size,
+		MinBits:     element.Bits * size,
+		ElementType: &element,
+		ArraySize:   size,
+	}
+}
+
 // BoolType returns type information for the boolean type.
 func BoolType() Info {
 	return Info{
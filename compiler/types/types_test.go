@@ -16,3 +16,23 @@ func TestUndefined(t *testing.T) {
 		t.Errorf("undef is not undefined")
 	}
 }
+
+func TestArray(t *testing.T) {
+	u8 := Info{
+		Type: Uint,
+		Bits: 8,
+	}
+	arr := ArrayType(u8, 4)
+	if arr.Bits != 32 {
+		t.Errorf("invalid array size: got %d, expected 32", arr.Bits)
+	}
+	if arr.String() != "[4]uint8" {
+		t.Errorf("invalid array name: %s", arr)
+	}
+	if !arr.Equal(ArrayType(u8, 4)) {
+		t.Errorf("equal arrays not equal")
+	}
+	if arr.Equal(ArrayType(Info{Type: Int, Bits: 8}, 4)) {
+		t.Errorf("arrays with different element types equal")
+	}
+}
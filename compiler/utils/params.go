@@ -10,12 +10,21 @@ import (
 	"io"
 )
 
+// DefaultMaxInlineDepth specifies the default maximum depth of
+// nested function call inlining.
+const DefaultMaxInlineDepth = 256
+
 // Params specify compiler parameters.
 type Params struct {
 	Verbose   bool
 	SSAOut    io.WriteCloser
 	SSADotOut io.WriteCloser
 
+	// MaxInlineDepth specifies the maximum depth of nested function
+	// call inlining. The DefaultMaxInlineDepth is used if the value
+	// is 0.
+	MaxInlineDepth int
+
 	NoCircCompile bool
 	CircOut       io.WriteCloser
 	CircDotOut    io.WriteCloser
@@ -0,0 +1,255 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package p2p
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+)
+
+// Config defines the peers of the peer-to-peer network. The
+// configuration is stored in JSON:
+//
+//	{
+//	    "peers": [
+//	        {
+//	            "id": 0,
+//	            "addr": "10.0.0.1:8080",
+//	            "cert": "player0.crt",
+//	            "key": "player0.key"
+//	        },
+//	        {
+//	            "id": 1,
+//	            "addr": "10.0.0.2:8080",
+//	            "cert": "player1.crt"
+//	        }
+//	    ]
+//	}
+//
+// The TLS identities are optional. If the peers define certificates,
+// all connections are authenticated with TLS and the peer
+// certificates are pinned to the configured ones. Each player needs
+// the private key only for its own certificate. Relative file names
+// are resolved from the configuration file's directory.
+type Config struct {
+	Peers []*PeerConfig `json:"peers"`
+}
+
+// PeerConfig defines a peer in the network.
+type PeerConfig struct {
+	ID int `json:"id"`
+	// Addr specifies the address where the peer accepts connections.
+	Addr string `json:"addr"`
+	// Listen optionally specifies the local address where the peer
+	// listens for connections. If it is unset, Addr is used.
+	Listen string `json:"listen,omitempty"`
+	// Cert specifies the peer's PEM encoded TLS certificate file.
+	Cert string `json:"cert,omitempty"`
+	// Key specifies the peer's PEM encoded private key file.
+	Key string `json:"key,omitempty"`
+
+	certDER []byte
+}
+
+// ReadConfig reads the peer configuration from the file.
+func ReadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	config := new(Config)
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+	// Certificate and key files are relative to the config file.
+	dir := filepath.Dir(file)
+	for _, peer := range config.Peers {
+		if len(peer.Cert) > 0 && !filepath.IsAbs(peer.Cert) {
+			peer.Cert = filepath.Join(dir, peer.Cert)
+		}
+		if len(peer.Key) > 0 && !filepath.IsAbs(peer.Key) {
+			peer.Key = filepath.Join(dir, peer.Key)
+		}
+	}
+	if err := config.init(); err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+	return config, nil
+}
+
+// LocalConfig creates a configuration for count peers running in
+// the local host. The peers listen at consecutive ports starting
+// from the base port.
+func LocalConfig(count, port int) *Config {
+	config := new(Config)
+	for i := 0; i < count; i++ {
+		config.Peers = append(config.Peers, &PeerConfig{
+			ID:   i,
+			Addr: fmt.Sprintf("127.0.0.1:%d", port+i),
+		})
+	}
+	return config
+}
+
+func (config *Config) init() error {
+	if len(config.Peers) == 0 {
+		return errors.New("no peers defined")
+	}
+	var numCerts int
+	seen := make(map[int]bool)
+	for _, peer := range config.Peers {
+		if seen[peer.ID] {
+			return fmt.Errorf("duplicate peer %d", peer.ID)
+		}
+		seen[peer.ID] = true
+		if len(peer.Addr) == 0 {
+			return fmt.Errorf("peer %d: no address", peer.ID)
+		}
+		if len(peer.Cert) == 0 {
+			continue
+		}
+		numCerts++
+		data, err := ioutil.ReadFile(peer.Cert)
+		if err != nil {
+			return fmt.Errorf("peer %d: %s", peer.ID, err)
+		}
+		block, _ := pem.Decode(data)
+		if block == nil || block.Type != "CERTIFICATE" {
+			return fmt.Errorf("peer %d: invalid certificate %s",
+				peer.ID, peer.Cert)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return fmt.Errorf("peer %d: %s", peer.ID, err)
+		}
+		peer.certDER = block.Bytes
+	}
+	if numCerts != 0 && numCerts != len(config.Peers) {
+		return errors.New("TLS certificates must be defined for all peers")
+	}
+	return nil
+}
+
+// Peer returns the configuration of the peer id.
+func (config *Config) Peer(id int) (*PeerConfig, error) {
+	for _, peer := range config.Peers {
+		if peer.ID == id {
+			return peer, nil
+		}
+	}
+	return nil, fmt.Errorf("peer %d not configured", id)
+}
+
+// TLS tests if the configuration uses TLS.
+func (config *Config) TLS() bool {
+	return len(config.Peers) > 0 && len(config.Peers[0].certDER) > 0
+}
+
+func (config *Config) tlsConfig(self *PeerConfig, pinned ...*PeerConfig) (
+	*tls.Config, error) {
+
+	if len(self.Key) == 0 {
+		return nil, fmt.Errorf("peer %d: no private key", self.ID)
+	}
+	cert, err := tls.LoadX509KeyPair(self.Cert, self.Key)
+	if err != nil {
+		return nil, fmt.Errorf("peer %d: %s", self.ID, err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		// The certificates are verified by pinning them to the
+		// configured peer certificates.
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte,
+			_ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("no peer certificate")
+			}
+			for _, peer := range pinned {
+				if bytes.Equal(rawCerts[0], peer.certDER) {
+					return nil
+				}
+			}
+			return errors.New("unknown peer certificate")
+		},
+	}, nil
+}
+
+// Listen creates a listener for the peer id.
+func (config *Config) Listen(id int) (net.Listener, error) {
+	self, err := config.Peer(id)
+	if err != nil {
+		return nil, err
+	}
+	addr := self.Listen
+	if len(addr) == 0 {
+		addr = self.Addr
+	}
+	if !config.TLS() {
+		return net.Listen("tcp", addr)
+	}
+	var peers []*PeerConfig
+	for _, peer := range config.Peers {
+		if peer.ID != id {
+			peers = append(peers, peer)
+		}
+	}
+	tlsConfig, err := config.tlsConfig(self, peers...)
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen("tcp", addr, tlsConfig)
+}
+
+// Dial connects the peer self to the peer id.
+func (config *Config) Dial(self, id int) (net.Conn, error) {
+	peer, err := config.Peer(id)
+	if err != nil {
+		return nil, err
+	}
+	if !config.TLS() {
+		return net.Dial("tcp", peer.Addr)
+	}
+	selfConfig, err := config.Peer(self)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := config.tlsConfig(selfConfig, peer)
+	if err != nil {
+		return nil, err
+	}
+	return tls.Dial("tcp", peer.Addr, tlsConfig)
+}
+
+// verifyPeer verifies that the inbound connection nc is from the peer
+// id.
+func (config *Config) verifyPeer(nc net.Conn, id int) error {
+	if !config.TLS() {
+		return nil
+	}
+	peer, err := config.Peer(id)
+	if err != nil {
+		return err
+	}
+	tlsConn, ok := nc.(*tls.Conn)
+	if !ok {
+		return fmt.Errorf("peer %d: not a TLS connection", id)
+	}
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 || !bytes.Equal(certs[0].Raw, peer.certDER) {
+		return fmt.Errorf("peer %d: certificate mismatch", id)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package p2p
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeIdentity creates a self-signed certificate and its private key
+// into the directory dir. The function returns the file names
+// relative to dir.
+func writeIdentity(t *testing.T, dir, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName: name,
+		},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := name + ".crt"
+	err = ioutil.WriteFile(filepath.Join(dir, cert), pem.EncodeToMemory(
+		&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: der,
+		}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := name + ".key"
+	err = ioutil.WriteFile(filepath.Join(dir, keyFile), pem.EncodeToMemory(
+		&pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: keyDER,
+		}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, keyFile
+}
+
+// writeConfig writes the peer configuration into the directory dir
+// and reads it back.
+func writeConfig(t *testing.T, dir, name string, config *Config) (
+	*Config, error) {
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return ReadConfig(file)
+}
+
+// tlsConfig creates a TLS configuration for count peers. Each peer
+// has a certificate and a private key. The peers listen at ephemeral
+// ports in the local host.
+func tlsConfig(t *testing.T, dir string, count int) *Config {
+	config := new(Config)
+	for i := 0; i < count; i++ {
+		cert, key := writeIdentity(t, dir, fmt.Sprintf("player%d", i))
+		config.Peers = append(config.Peers, &PeerConfig{
+			ID:   i,
+			Addr: "127.0.0.1:0",
+			Cert: cert,
+			Key:  key,
+		})
+	}
+	return config
+}
+
+// connect connects the peer self to the peer id which is listening
+// at ln. The function returns the accepted connection after the TLS
+// handshake. The dialer's configuration is dialConfig.
+func connect(t *testing.T, ln net.Listener, dialConfig *Config, self,
+	id int) (net.Conn, error) {
+
+	peer, err := dialConfig.Peer(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer.Addr = ln.Addr().String()
+
+	errc := make(chan error, 1)
+	go func() {
+		nc, err := dialConfig.Dial(self, id)
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer nc.Close()
+		_, err = nc.Write([]byte{byte(self)})
+		if err == nil {
+			// Wait until the listener has verified the connection.
+			_, err = nc.Read(make([]byte, 1))
+		}
+		errc <- err
+	}()
+
+	nc, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf [1]byte
+	_, err = nc.Read(buf[:])
+	if err != nil {
+		nc.Close()
+		<-errc
+		return nil, err
+	}
+	return nc, nil
+}
+
+func TestConfigTLSPinning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p2p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config, err := writeConfig(t, dir, "peers.json", tlsConfig(t, dir, 3))
+	if err != nil {
+		t.Fatalf("ReadConfig: %s", err)
+	}
+	if !config.TLS() {
+		t.Fatalf("TLS not enabled")
+	}
+	ln, err := config.Listen(1)
+	if err != nil {
+		t.Fatalf("Listen: %s", err)
+	}
+	defer ln.Close()
+
+	nc, err := connect(t, ln, config, 0, 1)
+	if err != nil {
+		t.Fatalf("pinned peer rejected: %s", err)
+	}
+	defer nc.Close()
+
+	if err := config.verifyPeer(nc, 0); err != nil {
+		t.Errorf("verifyPeer: %s", err)
+	}
+	err = config.verifyPeer(nc, 2)
+	if err == nil || !strings.Contains(err.Error(), "certificate mismatch") {
+		t.Errorf("verifyPeer accepted peer 2 for peer 0: %v", err)
+	}
+	if err := config.verifyPeer(nc, 3); err == nil {
+		t.Errorf("verifyPeer accepted unconfigured peer")
+	}
+}
+
+func TestConfigTLSUnknownPeer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p2p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config, err := writeConfig(t, dir, "peers.json", tlsConfig(t, dir, 2))
+	if err != nil {
+		t.Fatalf("ReadConfig: %s", err)
+	}
+
+	// The intruder has its own identity as peer 0.
+	cert, key := writeIdentity(t, dir, "intruder")
+	intruderConfig, err := writeConfig(t, dir, "intruder.json", &Config{
+		Peers: []*PeerConfig{
+			{
+				ID:   0,
+				Addr: "127.0.0.1:0",
+				Cert: cert,
+				Key:  key,
+			},
+			{
+				ID:   1,
+				Addr: "127.0.0.1:0",
+				Cert: "player1.crt",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ReadConfig: %s", err)
+	}
+
+	ln, err := config.Listen(1)
+	if err != nil {
+		t.Fatalf("Listen: %s", err)
+	}
+	defer ln.Close()
+
+	nc, err := connect(t, ln, intruderConfig, 0, 1)
+	if err == nil {
+		nc.Close()
+		t.Fatalf("unknown peer certificate accepted")
+	}
+	if !strings.Contains(err.Error(), "unknown peer certificate") {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestConfigVerifyPeerPlain(t *testing.T) {
+	config := LocalConfig(2, 8080)
+	if config.TLS() {
+		t.Fatalf("TLS enabled without certificates")
+	}
+	c0, c1 := net.Pipe()
+	defer c0.Close()
+	defer c1.Close()
+	if err := config.verifyPeer(c0, 0); err != nil {
+		t.Errorf("verifyPeer: %s", err)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p2p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	partial := tlsConfig(t, dir, 2)
+	partial.Peers[1].Cert = ""
+	_, err = writeConfig(t, dir, "partial.json", partial)
+	if err == nil || !strings.Contains(err.Error(),
+		"TLS certificates must be defined for all peers") {
+		t.Errorf("partial TLS configuration: %v", err)
+	}
+
+	duplicate := LocalConfig(2, 8080)
+	duplicate.Peers[1].ID = 0
+	_, err = writeConfig(t, dir, "duplicate.json", duplicate)
+	if err == nil || !strings.Contains(err.Error(), "duplicate peer 0") {
+		t.Errorf("duplicate peer: %v", err)
+	}
+}
@@ -25,6 +25,7 @@ type Network struct {
 	Peers    map[int]*Peer
 	addr     string
 	listener net.Listener
+	config   *Config
 }
 
 // NewNetwork creats a new peer-to-peer network.
@@ -43,6 +44,39 @@ func NewNetwork(addr string, id int) (*Network, error) {
 	return nw, nil
 }
 
+// NewNetworkConfig creates a new peer-to-peer network for the peer id
+// from the peer configuration. The function connects to all
+// configured peers before returning.
+func NewNetworkConfig(config *Config, id int) (*Network, error) {
+	self, err := config.Peer(id)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := config.Listen(id)
+	if err != nil {
+		return nil, err
+	}
+	nw := &Network{
+		ID:       id,
+		Peers:    make(map[int]*Peer),
+		addr:     self.Addr,
+		listener: listener,
+		config:   config,
+	}
+	go nw.acceptLoop()
+
+	for _, peer := range config.Peers {
+		if peer.ID == id {
+			continue
+		}
+		if err := nw.AddPeer(peer.Addr, peer.ID); err != nil {
+			nw.Close()
+			return nil, err
+		}
+	}
+	return nw, nil
+}
+
 // Close closes the network.
 func (nw *Network) Close() error {
 	return nw.listener.Close()
@@ -61,7 +95,7 @@ func (nw *Network) AddPeer(addr string, id int) error {
 		}
 
 		log.Printf("NW %d: Connecting to peer %d...\n", nw.ID, id)
-		nc, err := net.Dial("tcp", addr)
+		nc, err := nw.dial(addr, id)
 		if err != nil {
 			delay := 5 * time.Second
 			log.Printf("NW %d: Connect to %s failed, retrying in %s\n",
@@ -102,6 +136,13 @@ func (nw *Network) Stats() IOStats {
 	return result
 }
 
+func (nw *Network) dial(addr string, id int) (net.Conn, error) {
+	if nw.config != nil {
+		return nw.config.Dial(nw.ID, id)
+	}
+	return net.Dial("tcp", addr)
+}
+
 func (nw *Network) acceptLoop() {
 	for {
 		nc, err := nw.listener.Accept()
@@ -118,6 +159,13 @@ func (nw *Network) acceptLoop() {
 			conn.Close()
 			continue
 		}
+		if nw.config != nil {
+			if err := nw.config.verifyPeer(nc, id); err != nil {
+				log.Printf("NW %d: %s\n", nw.ID, err)
+				conn.Close()
+				continue
+			}
+		}
 
 		err = nw.newPeer(false, conn, id)
 		if err != nil {
@@ -224,6 +272,8 @@ func (peer *Peer) init() error {
 func (peer *Peer) OTLambda(count int, choices, x1, x2 *big.Int) (
 	result *big.Int, err error) {
 
+	defer peer.conn.SetMsgType(peer.conn.SetMsgType(MsgOT))
+
 	var mode string
 	if peer.client {
 		mode = "OT Lambda client"
@@ -355,6 +405,8 @@ func (peer *Peer) OTR(chA, chB, chC *big.Int,
 	x1Ag, x2Ag, x1Bg, x2Bg, x1Cg, x2Cg []ot.Label) (
 	ra, rb, rc []ot.Label, err error) {
 
+	defer peer.conn.SetMsgType(peer.conn.SetMsgType(MsgOT))
+
 	var mode string
 	if peer.client {
 		mode = "OT R client"
@@ -502,6 +554,8 @@ func (peer *Peer) otrRespond(x1, x2 []ot.Label) error {
 func (peer *Peer) ExchangeGates(ag, bg, cg, dg [][]ot.Label, lo *big.Int) (
 	ra, rb, rc, rd [][]ot.Label, ro *big.Int, err error) {
 
+	defer peer.conn.SetMsgType(peer.conn.SetMsgType(MsgTables))
+
 	var mode string
 	if peer.client {
 		mode = "Exch client"
@@ -0,0 +1,20 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package p2p
+
+import (
+	"net"
+)
+
+// Pipe creates a synchronous, in-memory, full duplex connection
+// pair. Data sent to one connection is received from the other
+// one. The function also returns the underlying network connections
+// so that the caller can abort a blocked peer by closing them.
+func Pipe() (*Conn, *Conn, net.Conn, net.Conn) {
+	a, b := net.Pipe()
+	return NewConn(a), NewConn(b), a, b
+}
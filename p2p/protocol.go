@@ -9,6 +9,8 @@ package p2p
 import (
 	"bufio"
 	"encoding/binary"
+	"encoding/json"
+	"fmt"
 	"io"
 
 	"github.com/markkurossi/mpc/ot"
@@ -16,32 +18,120 @@ import (
 
 // Conn implements a protocol connection.
 type Conn struct {
-	closer io.Closer
-	io     *bufio.ReadWriter
-	Stats  IOStats
+	closer  io.Closer
+	io      *bufio.ReadWriter
+	msgType MsgType
+	Stats   IOStats
 }
 
-// IOStats implements I/O statistics.
+// MsgType defines the logical message categories for I/O
+// statistics.
+type MsgType int
+
+// Message categories.
+const (
+	MsgOther MsgType = iota
+	MsgOT
+	MsgTables
+	MsgInputs
+	MsgResults
+	NumMsgTypes
+)
+
+var msgTypeNames = map[MsgType]string{
+	MsgOther:   "Other",
+	MsgOT:      "OT",
+	MsgTables:  "Garbled tables",
+	MsgInputs:  "Input labels",
+	MsgResults: "Results",
+}
+
+func (t MsgType) String() string {
+	name, ok := msgTypeNames[t]
+	if ok {
+		return name
+	}
+	return fmt.Sprintf("{MsgType %d}", t)
+}
+
+// MsgStats implements I/O statistics of a message category.
+type MsgStats struct {
+	Sent      uint64 `json:"sent"`
+	Recvd     uint64 `json:"recvd"`
+	SentMsgs  uint64 `json:"sent_msgs"`
+	RecvdMsgs uint64 `json:"recvd_msgs"`
+	Flushes   uint64 `json:"flushes"`
+}
+
+// Add adds the argument stats to this MsgStats and returns the sum.
+func (stats MsgStats) Add(o MsgStats) MsgStats {
+	return MsgStats{
+		Sent:      stats.Sent + o.Sent,
+		Recvd:     stats.Recvd + o.Recvd,
+		SentMsgs:  stats.SentMsgs + o.SentMsgs,
+		RecvdMsgs: stats.RecvdMsgs + o.RecvdMsgs,
+		Flushes:   stats.Flushes + o.Flushes,
+	}
+}
+
+// Sub subtracts the argument stats from this MsgStats and returns the
+// difference.
+func (stats MsgStats) Sub(o MsgStats) MsgStats {
+	return MsgStats{
+		Sent:      stats.Sent - o.Sent,
+		Recvd:     stats.Recvd - o.Recvd,
+		SentMsgs:  stats.SentMsgs - o.SentMsgs,
+		RecvdMsgs: stats.RecvdMsgs - o.RecvdMsgs,
+		Flushes:   stats.Flushes - o.Flushes,
+	}
+}
+
+// Sum returns sum of sent and received bytes.
+func (stats MsgStats) Sum() uint64 {
+	return stats.Sent + stats.Recvd
+}
+
+// Zero tests if the stats are all zero.
+func (stats MsgStats) Zero() bool {
+	return stats == MsgStats{}
+}
+
+// IOStats implements I/O statistics. The Sent, Recvd, and Flushes
+// fields contain the connection totals and Types contains the
+// statistics by message category. Flushes are a proxy for the
+// number of protocol rounds.
 type IOStats struct {
-	Sent  uint64
-	Recvd uint64
+	Sent    uint64
+	Recvd   uint64
+	Flushes uint64
+	Types   [NumMsgTypes]MsgStats
 }
 
 // Add adds the argument stats to this IOStats and returns the sum.
 func (stats IOStats) Add(o IOStats) IOStats {
-	return IOStats{
-		Sent:  stats.Sent + o.Sent,
-		Recvd: stats.Recvd + o.Recvd,
+	result := IOStats{
+		Sent:    stats.Sent + o.Sent,
+		Recvd:   stats.Recvd + o.Recvd,
+		Flushes: stats.Flushes + o.Flushes,
+	}
+	for i := range result.Types {
+		result.Types[i] = stats.Types[i].Add(o.Types[i])
 	}
+	return result
 }
 
 // Sub subtracts the argument stats from this IOStats and returns the
 // difference.
 func (stats IOStats) Sub(o IOStats) IOStats {
-	return IOStats{
-		Sent:  stats.Sent - o.Sent,
-		Recvd: stats.Recvd - o.Recvd,
+	result := IOStats{
+		Sent:    stats.Sent - o.Sent,
+		Recvd:   stats.Recvd - o.Recvd,
+		Flushes: stats.Flushes - o.Flushes,
 	}
+	for i := range result.Types {
+		result.Types[i] = stats.Types[i].Sub(o.Types[i])
+	}
+	return result
 }
 
 // Sum returns sum of sent and received bytes.
@@ -49,6 +139,29 @@ func (stats IOStats) Sum() uint64 {
 	return stats.Sent + stats.Recvd
 }
 
+// MarshalJSON implements json.Marshaler. The message category
+// statistics are encoded as an object, keyed by the category names.
+// Empty categories are omitted.
+func (stats IOStats) MarshalJSON() ([]byte, error) {
+	types := make(map[string]MsgStats)
+	for i, t := range stats.Types {
+		if !t.Zero() {
+			types[MsgType(i).String()] = t
+		}
+	}
+	return json.Marshal(&struct {
+		Sent    uint64              `json:"sent"`
+		Recvd   uint64              `json:"recvd"`
+		Flushes uint64              `json:"flushes"`
+		Types   map[string]MsgStats `json:"types"`
+	}{
+		Sent:    stats.Sent,
+		Recvd:   stats.Recvd,
+		Flushes: stats.Flushes,
+		Types:   types,
+	})
+}
+
 // NewConn creates a new connection around the argument connection.
 func NewConn(conn io.ReadWriter) *Conn {
 	closer, _ := conn.(io.Closer)
@@ -60,8 +173,33 @@ func NewConn(conn io.ReadWriter) *Conn {
 	}
 }
 
+// SetMsgType sets the message category for the I/O statistics of
+// the subsequent messages. The function returns the previous
+// category.
+func (c *Conn) SetMsgType(t MsgType) MsgType {
+	prev := c.msgType
+	c.msgType = t
+	return prev
+}
+
+func (c *Conn) sent(n int) {
+	c.Stats.Sent += uint64(n)
+	c.Stats.Types[c.msgType].Sent += uint64(n)
+	c.Stats.Types[c.msgType].SentMsgs++
+}
+
+func (c *Conn) recvd(n int) {
+	c.Stats.Recvd += uint64(n)
+	c.Stats.Types[c.msgType].Recvd += uint64(n)
+	c.Stats.Types[c.msgType].RecvdMsgs++
+}
+
 // Flush flushed any pending data in the connection.
 func (c *Conn) Flush() error {
+	if c.io.Writer.Buffered() > 0 {
+		c.Stats.Flushes++
+		c.Stats.Types[c.msgType].Flushes++
+	}
 	return c.io.Flush()
 }
 
@@ -82,7 +220,7 @@ func (c *Conn) SendByte(val byte) error {
 	if err != nil {
 		return err
 	}
-	c.Stats.Sent++
+	c.sent(1)
 	return nil
 }
 
@@ -92,7 +230,7 @@ func (c *Conn) SendUint16(val int) error {
 	if err != nil {
 		return err
 	}
-	c.Stats.Sent += 2
+	c.sent(2)
 	return nil
 }
 
@@ -102,13 +240,13 @@ func (c *Conn) SendUint32(val int) error {
 	if err != nil {
 		return err
 	}
-	c.Stats.Sent += 4
+	c.sent(4)
 	return nil
 }
 
 // SendData sends binary data.
 func (c *Conn) SendData(val []byte) error {
-	err := c.SendUint32(len(val))
+	err := binary.Write(c.io, binary.BigEndian, uint32(len(val)))
 	if err != nil {
 		return err
 	}
@@ -116,7 +254,7 @@ func (c *Conn) SendData(val []byte) error {
 	if err != nil {
 		return err
 	}
-	c.Stats.Sent += uint64(len(val))
+	c.sent(4 + len(val))
 	return nil
 }
 
@@ -126,7 +264,7 @@ func (c *Conn) SendLabel(val ot.Label) error {
 	if err != nil {
 		return err
 	}
-	c.Stats.Sent += uint64(n)
+	c.sent(n)
 	return nil
 }
 
@@ -141,7 +279,7 @@ func (c *Conn) ReceiveByte() (byte, error) {
 	if err != nil {
 		return 0, err
 	}
-	c.Stats.Recvd++
+	c.recvd(1)
 	return val, nil
 }
 
@@ -153,27 +291,34 @@ func (c *Conn) ReceiveUint16() (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	c.Stats.Recvd += 2
+	c.recvd(2)
 
 	return int(binary.BigEndian.Uint16(buf[:])), nil
 }
 
 // ReceiveUint32 receives an uint32 value.
 func (c *Conn) ReceiveUint32() (int, error) {
+	val, err := c.receiveUint32()
+	if err != nil {
+		return 0, err
+	}
+	c.recvd(4)
+	return val, nil
+}
+
+func (c *Conn) receiveUint32() (int, error) {
 	var buf [4]byte
 
 	_, err := io.ReadFull(c.io, buf[:])
 	if err != nil {
 		return 0, err
 	}
-	c.Stats.Recvd += 4
-
 	return int(binary.BigEndian.Uint32(buf[:])), nil
 }
 
 // ReceiveData receives binary data.
 func (c *Conn) ReceiveData() ([]byte, error) {
-	len, err := c.ReceiveUint32()
+	len, err := c.receiveUint32()
 	if err != nil {
 		return nil, err
 	}
@@ -183,7 +328,7 @@ func (c *Conn) ReceiveData() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	c.Stats.Recvd += uint64(len)
+	c.recvd(4 + len)
 
 	return result, nil
 }
@@ -195,7 +340,7 @@ func (c *Conn) ReceiveLabel() (ot.Label, error) {
 	if err != nil {
 		return ot.Label{}, err
 	}
-	c.Stats.Recvd += uint64(n)
+	c.recvd(n)
 
 	var result ot.Label
 	result.SetData(&buf)
@@ -213,6 +358,7 @@ func (c *Conn) ReceiveString() (string, error) {
 
 // Receive implements OT receive for the bit value of a wire.
 func (c *Conn) Receive(receiver *ot.Receiver, wire, bit uint) ([]byte, error) {
+	defer c.SetMsgType(c.SetMsgType(MsgOT))
 
 	if err := c.SendUint32(int(wire)); err != nil {
 		return nil, err
@@ -0,0 +1,223 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package p2p
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SimNet defines simulated network conditions.
+type SimNet struct {
+	// Latency specifies the one-way latency of the link.
+	Latency time.Duration
+	// Jitter specifies the maximum random delay added to Latency.
+	Jitter time.Duration
+	// Bandwidth specifies the link bandwidth in bits per second. The
+	// value 0 means unlimited bandwidth.
+	Bandwidth int64
+}
+
+// ParseSimNet parses the simulated network conditions from the
+// comma-separated key=value list. The supported keys are:
+//
+//	rtt     round-trip time, sets the one-way latency to rtt/2
+//	latency one-way latency
+//	jitter  maximum random delay added to each send
+//	bw      bandwidth with optional kbit, mbit, or gbit suffix
+func ParseSimNet(spec string) (*SimNet, error) {
+	sim := new(SimNet)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid simnet option: %s", part)
+		}
+		var err error
+		switch kv[0] {
+		case "rtt":
+			sim.Latency, err = time.ParseDuration(kv[1])
+			sim.Latency /= 2
+		case "latency":
+			sim.Latency, err = time.ParseDuration(kv[1])
+		case "jitter":
+			sim.Jitter, err = time.ParseDuration(kv[1])
+		case "bw":
+			sim.Bandwidth, err = parseBandwidth(kv[1])
+		default:
+			return nil, fmt.Errorf("unknown simnet option: %s", kv[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid simnet option %s: %s", part, err)
+		}
+	}
+	if sim.Latency < 0 || sim.Jitter < 0 || sim.Bandwidth < 0 {
+		return nil, fmt.Errorf("invalid simnet: %s", spec)
+	}
+	return sim, nil
+}
+
+var bandwidthUnits = []struct {
+	suffix string
+	mult   int64
+}{
+	{"gbit", 1000 * 1000 * 1000},
+	{"mbit", 1000 * 1000},
+	{"kbit", 1000},
+	{"bit", 1},
+}
+
+func parseBandwidth(val string) (int64, error) {
+	mult := int64(1)
+	lower := strings.ToLower(val)
+	for _, unit := range bandwidthUnits {
+		if strings.HasSuffix(lower, unit.suffix) {
+			lower = lower[:len(lower)-len(unit.suffix)]
+			mult = unit.mult
+			break
+		}
+	}
+	v, err := strconv.ParseInt(lower, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return v * mult, nil
+}
+
+func (sim *SimNet) String() string {
+	return fmt.Sprintf("latency=%s,jitter=%s,bw=%dbit",
+		sim.Latency, sim.Jitter, sim.Bandwidth)
+}
+
+// Wrap wraps the connection so that all data written to it is
+// subject to the simulated network conditions. The conditions apply
+// only to the sending direction so both peers must wrap their
+// connections to simulate a symmetric link.
+func (sim *SimNet) Wrap(conn io.ReadWriter) io.ReadWriteCloser {
+	c := &simConn{
+		sim:  sim,
+		conn: conn,
+		ch:   make(chan simPacket, 1024),
+		done: make(chan struct{}),
+	}
+	go c.deliver()
+	return c
+}
+
+type simPacket struct {
+	data []byte
+	at   time.Time
+}
+
+type simConn struct {
+	sim       *SimNet
+	conn      io.ReadWriter
+	ch        chan simPacket
+	done      chan struct{}
+	closeOnce sync.Once
+	// wm serializes writes and protects busy, last, and closed.
+	wm     sync.Mutex
+	busy   time.Time
+	last   time.Time
+	closed bool
+	m      sync.Mutex
+	err    error
+}
+
+func (c *simConn) Read(p []byte) (n int, err error) {
+	return c.conn.Read(p)
+}
+
+func (c *simConn) Write(p []byte) (n int, err error) {
+	c.wm.Lock()
+	defer c.wm.Unlock()
+
+	if c.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if err := c.error(); err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	start := now
+	if c.busy.After(now) {
+		start = c.busy
+	}
+	c.busy = start
+	if c.sim.Bandwidth > 0 {
+		c.busy = c.busy.Add(time.Duration(float64(len(p)*8) /
+			float64(c.sim.Bandwidth) * float64(time.Second)))
+	}
+	delay := c.sim.Latency
+	if c.sim.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(c.sim.Jitter)))
+	}
+	at := c.busy.Add(delay)
+	// Packets are delivered in order.
+	if at.Before(c.last) {
+		at = c.last
+	}
+	c.last = at
+
+	// Wait until the link is free for our packet.
+	time.Sleep(time.Until(start))
+
+	data := make([]byte, len(p))
+	copy(data, p)
+	c.ch <- simPacket{
+		data: data,
+		at:   at,
+	}
+	return len(p), nil
+}
+
+func (c *simConn) deliver() {
+	for pkt := range c.ch {
+		time.Sleep(time.Until(pkt.at))
+		if c.error() != nil {
+			continue
+		}
+		_, err := c.conn.Write(pkt.data)
+		if err != nil {
+			c.m.Lock()
+			c.err = err
+			c.m.Unlock()
+		}
+	}
+	close(c.done)
+}
+
+func (c *simConn) error() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.err
+}
+
+// Close delivers all pending data and closes the underlying
+// connection. Writes after Close fail with io.ErrClosedPipe.
+func (c *simConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.wm.Lock()
+		c.closed = true
+		close(c.ch)
+		c.wm.Unlock()
+	})
+	<-c.done
+	closer, ok := c.conn.(io.Closer)
+	if ok {
+		return closer.Close()
+	}
+	return c.error()
+}
@@ -0,0 +1,185 @@
+//
+// Copyright (c) 2020 Markku Rossi
+//
+// All rights reserved.
+//
+
+package p2p
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+var simNetTests = []struct {
+	spec string
+	sim  SimNet
+}{
+	{
+		spec: "",
+	},
+	{
+		spec: "rtt=50ms",
+		sim: SimNet{
+			Latency: 25 * time.Millisecond,
+		},
+	},
+	{
+		spec: "latency=10ms, jitter=5ms",
+		sim: SimNet{
+			Latency: 10 * time.Millisecond,
+			Jitter:  5 * time.Millisecond,
+		},
+	},
+	{
+		spec: "bw=100mbit",
+		sim: SimNet{
+			Bandwidth: 100 * 1000 * 1000,
+		},
+	},
+	{
+		spec: "bw=2Gbit,rtt=1s",
+		sim: SimNet{
+			Latency:   500 * time.Millisecond,
+			Bandwidth: 2 * 1000 * 1000 * 1000,
+		},
+	},
+	{
+		spec: "bw=64kbit",
+		sim: SimNet{
+			Bandwidth: 64 * 1000,
+		},
+	},
+	{
+		spec: "bw=1200",
+		sim: SimNet{
+			Bandwidth: 1200,
+		},
+	},
+}
+
+func TestParseSimNet(t *testing.T) {
+	for _, test := range simNetTests {
+		sim, err := ParseSimNet(test.spec)
+		if err != nil {
+			t.Errorf("ParseSimNet(%q) failed: %s", test.spec, err)
+			continue
+		}
+		if *sim != test.sim {
+			t.Errorf("ParseSimNet(%q)=%s, expected %s",
+				test.spec, sim, &test.sim)
+		}
+	}
+}
+
+var simNetErrorTests = []string{
+	"rtt",
+	"rtt=fast",
+	"delay=10ms",
+	"latency=-1ms",
+	"bw=10xbit",
+	"bw=-5mbit",
+}
+
+func TestParseSimNetErrors(t *testing.T) {
+	for _, spec := range simNetErrorTests {
+		_, err := ParseSimNet(spec)
+		if err == nil {
+			t.Errorf("ParseSimNet(%q) did not fail", spec)
+		}
+	}
+}
+
+// syncBuffer implements a concurrency safe io.ReadWriter.
+type syncBuffer struct {
+	m      sync.Mutex
+	buf    bytes.Buffer
+	writes []time.Time
+}
+
+func (b *syncBuffer) Read(p []byte) (int, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.buf.Read(p)
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.writes = append(b.writes, time.Now())
+	return b.buf.Write(p)
+}
+
+func TestSimNetWrap(t *testing.T) {
+	sim := &SimNet{
+		Latency: 20 * time.Millisecond,
+		Jitter:  5 * time.Millisecond,
+	}
+	buf := new(syncBuffer)
+	conn := sim.Wrap(buf)
+
+	start := time.Now()
+	for _, msg := range []string{"Hello", ", ", "world!"} {
+		n, err := conn.Write([]byte(msg))
+		if err != nil {
+			t.Fatalf("Write failed: %s", err)
+		}
+		if n != len(msg) {
+			t.Fatalf("Write returned %d, expected %d", n, len(msg))
+		}
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatalf("Close failed: %s", err)
+	}
+
+	// Close delivers all pending data in order.
+	if buf.buf.String() != "Hello, world!" {
+		t.Errorf("got %q, expected %q", buf.buf.String(), "Hello, world!")
+	}
+	if len(buf.writes) != 3 {
+		t.Fatalf("got %d writes, expected 3", len(buf.writes))
+	}
+	if buf.writes[0].Sub(start) < sim.Latency {
+		t.Errorf("data delivered after %s, expected latency %s",
+			buf.writes[0].Sub(start), sim.Latency)
+	}
+
+	_, err := conn.Write([]byte("late"))
+	if err != io.ErrClosedPipe {
+		t.Errorf("Write after Close: got %v, expected %v",
+			err, io.ErrClosedPipe)
+	}
+	if err := conn.Close(); err != nil {
+		t.Errorf("second Close failed: %s", err)
+	}
+}
+
+func TestSimNetBandwidth(t *testing.T) {
+	sim := &SimNet{
+		Bandwidth: 80 * 1000,
+	}
+	buf := new(syncBuffer)
+	conn := sim.Wrap(buf)
+
+	// 1000 bytes at 80kbit/s take 100ms. The first write is sent
+	// immediately and the second waits until the link is free.
+	data := make([]byte, 500)
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := conn.Write(data); err != nil {
+			t.Fatalf("Write failed: %s", err)
+		}
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatalf("Close failed: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("sent 1000 bytes in %s, expected at least 100ms", elapsed)
+	}
+	if buf.buf.Len() != 1000 {
+		t.Errorf("got %d bytes, expected 1000", buf.buf.Len())
+	}
+}